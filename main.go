@@ -1,21 +1,446 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	eventexportapparmor "runtime-behavior-profiler/pkg/event/export/apparmor"
+	eventexportfalco "runtime-behavior-profiler/pkg/event/export/falco"
+	eventpolicy "runtime-behavior-profiler/pkg/event/policy"
+	eventprocessorkafka "runtime-behavior-profiler/pkg/event/processor/kafka"
+	eventprocessorocsf "runtime-behavior-profiler/pkg/event/processor/ocsf"
 	eventprocessortetragon "runtime-behavior-profiler/pkg/event/processor/tetragon"
 	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"strings"
+	"syscall"
+	"time"
 )
 
 func main() {
 
-	cluster := eventtype.Cluster{
-		Name:       "test-cluster",
-		Namespaces: map[string]*eventtype.Namespace{},
+	options := eventprocessortetragon.GetDefaultOptions()
+	flag.StringVar(&options.ServerAddress, "server", options.ServerAddress, "address of the tetragon gRPC server")
+	flag.StringVar(&options.Output, "output", options.Output, "output mode: empty prints deviations, ocsf prints OCSF findings, ocsf-events streams events as OCSF NDJSON")
+	flag.BoolVar(&options.Host, "host", options.Host, "profile processes that do not run in a pod")
+	flag.StringVar(&options.HostNodeName, "host-node", options.HostNodeName, "node the profiler runs on, only its host processes have their systemd unit resolved from -host-proc-root")
+	flag.StringVar(&options.HostProcRoot, "host-proc-root", options.HostProcRoot, "proc filesystem of -host-node")
+	ocsfFile := flag.String("ocsf-file", "", "ingest OCSF events from an NDJSON or JSON array file instead of tetragon, - reads stdin")
+	deadLetterFile := flag.String("dead-letter", "", "file receiving the events that could not be ingested, as NDJSON")
+	kafkaOptions := eventprocessorkafka.GetDefaultOptions()
+	kafkaBrokers := flag.String("kafka-brokers", "", "consume events from these comma separated Kafka brokers instead of tetragon")
+	kafkaTopics := flag.String("kafka-topics", strings.Join(kafkaOptions.Topics, ","), "comma separated Kafka topics to consume")
+	flag.StringVar(&kafkaOptions.Group, "kafka-group", kafkaOptions.Group, "Kafka consumer group")
+	flag.StringVar(&kafkaOptions.Format, "kafka-format", kafkaOptions.Format, "format of the Kafka records: ocsf, tetragon, or empty to detect it per record")
+	httpAddress := flag.String("http-listen", "", "receive OCSF events pushed to POST /v1/events on this address instead of tetragon")
+	tcpAddress := flag.String("tcp-listen", "", "receive OCSF NDJSON or syslog events over TCP on this address instead of tetragon, TCP clients are not authenticated so it must be a loopback address unless -insecure is set")
+	tokenFile := flag.String("token-file", "", "file holding the bearer token required from HTTP clients, required by -http-listen unless -insecure is set")
+	insecure := flag.Bool("insecure", false, "accept unauthenticated HTTP clients and TCP clients from other hosts")
+	falcoRulesDir := flag.String("falco-rules", "", "directory receiving the Falco rules learned for each cluster once ingestion ends")
+	apparmorDir := flag.String("apparmor-profiles", "", "directory receiving the AppArmor profiles and pod annotations learned for each cluster once ingestion ends")
+	policyFile := flag.String("policy", "", "JSON file of CEL rules evaluated against every event and, once ingestion ends, against the learned profiles")
+	detections := flag.Bool("detections", true, "report suspicious behaviour matching the built-in detection rules, a -policy rule with the same name replaces one")
+	attackQuery := flag.String("attack", "", "print the profile nodes tagged with this MITRE ATT&CK tactic or technique, e.g. Discovery or T1059, once ingestion ends")
+	findImage := flag.String("find-image", "", "print the containers running this image repository in every cluster, e.g. nginx, once ingestion ends")
+	compareImage := flag.String("compare-image", "", "print the behaviour common to and unique to each cluster running this image repository once ingestion ends")
+	prevalence := flag.Bool("prevalence", false, "print the prevalence and rarity of every process, argument set and connection, from the rarest, once ingestion ends")
+	attackMappingFile := flag.String("attack-mapping", "", "JSON file replacing the bundled MITRE ATT&CK mapping")
+	argumentRulesFile := flag.String("argument-rules", "", "JSON file of argument normalization rules applied before the built-in masks, e.g. [{\"pattern\": \"^--job=.*\", \"replacement\": \"--job=<job>\"}]")
+	learningPeriod := flag.Duration("learning-period", eventtype.DefaultLearningPeriod, "how long a container is observed before new behaviour is reported as a deviation")
+	retentionFile := flag.String("retention", "", "JSON file of the default and per namespace retention policies, e.g. {\"namespaces\": {\"batch\": {\"ttl\": \"1h\", \"max_children\": 64}}}")
+	flag.Parse()
+
+	// Events that do not name their cluster are profiled under test-cluster.
+	registry := eventtype.NewClusterRegistry("test-cluster")
+	configure := []func(cluster *eventtype.Cluster){
+		func(cluster *eventtype.Cluster) { cluster.LearningPeriod = *learningPeriod },
+	}
+	if *attackMappingFile != "" {
+		mapping, err := loadAttackMapping(*attackMappingFile)
+		if err != nil {
+			println(err.Error())
+			os.Exit(1)
+		}
+		configure = append(configure, func(cluster *eventtype.Cluster) { cluster.Attack = mapping })
+	}
+	if *argumentRulesFile != "" {
+		normalizer, err := loadArgumentNormalizer(*argumentRulesFile)
+		if err != nil {
+			println(err.Error())
+			os.Exit(1)
+		}
+		configure = append(configure, func(cluster *eventtype.Cluster) { cluster.Normalizer = normalizer })
+	}
+	if *retentionFile != "" {
+		retention, err := loadRetentionConfig(*retentionFile)
+		if err != nil {
+			println(err.Error())
+			os.Exit(1)
+		}
+		configure = append(configure, func(cluster *eventtype.Cluster) { cluster.Retention = retention })
+	}
+	registry.NewCluster = func(name string) *eventtype.Cluster {
+		cluster := eventtype.NewCluster(name)
+		for _, fn := range configure {
+			fn(cluster)
+		}
+		return cluster
+	}
+
+	var sink eventtype.IEventSink = registry
+	var policy *eventpolicy.Policy
+	if *policyFile != "" || *detections {
+		var err error
+		var rules []*eventpolicy.Rule
+		if *policyFile != "" {
+			rules, err = eventpolicy.LoadRules(*policyFile)
+		}
+		if err == nil && *detections {
+			rules = eventpolicy.WithDetectionRules(rules)
+		}
+		if err == nil {
+			policy, err = eventpolicy.NewPolicy(rules)
+		}
+		if err != nil {
+			println(err.Error())
+			os.Exit(1)
+		}
+		sink = eventpolicy.NewSink(registry, policy)
 	}
 
-	eventLister := eventprocessortetragon.NewEventListener(&cluster)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	err := eventLister.ListenToEvents()
+	var err error
+	switch {
+	case *ocsfFile != "":
+		err = ingestOCSFFile(sink, *ocsfFile, *deadLetterFile, options.Output)
+	case *httpAddress != "" || *tcpAddress != "":
+		registry.StartCompaction(ctx, 10*time.Minute)
+		err = receiveOCSF(ctx, registry, sink, *httpAddress, *tcpAddress, *tokenFile, *insecure, *deadLetterFile, options.Output)
+	case *kafkaBrokers != "":
+		registry.StartCompaction(ctx, 10*time.Minute)
+		kafkaOptions.Brokers = strings.Split(*kafkaBrokers, ",")
+		kafkaOptions.Topics = strings.Split(*kafkaTopics, ",")
+		kafkaOptions.Output = options.Output
+		kafkaOptions.Host = options.Host
+		err = consumeKafka(ctx, registry, sink, kafkaOptions, *deadLetterFile)
+	default:
+		registry.StartCompaction(ctx, 10*time.Minute)
+		// The listener ends with an error once interrupted, it is reported without failing.
+		listener := eventprocessortetragon.NewEventListenerWithOptions(registry, options)
+		listener.Sink = sink
+		if err := listener.ListenToEvents(); err != nil {
+			println(err.Error())
+		}
+	}
 	if err != nil {
 		println(err.Error())
 	}
+
+	if policy != nil {
+		if err := evaluatePolicy(registry, policy); err != nil {
+			println(err.Error())
+		}
+	}
+	if *prevalence {
+		for _, name := range registry.ClusterNames() {
+			for _, prevalence := range registry.GetCluster(name).Prevalences() {
+				prevalenceJSON, _ := json.Marshal(prevalence)
+				fmt.Println(string(prevalenceJSON))
+			}
+		}
+	}
+	if *findImage != "" {
+		for _, usage := range registry.FindImage(*findImage) {
+			usageJSON, _ := json.Marshal(usage)
+			fmt.Println(string(usageJSON))
+		}
+	}
+	if *compareImage != "" {
+		if comparison := registry.CompareImage(*compareImage); comparison != nil {
+			comparisonJSON, _ := json.Marshal(comparison)
+			fmt.Println(string(comparisonJSON))
+		} else {
+			println("No cluster runs image " + *compareImage)
+		}
+	}
+	if *attackQuery != "" {
+		for _, usage := range registry.FindAttack(*attackQuery) {
+			usageJSON, _ := json.Marshal(usage)
+			fmt.Println(string(usageJSON))
+		}
+	}
+	if *falcoRulesDir != "" {
+		if err := exportFalcoRules(registry, *falcoRulesDir); err != nil {
+			println(err.Error())
+			os.Exit(1)
+		}
+	}
+	if *apparmorDir != "" {
+		if err := exportAppArmorProfiles(registry, *apparmorDir); err != nil {
+			println(err.Error())
+			os.Exit(1)
+		}
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// evaluatePolicy prints the policy violations of the profiles learned for every cluster of
+// the registry as JSON deviations.
+func evaluatePolicy(registry *eventtype.ClusterRegistry, policy *eventpolicy.Policy) error {
+	var errs []error
+	for _, name := range registry.ClusterNames() {
+		deviations, err := policy.EvaluateCluster(registry.GetCluster(name))
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, deviation := range deviations {
+			deviationJSON, _ := json.Marshal(deviation)
+			fmt.Println(string(deviationJSON))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loadAttackMapping reads a MITRE ATT&CK mapping in the format of the bundled one.
+func loadAttackMapping(path string) (*eventtype.AttackMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return eventtype.ParseAttackMapping(data)
+}
+
+// loadArgumentNormalizer reads a JSON array of argument rules and returns the normalizer
+// applying them before the built-in masks.
+func loadArgumentNormalizer(path string) (*eventtype.RegexArgumentNormalizer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []eventtype.ArgumentRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid argument rules %s: %w", path, err)
+	}
+	return eventtype.NewArgumentNormalizer(rules)
+}
+
+// loadRetentionConfig reads the retention configuration of the profiles, see
+// eventtype.ParseRetentionConfig.
+func loadRetentionConfig(path string) (*eventtype.RetentionConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return eventtype.ParseRetentionConfig(data)
+}
+
+// exportFalcoRules writes the Falco rules learned for every cluster of the registry to
+// <dir>/<cluster>.yaml.
+func exportFalcoRules(registry *eventtype.ClusterRegistry, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	for _, name := range registry.ClusterNames() {
+		rules := eventexportfalco.Export(registry.GetCluster(name), eventexportfalco.Options{})
+		path := filepath.Join(dir, name+".yaml")
+		if err := os.WriteFile(path, []byte(rules), 0o644); err != nil {
+			return err
+		}
+		println("Falco rules of cluster " + name + " written to " + path)
+	}
+
+	return nil
+}
+
+// exportAppArmorProfiles writes the AppArmor profiles learned for every cluster of the
+// registry to <dir>/<cluster>/<profile>, with the annotations selecting them for each pod
+// in <dir>/<cluster>/annotations.json.
+func exportAppArmorProfiles(registry *eventtype.ClusterRegistry, dir string) error {
+	for _, name := range registry.ClusterNames() {
+		clusterDir := filepath.Join(dir, name)
+		if err := os.MkdirAll(clusterDir, 0o755); err != nil {
+			return err
+		}
+
+		annotations := map[string]map[string]string{}
+		for _, profile := range eventexportapparmor.Export(registry.GetCluster(name), eventexportapparmor.Options{}) {
+			if err := os.WriteFile(filepath.Join(clusterDir, profile.Name), []byte(profile.Policy), 0o644); err != nil {
+				return err
+			}
+			for pod, podAnnotations := range profile.Annotations {
+				if annotations[pod] == nil {
+					annotations[pod] = map[string]string{}
+				}
+				for key, value := range podAnnotations {
+					annotations[pod][key] = value
+				}
+			}
+		}
+
+		data, err := json.MarshalIndent(annotations, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(clusterDir, "annotations.json"), append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+		println("AppArmor profiles of cluster " + name + " written to " + clusterDir)
+	}
+
+	return nil
+}
+
+// ingestOCSFFile streams the OCSF events of path into the sink, printing the result of each
+// event in the output mode, and prints the ingestion summary to stderr.
+func ingestOCSFFile(sink eventtype.IEventSink, path string, deadLetterPath string, output string) error {
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	deadLetters, closeDeadLetters, err := openDeadLetters(deadLetterPath)
+	if err != nil {
+		return err
+	}
+	defer closeDeadLetters()
+
+	result, err := eventprocessorocsf.ProcessStreamWithOutput(input, sink, deadLetters, output)
+	if result != nil {
+		resultJSON, _ := json.Marshal(result)
+		println(string(resultJSON))
+	}
+
+	return err
+}
+
+// consumeKafka sinks the events of the Kafka topics until interrupted and prints the registry.
+func consumeKafka(ctx context.Context, registry *eventtype.ClusterRegistry, sink eventtype.IEventSink, options eventprocessorkafka.KafkaConsumerOptions, deadLetterPath string) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	deadLetters, closeDeadLetters, err := openDeadLetters(deadLetterPath)
+	if err != nil {
+		return err
+	}
+	defer closeDeadLetters()
+	if deadLetters != nil {
+		options.DeadLetters = deadLetters
+	}
+
+	err = eventprocessorkafka.NewConsumerWithOptions(sink, options).Consume(ctx)
+
+	profileJSON, marshalErr := json.MarshalIndent(registry, "", "  ")
+	if marshalErr != nil {
+		fmt.Fprintf(os.Stderr, "failed to marshal the profiles: %v\n", marshalErr)
+	} else {
+		println("\n" + string(profileJSON))
+	}
+
+	return err
+}
+
+// receiveOCSF sinks the OCSF events pushed over HTTP and TCP until interrupted and prints the registry.
+// Unauthenticated HTTP clients and TCP clients from other hosts are refused unless insecure is set.
+func receiveOCSF(ctx context.Context, registry *eventtype.ClusterRegistry, sink eventtype.IEventSink, httpAddress string, tcpAddress string, tokenFile string, insecure bool, deadLetterPath string, output string) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	token := ""
+	if tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return err
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if httpAddress != "" && token == "" {
+		if !insecure {
+			return errors.New("-http-listen requires a -token-file, or -insecure to accept unauthenticated clients")
+		}
+		println("No token file given, HTTP clients are not authenticated")
+	}
+	if tcpAddress != "" && !isLoopbackAddress(tcpAddress) && !insecure {
+		return errors.New("TCP clients are not authenticated, -tcp-listen requires a loopback address, or -insecure to accept other hosts")
+	}
+
+	deadLetters, closeDeadLetters, err := openDeadLetters(deadLetterPath)
+	if err != nil {
+		return err
+	}
+	defer closeDeadLetters()
+
+	receiver := eventprocessorocsf.NewReceiver(sink, token)
+	receiver.DeadLetters = deadLetters
+	receiver.Output = output
+
+	errs := make(chan error, 2)
+	listeners := 0
+	if httpAddress != "" {
+		listeners++
+		go func() { errs <- receiver.ListenAndServe(ctx, httpAddress) }()
+	}
+	if tcpAddress != "" {
+		listeners++
+		go func() { errs <- receiver.ListenTCP(ctx, tcpAddress) }()
+	}
+
+	// The first listener failing stops the others.
+	var listenErr error
+	for ; listeners > 0; listeners-- {
+		if err := <-errs; err != nil && listenErr == nil {
+			listenErr = err
+			stop()
+		}
+	}
+
+	profileJSON, err := json.MarshalIndent(registry, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to marshal the profiles: %v\n", err)
+	} else {
+		println("\n" + string(profileJSON))
+	}
+
+	return listenErr
+}
+
+// isLoopbackAddress reports whether a listen address only accepts connections from the local host.
+func isLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// openDeadLetters creates the dead letter file, a nil writer is returned when path is empty.
+func openDeadLetters(path string) (io.Writer, func(), error) {
+	if path == "" {
+		return nil, func() {}, nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, func() { file.Close() }, nil
 }
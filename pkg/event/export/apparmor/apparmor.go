@@ -0,0 +1,266 @@
+package eventexportapparmor
+
+import (
+	"fmt"
+	eventexport "runtime-behavior-profiler/pkg/event/export"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"sort"
+	"strings"
+)
+
+const (
+	// AnnotationPrefix is the pod annotation selecting the AppArmor profile of a container,
+	// followed by the container name.
+	AnnotationPrefix = "container.apparmor.security.beta.kubernetes.io/"
+)
+
+// protocolTypes maps the socket protocols reported by the kprobe decoder to AppArmor socket types.
+var protocolTypes = map[string]string{
+	"IPPROTO_TCP":  "stream",
+	"IPPROTO_UDP":  "dgram",
+	"IPPROTO_ICMP": "raw",
+	"IPPROTO_RAW":  "raw",
+}
+
+type Options struct {
+	// Prefix of the names of the generated profiles, defaults to eventexport.DefaultPrefix.
+	Prefix string
+	// Complain generates profiles in complain mode, which log the denials instead of enforcing them.
+	Complain bool
+}
+
+// Profile is the AppArmor profile learned for an image and the containers it applies to.
+type Profile struct {
+	Name       string
+	Repository string
+	// Policy is the profile in the AppArmor policy language, to be loaded on every node.
+	Policy string
+	// Annotations are the annotations selecting the profile, keyed by namespace/pod.
+	Annotations map[string]map[string]string
+}
+
+// imageProfile is the behaviour learned for an image, merged across the containers running it.
+type imageProfile struct {
+	repository string
+	// binaries are the paths of the processes of the learned tree, run under the profile.
+	binaries map[string]bool
+	// executables are the paths the processes were seen executing.
+	executables map[string]bool
+	// files are the access modes of each accessed path.
+	files map[string]map[string]bool
+	// networks are the socket types of each address family, an empty type allows the family.
+	networks map[string]map[string]bool
+	// capabilities are the capabilities checked by the kernel on behalf of the processes.
+	capabilities map[string]bool
+	// effective are the effective capabilities of the processes, used when no check was seen.
+	effective map[string]bool
+	// containers are the namespace/pod of each container running the image.
+	containers map[string]map[string]bool
+}
+
+// Export returns the AppArmor profiles learned for every image of the cluster, sorted by
+// name. The behaviours of the containers running the same image repository are merged.
+func Export(cluster *eventtype.Cluster, options Options) []*Profile {
+	profiles := map[string]*imageProfile{}
+
+	cluster.ForEachContainer(func(namespace *eventtype.Namespace, pod *eventtype.Pod, container *eventtype.Container) {
+		repository := container.Image.FullName()
+		if repository == "" {
+			return
+		}
+
+		profile, ok := profiles[repository]
+		if !ok {
+			profile = &imageProfile{
+				repository:   repository,
+				binaries:     map[string]bool{},
+				executables:  map[string]bool{},
+				files:        map[string]map[string]bool{},
+				networks:     map[string]map[string]bool{},
+				capabilities: map[string]bool{},
+				effective:    map[string]bool{},
+				containers:   map[string]map[string]bool{},
+			}
+			profiles[repository] = profile
+		}
+		eventexport.AddTo(profile.containers, namespace.Name+"/"+pod.Name, container.Name)
+		profile.add(container.Processes)
+	})
+
+	exported := make([]*Profile, 0, len(profiles))
+	for _, profile := range profiles {
+		name := eventexport.Name(options.Prefix, profile.repository, "-")
+
+		annotations := map[string]map[string]string{}
+		for pod, containers := range profile.containers {
+			annotations[pod] = map[string]string{}
+			for container := range containers {
+				annotations[pod][AnnotationPrefix+container] = "localhost/" + name
+			}
+		}
+
+		exported = append(exported, &Profile{
+			Name:        name,
+			Repository:  profile.repository,
+			Policy:      profile.policy(name, options),
+			Annotations: annotations,
+		})
+	}
+	sort.Slice(exported, func(i, j int) bool { return exported[i].Name < exported[j].Name })
+
+	return exported
+}
+
+// add records the behaviour of the processes and their descendants.
+func (profile *imageProfile) add(processes map[string]*eventtype.Process) {
+	for _, process := range processes {
+		if strings.HasPrefix(process.Binary, "/") {
+			profile.binaries[process.Binary] = true
+		}
+
+		for _, library := range process.Libraries {
+			eventexport.AddTo(profile.files, library.Path, "m")
+			eventexport.AddTo(profile.files, library.Path, "r")
+		}
+
+		for _, behavior := range process.KernelBehaviors {
+			profile.addBehavior(behavior)
+		}
+
+		if process.Privileges != nil {
+			for capability := range process.Privileges.Capabilities {
+				profile.effective[capabilityName(capability)] = true
+			}
+		}
+
+		profile.add(process.ChildProcesses)
+	}
+}
+
+func (profile *imageProfile) addBehavior(behavior *eventtype.KernelBehavior) {
+	switch {
+	case behavior.Exec != nil && strings.HasPrefix(behavior.Exec.Path, "/"):
+		profile.executables[behavior.Exec.Path] = true
+	case behavior.File != nil && strings.HasPrefix(behavior.File.Path, "/"):
+		for _, mode := range fileModes(behavior) {
+			eventexport.AddTo(profile.files, behavior.File.Path, mode)
+		}
+	case behavior.Network != nil && behavior.Network.Family != "":
+		family := strings.ToLower(strings.TrimPrefix(behavior.Network.Family, "AF_"))
+		eventexport.AddTo(profile.networks, family, protocolTypes[behavior.Network.Protocol])
+	case behavior.Capability != nil && behavior.Capability.Name != "":
+		profile.capabilities[capabilityName(behavior.Capability.Name)] = true
+	}
+}
+
+// fileModes returns the AppArmor access modes implied by a file behaviour. Only the access
+// reported by the function grants w, the mode of the file does not tell it was written.
+func fileModes(behavior *eventtype.KernelBehavior) []string {
+	switch {
+	case behavior.Function == "security_mmap_file":
+		return []string{"r", "m"}
+	case behavior.Function == "security_path_truncate":
+		return []string{"w"}
+	case behavior.File.Access == eventtype.FileAccessWrite:
+		return []string{"w"}
+	case strings.Contains(behavior.File.Access, eventtype.FileAccessWrite):
+		return []string{"r", "w"}
+	}
+	return []string{"r"}
+}
+
+func (profile *imageProfile) policy(name string, options Options) string {
+	flags := "attach_disconnected,mediate_deleted"
+	if options.Complain {
+		flags += ",complain"
+	}
+
+	var policy strings.Builder
+	fmt.Fprintf(&policy, "# AppArmor profile generated by runtime-behavior-profiler from the learned behaviour of %s.\n", profile.repository)
+	policy.WriteString("#include <tunables/global>\n\n")
+	fmt.Fprintf(&policy, "profile %s flags=(%s) {\n", name, flags)
+	policy.WriteString("  #include <abstractions/base>\n")
+
+	// Capabilities the kernel checked are the ones the workload needs, the effective set is
+	// only a fallback for profiles learned without the capability kprobe.
+	capabilities := profile.capabilities
+	if len(capabilities) == 0 {
+		capabilities = profile.effective
+	}
+	var rules []string
+	for _, capability := range eventexport.SortedKeys(capabilities) {
+		rules = append(rules, "capability "+capability)
+	}
+	writeRules(&policy, rules)
+
+	rules = nil
+	for _, family := range eventexport.SortedKeys(profile.networks) {
+		types := profile.networks[family]
+		if types[""] {
+			rules = append(rules, "network "+family)
+			continue
+		}
+		for _, socketType := range eventexport.SortedKeys(types) {
+			rules = append(rules, "network "+family+" "+socketType)
+		}
+	}
+	writeRules(&policy, rules)
+
+	// The processes of the learned tree inherit the profile, any other executable is only
+	// allowed to run confined by its own profile.
+	rules = nil
+	for _, binary := range eventexport.SortedKeys(profile.binaries) {
+		rules = append(rules, pathRule(binary, "ix"))
+	}
+	for _, executable := range eventexport.SortedKeys(profile.executables) {
+		if !profile.binaries[executable] {
+			rules = append(rules, pathRule(executable, "px"))
+		}
+	}
+	writeRules(&policy, rules)
+
+	rules = nil
+	for _, path := range eventexport.SortedKeys(profile.files) {
+		modes := profile.files[path]
+		// Binaries of the tree are already readable and mappable through their exec rule.
+		if profile.binaries[path] && !modes["w"] {
+			continue
+		}
+		var permissions string
+		for _, mode := range []string{"m", "r", "w"} {
+			if modes[mode] {
+				permissions += mode
+			}
+		}
+		rules = append(rules, pathRule(path, permissions))
+	}
+	writeRules(&policy, rules)
+
+	policy.WriteString("}\n")
+	return policy.String()
+}
+
+func writeRules(policy *strings.Builder, rules []string) {
+	if len(rules) == 0 {
+		return
+	}
+	policy.WriteString("\n")
+	for _, rule := range rules {
+		fmt.Fprintf(policy, "  %s,\n", rule)
+	}
+}
+
+// pathRule returns the rule granting permissions on path, escaping the AppArmor glob
+// characters and quoting paths holding whitespace.
+func pathRule(path string, permissions string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`, `{`, `\{`, `}`, `\}`, `^`, `\^`, `"`, `\"`).Replace(path)
+	if strings.ContainsAny(path, " \t") {
+		escaped = `"` + escaped + `"`
+	}
+	return escaped + " " + permissions
+}
+
+// capabilityName returns the AppArmor name of a capability, e.g. net_raw for CAP_NET_RAW.
+func capabilityName(capability string) string {
+	return strings.ToLower(strings.TrimPrefix(capability, "CAP_"))
+}
@@ -0,0 +1,204 @@
+package eventexportapparmor
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func process(binary string, behaviors []*eventtype.KernelBehavior, children ...*eventtype.Process) *eventtype.Process {
+	process := &eventtype.Process{
+		Binary:          binary,
+		ChildProcesses:  map[string]*eventtype.Process{},
+		KernelBehaviors: map[string]*eventtype.KernelBehavior{},
+	}
+	for _, behavior := range behaviors {
+		process.KernelBehaviors[behavior.GetKey()] = behavior
+	}
+	for _, child := range children {
+		process.ChildProcesses[child.GetKey()] = child
+	}
+	return process
+}
+
+func file(function string, path string, permission string, access string) *eventtype.KernelBehavior {
+	return &eventtype.KernelBehavior{
+		Kind:     eventtype.KernelBehaviorFile,
+		Function: function,
+		File:     &eventtype.FileBehavior{Path: path, Permission: permission, Access: access},
+	}
+}
+
+func network(function string, family string, protocol string) *eventtype.KernelBehavior {
+	return &eventtype.KernelBehavior{
+		Kind:     eventtype.KernelBehaviorNetwork,
+		Function: function,
+		Network:  &eventtype.NetworkBehavior{Family: family, Protocol: protocol},
+	}
+}
+
+func exec(path string) *eventtype.KernelBehavior {
+	return &eventtype.KernelBehavior{
+		Kind:     eventtype.KernelBehaviorExec,
+		Function: "security_bprm_check",
+		Exec:     &eventtype.ExecBehavior{Path: path},
+	}
+}
+
+func capability(name string) *eventtype.KernelBehavior {
+	return &eventtype.KernelBehavior{
+		Kind:       eventtype.KernelBehaviorCapability,
+		Function:   "cap_capable",
+		Capability: &eventtype.CapabilityBehavior{Name: name},
+	}
+}
+
+func container(name string, image *eventtype.Image, processes ...*eventtype.Process) *eventtype.Container {
+	container := &eventtype.Container{
+		Name:      name,
+		Image:     image,
+		Processes: map[string]*eventtype.Process{},
+	}
+	for _, process := range processes {
+		container.Processes[process.GetKey()] = process
+	}
+	return container
+}
+
+func testCluster() *eventtype.Cluster {
+	nginx := &eventtype.Image{Repo: "library/nginx", Tag: "1.27", Registry: &eventtype.Registry{Name: "docker.io"}}
+	redis := &eventtype.Image{Repo: "redis", Tag: "latest", Registry: &eventtype.Registry{}}
+
+	server := process("/usr/sbin/nginx", []*eventtype.KernelBehavior{
+		file("security_file_open", "/etc/nginx/nginx.conf", "-rw-r--r--", ""),
+		file("security_file_permission", "/var/log/nginx/access.log", "-rw-r-----", "read,write"),
+		// A writable file that was only read.
+		file("security_file_permission", "/etc/nginx/mime.types", "-rw-r--r--", ""),
+		file("security_mmap_file", "/usr/lib/nginx/modules/ngx_http_geoip_module.so", "-rw-r--r--", ""),
+		file("security_file_open", "/usr/share/nginx/html/index page.html", "-rw-r--r--", ""),
+		network("inet_csk_accept", "AF_INET", "IPPROTO_TCP"),
+		network("inet_csk_accept", "AF_INET6", "IPPROTO_TCP"),
+		capability("CAP_NET_BIND_SERVICE"),
+		capability("CAP_SETUID"),
+	})
+	server.Libraries = map[string]*eventtype.Library{
+		"library:libssl": {Path: "/usr/lib/x86_64-linux-gnu/libssl.so.3"},
+	}
+	server.Privileges = &eventtype.PrivilegeProfile{Capabilities: map[string]int64{"CAP_CHOWN": 1}}
+
+	cli := process("/usr/local/bin/redis-cli", []*eventtype.KernelBehavior{
+		network("udp_sendmsg", "AF_INET", "IPPROTO_UDP"),
+		network("tcp_connect", "AF_UNIX", ""),
+	})
+	cli.Privileges = &eventtype.PrivilegeProfile{Capabilities: map[string]int64{"CAP_CHOWN": 1, "CAP_KILL": 2}}
+
+	cluster := eventtype.NewCluster("test-cluster")
+	cluster.Namespaces["namespace:default"] = &eventtype.Namespace{
+		Name: "default",
+		Pods: map[string]*eventtype.Pod{
+			"pod:nginx-1": {Name: "nginx-1", Containers: map[string]*eventtype.Container{
+				"container:nginx": container("nginx", nginx,
+					process("/docker-entrypoint.sh", []*eventtype.KernelBehavior{exec("/bin/sh"), exec("/usr/sbin/nginx")},
+						process("/bin/sh", []*eventtype.KernelBehavior{exec("/usr/bin/curl")}),
+						server,
+					),
+				),
+			}},
+			"pod:nginx-2": {Name: "nginx-2", Containers: map[string]*eventtype.Container{
+				"container:web": container("web", nginx,
+					process("/docker-entrypoint.sh", []*eventtype.KernelBehavior{
+						file("security_path_truncate", "/var/cache/nginx/[id]*.tmp", "", ""),
+						file("security_file_open", "/docker-entrypoint.sh", "-rwxr-xr-x", ""),
+					}),
+				),
+			}},
+			"pod:redis": {Name: "redis", Containers: map[string]*eventtype.Container{
+				"container:redis": container("redis", redis, cli),
+			}},
+		},
+	}
+	cluster.Namespaces["namespace:host"] = &eventtype.Namespace{
+		Name: "host",
+		Pods: map[string]*eventtype.Pod{
+			"pod:node-1": {Name: "node-1", Containers: map[string]*eventtype.Container{
+				"container:kubelet.service": container("kubelet.service", nil, process("/usr/bin/kubelet", nil)),
+			}},
+		},
+	}
+
+	return cluster
+}
+
+func TestExport(t *testing.T) {
+	profiles := Export(testCluster(), Options{})
+
+	if len(profiles) != 2 {
+		t.Fatalf("Export() returned %d profiles; want 2", len(profiles))
+	}
+
+	for _, profile := range profiles {
+		golden := filepath.Join("testdata", profile.Name)
+		if *update {
+			if err := os.WriteFile(golden, []byte(profile.Policy), 0o644); err != nil {
+				t.Fatalf("failed to update golden file: %v", err)
+			}
+		}
+
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("failed to read golden file: %v", err)
+		}
+		if profile.Policy != string(want) {
+			t.Errorf("profile %s does not match %s, run go test -update to review the difference\n%s", profile.Name, golden, profile.Policy)
+		}
+	}
+}
+
+func TestExportAnnotations(t *testing.T) {
+	profiles := Export(testCluster(), Options{Prefix: "prod"})
+
+	if profiles[0].Name != "prod-docker-io-library-nginx" {
+		t.Fatalf("profile name = %s; want prod-docker-io-library-nginx", profiles[0].Name)
+	}
+
+	want := map[string]map[string]string{
+		"default/nginx-1": {AnnotationPrefix + "nginx": "localhost/prod-docker-io-library-nginx"},
+		"default/nginx-2": {AnnotationPrefix + "web": "localhost/prod-docker-io-library-nginx"},
+	}
+	if !reflect.DeepEqual(profiles[0].Annotations, want) {
+		t.Errorf("annotations = %v; want %v", profiles[0].Annotations, want)
+	}
+}
+
+func TestExportComplain(t *testing.T) {
+	profiles := Export(testCluster(), Options{Complain: true})
+
+	want := "profile rbp-redis flags=(attach_disconnected,mediate_deleted,complain) {"
+	if got := profiles[1].Policy; !strings.Contains(got, want) {
+		t.Errorf("policy = %s; want %s", got, want)
+	}
+}
+
+func TestPathRule(t *testing.T) {
+	tests := []struct {
+		path        string
+		permissions string
+		want        string
+	}{
+		{"/etc/passwd", "r", "/etc/passwd r"},
+		{"/var/cache/[id]*.tmp", "w", `/var/cache/\[id\]\*.tmp w`},
+		{"/srv/my site/index.html", "r", `"/srv/my site/index.html" r`},
+	}
+
+	for _, tt := range tests {
+		if got := pathRule(tt.path, tt.permissions); got != tt.want {
+			t.Errorf("pathRule(%q, %q) = %q; want %q", tt.path, tt.permissions, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package eventexport
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultPrefix prefixes the names of the generated lists, macros and profiles.
+const DefaultPrefix = "rbp"
+
+var identifierPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Name returns the name of the generated object of an image repository, the prefix, or
+// DefaultPrefix when empty, followed by the lower cased repository with every run of other
+// characters than letters and digits replaced by separator, e.g. rbp_docker_io_nginx.
+func Name(prefix string, repository string, separator string) string {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	return prefix + separator + strings.Trim(identifierPattern.ReplaceAllString(strings.ToLower(repository), separator), separator)
+}
+
+// AddTo adds value to the set of key, creating it on first use.
+func AddTo(sets map[string]map[string]bool, key string, value string) {
+	if sets[key] == nil {
+		sets[key] = map[string]bool{}
+	}
+	sets[key][value] = true
+}
+
+// SortedKeys returns the keys of the map in sorted order.
+func SortedKeys[V any](set map[string]V) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,220 @@
+package eventexportfalco
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	eventexport "runtime-behavior-profiler/pkg/event/export"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultPriority is the priority of the generated rules.
+	DefaultPriority = "WARNING"
+
+	// commLength is the length the kernel truncates command names to, as matched by proc.name.
+	commLength = 15
+)
+
+var (
+	// placeholderPattern matches the values masked by the argument normalizer, e.g. <num>.
+	placeholderPattern = regexp.MustCompile(`<(uuid|time|ip|tmp|num|hex)>`)
+)
+
+type Options struct {
+	// Prefix of the names of the generated lists and macros, defaults to eventexport.DefaultPrefix.
+	Prefix string
+	// Priority of the generated rules, defaults to DefaultPriority.
+	Priority string
+}
+
+// imageProfile is the process tree learned for an image, merged across the containers running it.
+type imageProfile struct {
+	repository string
+	// binaries are the command names of the processes.
+	binaries map[string]bool
+	// roots are the command names of the processes whose parent is outside the container.
+	roots map[string]bool
+	// parents are the command names of the parents of each command name.
+	parents map[string]map[string]bool
+	// arguments are the argument patterns of each command name.
+	arguments map[string]map[string]bool
+}
+
+// Export returns Falco rules enforcing the process trees learned for every image of the
+// cluster. The profiles of the containers running the same image repository are merged.
+func Export(cluster *eventtype.Cluster, options Options) string {
+	profiles := map[string]*imageProfile{}
+
+	cluster.ForEachContainer(func(namespace *eventtype.Namespace, pod *eventtype.Pod, container *eventtype.Container) {
+		repository := container.Image.FullName()
+		if repository == "" {
+			return
+		}
+
+		profile, ok := profiles[repository]
+		if !ok {
+			profile = &imageProfile{
+				repository: repository,
+				binaries:   map[string]bool{},
+				roots:      map[string]bool{},
+				parents:    map[string]map[string]bool{},
+				arguments:  map[string]map[string]bool{},
+			}
+			profiles[repository] = profile
+		}
+		profile.add(container.Processes, "")
+	})
+
+	repositories := make([]string, 0, len(profiles))
+	for repository := range profiles {
+		repositories = append(repositories, repository)
+	}
+	sort.Strings(repositories)
+
+	var rules strings.Builder
+	rules.WriteString("# Falco rules generated by runtime-behavior-profiler from learned process trees.\n\n")
+	for _, repository := range repositories {
+		profiles[repository].write(&rules, options)
+	}
+
+	return strings.TrimRight(rules.String(), "\n") + "\n"
+}
+
+// add records the processes and their descendants, parent is the command name of their
+// parent or empty for the processes started by the container runtime.
+func (profile *imageProfile) add(processes map[string]*eventtype.Process, parent string) {
+	for _, process := range processes {
+		name := commName(process.Binary)
+		profile.binaries[name] = true
+
+		if parent == "" {
+			profile.roots[name] = true
+		} else {
+			eventexport.AddTo(profile.parents, name, parent)
+		}
+		eventexport.AddTo(profile.arguments, name, process.Arguments)
+
+		profile.add(process.ChildProcesses, name)
+	}
+}
+
+func (profile *imageProfile) write(rules *strings.Builder, options Options) {
+	priority := options.Priority
+	if priority == "" {
+		priority = DefaultPriority
+	}
+
+	name := eventexport.Name(options.Prefix, profile.repository, "_")
+	binaries := name + "_binaries"
+	container := name + "_container"
+	lineage := name + "_known_lineage"
+	arguments := name + "_known_arguments"
+	output := "(proc=%proc.name parent=%proc.pname cmdline=%proc.cmdline container=%container.name image=%container.image.repository k8s.ns=%k8s.ns.name k8s.pod=%k8s.pod.name)"
+
+	fmt.Fprintf(rules, "# Learned profile of %s\n", profile.repository)
+
+	fmt.Fprintf(rules, "- list: %s\n", binaries)
+	fmt.Fprintf(rules, "  items: [%s]\n\n", strings.Join(quoteAll(eventexport.SortedKeys(profile.binaries)), ", "))
+
+	fmt.Fprintf(rules, "- macro: %s\n", container)
+	fmt.Fprintf(rules, "  condition: (container and container.image.repository = %s)\n\n", quote(profile.repository))
+
+	// A command seen as a root may be started by any parent, other commands only by the
+	// parents they were learned with.
+	var lineageConditions []string
+	for _, binary := range eventexport.SortedKeys(profile.binaries) {
+		if profile.roots[binary] {
+			lineageConditions = append(lineageConditions, fmt.Sprintf("proc.name = %s", quote(binary)))
+			continue
+		}
+		lineageConditions = append(lineageConditions, fmt.Sprintf("(proc.name = %s and proc.pname in (%s))",
+			quote(binary), strings.Join(quoteAll(eventexport.SortedKeys(profile.parents[binary])), ", ")))
+	}
+	writeMacro(rules, lineage, lineageConditions)
+
+	var argumentConditions []string
+	for _, binary := range eventexport.SortedKeys(profile.binaries) {
+		var patterns []string
+		for _, pattern := range eventexport.SortedKeys(profile.arguments[binary]) {
+			patterns = append(patterns, argumentCondition(pattern))
+		}
+		argumentConditions = append(argumentConditions, fmt.Sprintf("(proc.name = %s and (%s))", quote(binary), strings.Join(patterns, " or ")))
+	}
+	writeMacro(rules, arguments, argumentConditions)
+
+	writeRule(rules, fmt.Sprintf("Unexpected process in %s", profile.repository),
+		fmt.Sprintf("A process that is not part of the learned profile of %s was spawned", profile.repository),
+		fmt.Sprintf("spawned_process and %s and not proc.name in (%s)", container, binaries),
+		"Process not in the learned profile "+output, priority)
+
+	writeRule(rules, fmt.Sprintf("Unexpected parent in %s", profile.repository),
+		fmt.Sprintf("A learned process of %s was spawned by a parent it was not learned with", profile.repository),
+		fmt.Sprintf("spawned_process and %s and proc.name in (%s) and not %s", container, binaries, lineage),
+		"Process spawned by an unexpected parent "+output, priority)
+
+	writeRule(rules, fmt.Sprintf("Unexpected arguments in %s", profile.repository),
+		fmt.Sprintf("A learned process of %s was spawned with arguments it was not learned with", profile.repository),
+		fmt.Sprintf("spawned_process and %s and proc.name in (%s) and not %s", container, binaries, arguments),
+		"Process spawned with unexpected arguments "+output, priority)
+}
+
+// argumentCondition matches learned arguments, arguments generalized by the normalizer are
+// matched with a glob where every masked value is a wildcard and the rest is matched literally.
+func argumentCondition(arguments string) string {
+	if !placeholderPattern.MatchString(arguments) {
+		return fmt.Sprintf("proc.args = %s", quote(arguments))
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`).Replace(arguments)
+	return fmt.Sprintf("proc.args glob %s", quote(placeholderPattern.ReplaceAllString(escaped, "*")))
+}
+
+func writeMacro(rules *strings.Builder, name string, conditions []string) {
+	fmt.Fprintf(rules, "- macro: %s\n", name)
+	fmt.Fprintf(rules, "  condition: >\n")
+	for i, condition := range conditions {
+		if i < len(conditions)-1 {
+			condition += " or"
+		}
+		fmt.Fprintf(rules, "    %s\n", condition)
+	}
+	rules.WriteString("\n")
+}
+
+func writeRule(rules *strings.Builder, name string, desc string, condition string, output string, priority string) {
+	fmt.Fprintf(rules, "- rule: %s\n", name)
+	fmt.Fprintf(rules, "  desc: %s\n", yamlQuote(desc))
+	fmt.Fprintf(rules, "  condition: %s\n", yamlQuote(condition))
+	fmt.Fprintf(rules, "  output: %s\n", yamlQuote(output))
+	fmt.Fprintf(rules, "  priority: %s\n", priority)
+	fmt.Fprintf(rules, "  tags: [container, process, runtime-behavior-profiler]\n\n")
+}
+
+// commName returns the name proc.name reports for a binary.
+func commName(binary string) string {
+	name := path.Base(binary)
+	if len(name) > commLength {
+		name = name[:commLength]
+	}
+	return name
+}
+
+// quote returns value as a string literal of a Falco condition.
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+func quoteAll(values []string) []string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = quote(value)
+	}
+	return quoted
+}
+
+// yamlQuote returns value as a single quoted YAML scalar.
+func yamlQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
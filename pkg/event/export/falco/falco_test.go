@@ -0,0 +1,154 @@
+package eventexportfalco
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func process(binary string, arguments string, children ...*eventtype.Process) *eventtype.Process {
+	process := &eventtype.Process{
+		Binary:         binary,
+		Arguments:      arguments,
+		ChildProcesses: map[string]*eventtype.Process{},
+	}
+	for _, child := range children {
+		process.ChildProcesses[child.GetKey()] = child
+	}
+	return process
+}
+
+func container(name string, image *eventtype.Image, processes ...*eventtype.Process) *eventtype.Container {
+	container := &eventtype.Container{
+		Name:      name,
+		Image:     image,
+		Processes: map[string]*eventtype.Process{},
+	}
+	for _, process := range processes {
+		container.Processes[process.GetKey()] = process
+	}
+	return container
+}
+
+func testCluster() *eventtype.Cluster {
+	nginx := &eventtype.Image{Repo: "library/nginx", Tag: "1.27", Registry: &eventtype.Registry{Name: "docker.io"}}
+	redis := &eventtype.Image{Repo: "redis", Tag: "latest", Registry: &eventtype.Registry{}}
+
+	cluster := eventtype.NewCluster("test-cluster")
+	cluster.Namespaces["namespace:default"] = &eventtype.Namespace{
+		Name: "default",
+		Pods: map[string]*eventtype.Pod{
+			"pod:nginx-1": {Name: "nginx-1", Containers: map[string]*eventtype.Container{
+				"container:nginx": container("nginx", nginx,
+					process("/docker-entrypoint.sh", "nginx -g daemon off;",
+						process("/bin/sh", `-c echo "ready"`),
+						process("/usr/sbin/nginx", "-g daemon off;"),
+					),
+				),
+			}},
+			"pod:nginx-2": {Name: "nginx-2", Containers: map[string]*eventtype.Container{
+				"container:nginx": container("nginx", nginx,
+					process("/docker-entrypoint.sh", "nginx -g daemon off;",
+						process("/usr/local/bin/nginx-log-rotator", "--keep <num>"),
+					),
+				),
+			}},
+			"pod:redis": {Name: "redis", Containers: map[string]*eventtype.Container{
+				"container:redis": container("redis", redis,
+					process("/usr/local/bin/redis-server", "*:6379"),
+					process("/usr/local/bin/redis-cli", "ping",
+						process("/bin/sh", ""),
+					),
+				),
+			}},
+		},
+	}
+	cluster.Namespaces["namespace:host"] = &eventtype.Namespace{
+		Name: "host",
+		Pods: map[string]*eventtype.Pod{
+			"pod:node-1": {Name: "node-1", Containers: map[string]*eventtype.Container{
+				"container:kubelet.service": container("kubelet.service", nil, process("/usr/bin/kubelet", "")),
+			}},
+		},
+	}
+
+	return cluster
+}
+
+func TestExport(t *testing.T) {
+	rules := Export(testCluster(), Options{})
+
+	golden := filepath.Join("testdata", "rules.yaml")
+	if *update {
+		if err := os.WriteFile(golden, []byte(rules), 0o644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	if rules != string(want) {
+		t.Errorf("Export() does not match %s, run go test -update to review the difference\n%s", golden, rules)
+	}
+}
+
+func TestExportOptions(t *testing.T) {
+	rules := Export(testCluster(), Options{Prefix: "prod", Priority: "CRITICAL"})
+
+	golden := filepath.Join("testdata", "rules-options.yaml")
+	if *update {
+		if err := os.WriteFile(golden, []byte(rules), 0o644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	if rules != string(want) {
+		t.Errorf("Export() does not match %s, run go test -update to review the difference\n%s", golden, rules)
+	}
+}
+
+func TestArgumentCondition(t *testing.T) {
+	tests := []struct {
+		arguments string
+		want      string
+	}{
+		{"-g daemon off;", `proc.args = "-g daemon off;"`},
+		{"--keep *.log", `proc.args = "--keep *.log"`},
+		{"--keep <num>", `proc.args glob "--keep *"`},
+		{"--match [a-z]*.log? --keep <num>", `proc.args glob "--match \\[a-z]\\*.log\\? --keep *"`},
+		{`C:\logs <num>`, `proc.args glob "C:\\\\logs *"`},
+	}
+
+	for _, tt := range tests {
+		if got := argumentCondition(tt.arguments); got != tt.want {
+			t.Errorf("argumentCondition(%q) = %s; want %s", tt.arguments, got, tt.want)
+		}
+	}
+}
+
+func TestCommName(t *testing.T) {
+	tests := []struct {
+		binary string
+		want   string
+	}{
+		{"/usr/sbin/nginx", "nginx"},
+		{"/usr/local/bin/nginx-log-rotator", "nginx-log-rotat"},
+		{"sh", "sh"},
+	}
+
+	for _, tt := range tests {
+		if got := commName(tt.binary); got != tt.want {
+			t.Errorf("commName(%q) = %q; want %q", tt.binary, got, tt.want)
+		}
+	}
+}
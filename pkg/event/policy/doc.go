@@ -0,0 +1,37 @@
+// Package eventpolicy evaluates organizational rules written in CEL against incoming events
+// and against the learned profiles, e.g. "no container in namespace payments may spawn a
+// shell" or "only images from registry X may open outbound 443".
+//
+// A rule is a CEL condition that is true when its input violates the rule. Every input
+// describes one process of a container with the following variables:
+//
+//	source      string        "event" for an incoming event, "profile" for a learned process
+//	event       string        kind of the incoming event: exec, exit, library, symbol or
+//	                          kernel, empty for a learned process
+//	cluster     string        cluster name, empty when the event does not name it
+//	pod         map           name and namespace of the pod, the name without the suffix
+//	                          of its replica set or daemon set as in the profile
+//	container   map           name, image, registry, repository and tag of the container
+//	process     map           binary, name (the base name of the binary), arguments,
+//	                          parent (binary of the parent, empty for a root process),
+//	                          ancestors (binaries from the oldest ancestor to the parent)
+//	                          and capabilities (effective capabilities, e.g. CAP_NET_RAW)
+//	behaviors   list of maps  kernel behaviours with kind, function, path, permission, access,
+//	                          write (true when a file is modified), family, protocol,
+//	                          address, port, capability and module, fields a behaviour
+//	                          does not report are empty, false or 0
+//	libraries   list          paths of the shared objects loaded by the process
+//
+// An event carries at most the one behaviour or library it reports, a learned process
+// carries every behaviour and library of its profile. A rule not testing event is evaluated
+// against every event of a process, its exec as well as its exit, library, symbol and
+// kernel events, event in ["", "exec"] limits it to the exec and the learned process.
+// For example:
+//
+//	event in ["", "exec"] && pod.namespace == "payments" && process.name in ["sh", "bash", "dash"]
+//	container.registry != "registry.example.com" && behaviors.exists(b, b.kind == "NETWORK" && b.port == 443)
+//
+// Violations are reported as POLICY_VIOLATION deviations naming the rule in their value,
+// unless the rule sets another type. The built-in detection rules returned by
+// DetectionRules report SUSPICIOUS_BEHAVIOR deviations tagged with MITRE ATT&CK techniques.
+package eventpolicy
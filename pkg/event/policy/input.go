@@ -0,0 +1,224 @@
+package eventpolicy
+
+import (
+	"path"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"runtime-behavior-profiler/pkg/util"
+	"time"
+)
+
+// input is a process in its container, as evaluated by the rules.
+type input struct {
+	source string
+	// event is the kind of the incoming event, empty for a learned process.
+	event     string
+	cluster   string
+	node      string
+	namespace string
+	// pod is the name of the pod as in the profile, see eventtype.Pod.GetName.
+	pod       string
+	container string
+	image     *eventtype.Image
+	process   *eventtype.Process
+	// ancestors are the binaries of the ancestors, from the oldest to the parent.
+	ancestors    []string
+	capabilities []string
+	// time is when the event happened, zero when the source does not report it.
+	time      time.Time
+	behaviors []*eventtype.KernelBehavior
+	libraries []string
+}
+
+// eventInput returns the input describing the process of an event.
+func eventInput(rawEvent eventtype.IEvent) (*input, error) {
+	namespace, err := rawEvent.GetNamespace()
+	if err != nil {
+		return nil, err
+	}
+	pod, err := rawEvent.GetPod()
+	if err != nil {
+		return nil, err
+	}
+	container, err := rawEvent.GetContainer()
+	if err != nil {
+		return nil, err
+	}
+	process, err := rawEvent.GetProcess()
+	if err != nil {
+		return nil, err
+	}
+	source, err := rawEvent.GetSource()
+	if err != nil {
+		return nil, err
+	}
+
+	input := &input{
+		source:    ScopeEvent,
+		event:     EventExec,
+		namespace: namespace.Name,
+		pod:       pod.GetName(),
+		container: container.Name,
+		process:   process,
+	}
+	if source != nil {
+		input.cluster = source.ClusterName
+		input.node = source.NodeName
+		input.time = source.Time
+	}
+	if container.Image != nil {
+		registry, repo, tag := util.ExtractImageParts(container.Image.Repo)
+		input.image = &eventtype.Image{Repo: repo, Tag: tag, Registry: &eventtype.Registry{Name: registry}}
+	}
+	if process.Credentials != nil {
+		input.capabilities = process.Credentials.Capabilities
+	}
+
+	if ancestryEvent, ok := rawEvent.(eventtype.IAncestryEvent); ok {
+		ancestors, err := ancestryEvent.GetAncestors()
+		if err != nil {
+			return nil, err
+		}
+		for _, ancestor := range ancestors {
+			input.ancestors = append(input.ancestors, ancestor.Binary)
+		}
+	} else if parent, err := rawEvent.GetParentProcess(); err == nil && parent != nil && parent.Binary != "" {
+		input.ancestors = []string{parent.Binary}
+	}
+
+	if _, ok := rawEvent.(eventtype.IExitEvent); ok {
+		input.event = EventExit
+	}
+	if _, ok := rawEvent.(eventtype.IUprobeEvent); ok {
+		input.event = EventSymbol
+	}
+	if kernelEvent, ok := rawEvent.(eventtype.IKernelEvent); ok {
+		input.event = EventKernel
+		behavior, err := kernelEvent.GetKernelBehavior()
+		if err != nil {
+			return nil, err
+		}
+		if behavior != nil {
+			input.behaviors = []*eventtype.KernelBehavior{behavior}
+		}
+	}
+	if loaderEvent, ok := rawEvent.(eventtype.ILoaderEvent); ok {
+		input.event = EventLibrary
+		library, err := loaderEvent.GetLibrary()
+		if err != nil {
+			return nil, err
+		}
+		if library != nil {
+			input.libraries = []string{library.Path}
+		}
+	}
+
+	return input, nil
+}
+
+// connection returns the destination of the network behaviour of an event, empty for other
+// events and for a learned process, whose rule may be about any of its behaviours.
+func (input *input) connection() string {
+	if input.source != ScopeEvent || len(input.behaviors) != 1 {
+		return ""
+	}
+	return input.behaviors[0].Connection()
+}
+
+// activation returns the variables of the input document.
+func (input *input) activation() map[string]any {
+	container := map[string]string{
+		"name":       input.container,
+		"image":      "",
+		"registry":   "",
+		"repository": "",
+		"tag":        "",
+	}
+	if image := input.image; image != nil {
+		container["repository"] = image.Repo
+		container["tag"] = image.Tag
+		container["image"] = image.FullName()
+		if image.Registry != nil {
+			container["registry"] = image.Registry.Name
+		}
+		if image.Tag != "" {
+			container["image"] += ":" + image.Tag
+		}
+	}
+
+	parent := ""
+	if len(input.ancestors) > 0 {
+		parent = input.ancestors[len(input.ancestors)-1]
+	}
+	process := map[string]any{
+		"binary":       input.process.Binary,
+		"name":         path.Base(input.process.Binary),
+		"arguments":    input.process.Arguments,
+		"parent":       parent,
+		"ancestors":    nonNil(input.ancestors),
+		"capabilities": nonNil(input.capabilities),
+	}
+
+	behaviors := make([]map[string]any, 0, len(input.behaviors))
+	for _, behavior := range input.behaviors {
+		behaviors = append(behaviors, behaviorInput(behavior))
+	}
+
+	return map[string]any{
+		"source":    input.source,
+		"event":     input.event,
+		"cluster":   input.cluster,
+		"pod":       map[string]string{"name": input.pod, "namespace": input.namespace},
+		"container": container,
+		"process":   process,
+		"behaviors": behaviors,
+		"libraries": nonNil(input.libraries),
+	}
+}
+
+// behaviorInput flattens a kernel behaviour, every field is set so that rules can test any
+// of them whatever the kind of the behaviour.
+func behaviorInput(behavior *eventtype.KernelBehavior) map[string]any {
+	input := map[string]any{
+		"kind":       string(behavior.Kind),
+		"function":   behavior.Function,
+		"path":       "",
+		"permission": "",
+		"access":     "",
+		"family":     "",
+		"protocol":   "",
+		"address":    "",
+		"port":       int64(0),
+		"capability": "",
+		"module":     "",
+		"write":      behavior.IsWrite(),
+	}
+
+	switch {
+	case behavior.Exec != nil:
+		input["path"] = behavior.Exec.Path
+	case behavior.File != nil:
+		input["path"] = behavior.File.Path
+		input["permission"] = behavior.File.Permission
+		input["access"] = behavior.File.Access
+	case behavior.Network != nil:
+		input["family"] = behavior.Network.Family
+		input["protocol"] = behavior.Network.Protocol
+		input["address"] = behavior.Network.DestinationAddr
+		input["port"] = int64(behavior.Network.DestinationPort)
+	case behavior.Capability != nil:
+		input["capability"] = behavior.Capability.Name
+	case behavior.Module != nil:
+		input["module"] = behavior.Module.Name
+	case behavior.Mount != nil:
+		input["path"] = behavior.Mount.Target
+	}
+
+	return input
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
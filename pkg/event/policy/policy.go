@@ -0,0 +1,260 @@
+package eventpolicy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"sort"
+	"time"
+
+	"github.com/google/cel-go/cel"
+)
+
+const (
+	// ScopeEvent restricts a rule to incoming events.
+	ScopeEvent = "event"
+	// ScopeProfile restricts a rule to the learned profiles.
+	ScopeProfile = "profile"
+)
+
+// Kinds of incoming events, as set in the event variable of the input document.
+const (
+	EventExec    = "exec"
+	EventExit    = "exit"
+	EventLibrary = "library"
+	EventSymbol  = "symbol"
+	EventKernel  = "kernel"
+)
+
+// Rule is an organizational rule, Condition is a CEL expression that is true when its input
+// violates the rule.
+type Rule struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Scope restricts the rule to ScopeEvent or ScopeProfile, empty evaluates it against both.
+	Scope     string `json:"scope,omitempty"`
+	Condition string `json:"condition"`
+	// Type of the reported deviations, defaults to POLICY_VIOLATION.
+	Type eventtype.DeviationType `json:"type,omitempty"`
+	// Techniques are the MITRE ATT&CK technique IDs attached to the reported deviations.
+	Techniques []string `json:"techniques,omitempty"`
+}
+
+// Policy is a set of compiled rules.
+type Policy struct {
+	Rules []*Rule `json:"rules"`
+
+	programs []cel.Program
+}
+
+// LoadRules reads a JSON document holding the rules of a policy, e.g.
+// {"rules": [{"name": "no-shell-in-payments", "condition": "pod.namespace == \"payments\" && process.name == \"sh\""}]}.
+func LoadRules(path string) ([]*Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &Policy{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", path, err)
+	}
+	return policy.Rules, nil
+}
+
+// LoadPolicy compiles the rules read by LoadRules.
+func LoadPolicy(path string) (*Policy, error) {
+	rules, err := LoadRules(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewPolicy(rules)
+}
+
+// NewPolicy compiles the rules against the input document described in the package
+// documentation and returns an error naming every invalid rule.
+func NewPolicy(rules []*Rule) (*Policy, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("source", cel.StringType),
+		cel.Variable("event", cel.StringType),
+		cel.Variable("cluster", cel.StringType),
+		cel.Variable("pod", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("container", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("process", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("behaviors", cel.ListType(cel.MapType(cel.StringType, cel.DynType))),
+		cel.Variable("libraries", cel.ListType(cel.StringType)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &Policy{Rules: rules}
+	names := map[string]bool{}
+	var errs []error
+	for _, rule := range rules {
+		switch {
+		case rule.Name == "":
+			errs = append(errs, fmt.Errorf("rule %q has no name", rule.Condition))
+			continue
+		case names[rule.Name]:
+			errs = append(errs, fmt.Errorf("rule %s is defined twice", rule.Name))
+			continue
+		case rule.Scope != "" && rule.Scope != ScopeEvent && rule.Scope != ScopeProfile:
+			errs = append(errs, fmt.Errorf("rule %s has an invalid scope %q", rule.Name, rule.Scope))
+			continue
+		}
+		names[rule.Name] = true
+
+		ast, issues := env.Compile(rule.Condition)
+		if issues.Err() != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", rule.Name, issues.Err()))
+			continue
+		}
+		if ast.OutputType() != cel.BoolType {
+			errs = append(errs, fmt.Errorf("rule %s: condition is a %s, not a bool", rule.Name, ast.OutputType()))
+			continue
+		}
+
+		program, err := env.Program(ast)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", rule.Name, err))
+			continue
+		}
+		policy.programs = append(policy.programs, program)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return policy, nil
+}
+
+// EvaluateCluster evaluates the rules against every learned process of the cluster and
+// returns the violations as deviations scored against the cluster and tagged with its ATT&CK
+// mapping. Rules failing to evaluate are reported in the error without stopping the
+// evaluation of the others.
+func (policy *Policy) EvaluateCluster(cluster *eventtype.Cluster) ([]*eventtype.Deviation, error) {
+	var deviations []*eventtype.Deviation
+	var errs []error
+
+	cluster.ForEachContainer(func(namespace *eventtype.Namespace, pod *eventtype.Pod, container *eventtype.Container) {
+		var walk func(processes map[string]*eventtype.Process, ancestors []string)
+		walk = func(processes map[string]*eventtype.Process, ancestors []string) {
+			for _, key := range sortedKeys(processes) {
+				process := processes[key]
+				// The profile names pods as eventtype.Pod.GetName does for an event.
+				input := &input{
+					source:    ScopeProfile,
+					cluster:   cluster.Name,
+					namespace: namespace.Name,
+					pod:       pod.Name,
+					container: container.Name,
+					image:     container.Image,
+					process:   process,
+					ancestors: ancestors,
+				}
+				for _, key := range sortedKeys(process.KernelBehaviors) {
+					input.behaviors = append(input.behaviors, process.KernelBehaviors[key])
+				}
+				for _, key := range sortedKeys(process.Libraries) {
+					input.libraries = append(input.libraries, process.Libraries[key].Path)
+				}
+				if process.Privileges != nil {
+					input.capabilities = sortedKeys(process.Privileges.Capabilities)
+				}
+
+				violations, err := policy.evaluate(input, process.LastSeen)
+				deviations = append(deviations, violations...)
+				if err != nil {
+					errs = append(errs, err)
+				}
+
+				walk(process.ChildProcesses, append(ancestors[:len(ancestors):len(ancestors)], process.Binary))
+			}
+		}
+		walk(container.Processes, nil)
+	})
+	for _, deviation := range deviations {
+		cluster.ScoreDeviation(deviation)
+		cluster.Attack.TagDeviation(deviation)
+	}
+
+	return deviations, errors.Join(errs...)
+}
+
+// EvaluateEvent evaluates the rules against an incoming event and returns the violations
+// as deviations, at the time of the event or now when the source does not report it. The
+// deviations carry the techniques of their rule, their tactics are left to the ATT&CK
+// mapping of the cluster, see Sink.
+func (policy *Policy) EvaluateEvent(rawEvent eventtype.IEvent) ([]*eventtype.Deviation, error) {
+	input, err := eventInput(rawEvent)
+	if err != nil {
+		return nil, err
+	}
+	at := input.time
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return policy.evaluate(input, at)
+}
+
+func (policy *Policy) evaluate(input *input, at time.Time) ([]*eventtype.Deviation, error) {
+	var activation map[string]any
+	var deviations []*eventtype.Deviation
+	var errs []error
+
+	for i, rule := range policy.Rules {
+		if rule.Scope != "" && rule.Scope != input.source {
+			continue
+		}
+		if activation == nil {
+			activation = input.activation()
+		}
+
+		out, _, err := policy.programs[i].Eval(activation)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", rule.Name, err))
+			continue
+		}
+		if violated, ok := out.Value().(bool); !ok || !violated {
+			continue
+		}
+
+		message := rule.Description
+		if message == "" {
+			message = fmt.Sprintf("%s violates policy rule %s", input.process.Binary, rule.Name)
+		}
+		deviationType := rule.Type
+		if deviationType == "" {
+			deviationType = eventtype.DeviationPolicyViolation
+		}
+		deviations = append(deviations, &eventtype.Deviation{
+			Type:       deviationType,
+			Cluster:    input.cluster,
+			Namespace:  input.namespace,
+			Pod:        input.pod,
+			Container:  input.container,
+			Node:       input.node,
+			Binary:     input.process.Binary,
+			Arguments:  input.process.Arguments,
+			Value:      rule.Name,
+			Message:    message,
+			Techniques: rule.Techniques,
+			Connection: input.connection(),
+			Time:       at,
+		})
+	}
+
+	return deviations, errors.Join(errs...)
+}
+
+func sortedKeys[V any](set map[string]V) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,324 @@
+package eventpolicy
+
+import (
+	"os"
+	"path/filepath"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEvent is a minimal IEvent used to drive the policy in tests.
+type testEvent struct {
+	namespace string
+	pod       string
+	image     string
+	parent    string
+	process   *eventtype.Process
+	time      time.Time
+}
+
+// testKernelEvent is a minimal IKernelEvent.
+type testKernelEvent struct {
+	testEvent
+	behavior *eventtype.KernelBehavior
+}
+
+// testExitEvent is a minimal IExitEvent.
+type testExitEvent struct {
+	testEvent
+}
+
+func (e *testEvent) GetNamespace() (*eventtype.Namespace, error) {
+	return &eventtype.Namespace{Name: e.namespace, Pods: map[string]*eventtype.Pod{}}, nil
+}
+
+func (e *testEvent) GetPod() (*eventtype.Pod, error) {
+	return &eventtype.Pod{Name: e.pod, Containers: map[string]*eventtype.Container{}}, nil
+}
+
+func (e *testEvent) GetContainer() (*eventtype.Container, error) {
+	return &eventtype.Container{Name: "checkout", Image: &eventtype.Image{Repo: e.image}, Processes: map[string]*eventtype.Process{}}, nil
+}
+
+func (e *testEvent) GetParentProcess() (*eventtype.Process, error) {
+	return &eventtype.Process{Binary: e.parent}, nil
+}
+
+func (e *testEvent) GetProcess() (*eventtype.Process, error) {
+	return e.process, nil
+}
+
+func (e *testEvent) GetSource() (*eventtype.EventSource, error) {
+	return &eventtype.EventSource{ClusterName: "prod", NodeName: "node-1", Time: e.time}, nil
+}
+
+func (e *testExitEvent) GetExit() (*eventtype.ProcessExit, error) {
+	return &eventtype.ProcessExit{}, nil
+}
+
+func (e *testKernelEvent) GetKernelBehavior() (*eventtype.KernelBehavior, error) {
+	return e.behavior, nil
+}
+
+func shellEvent(namespace string) *testEvent {
+	return &testEvent{
+		namespace: namespace,
+		pod:       "checkout-1",
+		image:     "registry.example.com/payments/checkout:1.2",
+		parent:    "/app/checkout",
+		process:   &eventtype.Process{Binary: "/bin/sh", Arguments: "-c id"},
+		time:      time.Date(2024, 12, 2, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func connectEvent(image string, port uint32) *testKernelEvent {
+	return &testKernelEvent{
+		testEvent: testEvent{
+			namespace: "payments",
+			pod:       "checkout-2",
+			image:     image,
+			parent:    "/sbin/tini",
+			process:   &eventtype.Process{Binary: "/app/checkout"},
+		},
+		behavior: &eventtype.KernelBehavior{
+			Kind:     eventtype.KernelBehaviorNetwork,
+			Function: "tcp_connect",
+			Network:  &eventtype.NetworkBehavior{Family: "AF_INET", Protocol: "IPPROTO_TCP", DestinationAddr: "203.0.113.7", DestinationPort: port},
+		},
+	}
+}
+
+func testPolicy(t *testing.T) *Policy {
+	t.Helper()
+
+	policy, err := NewPolicy([]*Rule{
+		{
+			Name:        "no-shell-in-payments",
+			Description: "No container in namespace payments may spawn a shell",
+			Condition:   `event in ["", "exec"] && pod.namespace == "payments" && process.name in ["sh", "bash", "dash"]`,
+		},
+		{
+			Name:      "egress-443-from-trusted-registry",
+			Condition: `container.registry != "registry.example.com" && behaviors.exists(b, b.kind == "NETWORK" && b.port == 443)`,
+		},
+		{
+			Name:      "checkout-children",
+			Scope:     ScopeProfile,
+			Condition: `process.parent == "/app/checkout"`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to compile policy: %v", err)
+	}
+	return policy
+}
+
+func TestEvaluateEvent(t *testing.T) {
+	policy := testPolicy(t)
+
+	tests := []struct {
+		name  string
+		event eventtype.IEvent
+		want  []string
+	}{
+		{"shell in payments", shellEvent("payments"), []string{"no-shell-in-payments"}},
+		{"shell elsewhere", shellEvent("default"), nil},
+		{"shell exit in payments", &testExitEvent{*shellEvent("payments")}, nil},
+		{"443 from trusted registry", connectEvent("registry.example.com/payments/checkout:1.2", 443), nil},
+		{"443 from docker hub", connectEvent("docker.io/library/checkout:1.2", 443), []string{"egress-443-from-trusted-registry"}},
+		{"80 from docker hub", connectEvent("docker.io/library/checkout:1.2", 80), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deviations, err := policy.EvaluateEvent(tt.event)
+			if err != nil {
+				t.Fatalf("EvaluateEvent() error: %v", err)
+			}
+
+			var got []string
+			for _, deviation := range deviations {
+				if deviation.Type != eventtype.DeviationPolicyViolation {
+					t.Errorf("deviation type = %s; want %s", deviation.Type, eventtype.DeviationPolicyViolation)
+				}
+				if deviation.Cluster != "prod" || deviation.Node != "node-1" || deviation.Namespace != "payments" {
+					t.Errorf("deviation = %+v; want cluster prod, node node-1 and namespace payments", deviation)
+				}
+				if source, _ := tt.event.GetSource(); !source.Time.IsZero() && !deviation.Time.Equal(source.Time) {
+					t.Errorf("deviation time = %s; want the event time %s", deviation.Time, source.Time)
+				}
+				got = append(got, deviation.Value)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("violations = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateCluster(t *testing.T) {
+	cluster := eventtype.NewCluster("prod")
+	cluster.LearningPeriod = 0
+
+	sink := NewSink(cluster, testPolicy(t))
+	result, err := sink.SinkEvent(shellEvent("payments"))
+	if err != nil {
+		t.Fatalf("failed to sink event: %v", err)
+	}
+	if len(result.Deviations) != 1 || result.Deviations[0].Value != "no-shell-in-payments" {
+		t.Fatalf("sink deviations = %+v; want no-shell-in-payments", result.Deviations)
+	}
+	// The only workload of the cluster runs the shell.
+	if result.Deviations[0].Rarity != 0 {
+		t.Errorf("sink deviation rarity = %v; want 0", result.Deviations[0].Rarity)
+	}
+	result, err = sink.SinkEvent(connectEvent("docker.io/library/checkout:1.2", 443))
+	if err != nil {
+		t.Fatalf("failed to sink event: %v", err)
+	}
+	// /app/checkout runs in both workloads, the destination is only connected to by one.
+	if len(result.Deviations) != 1 || result.Deviations[0].Connection != "IPPROTO_TCP 203.0.113.7:443" || result.Deviations[0].Rarity <= 0 {
+		t.Errorf("sink deviations = %+v; want egress-443-from-trusted-registry scored by its connection", result.Deviations)
+	}
+
+	deviations, err := sink.Policy.EvaluateCluster(cluster)
+	if err != nil {
+		t.Fatalf("EvaluateCluster() error: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, deviation := range deviations {
+		got[deviation.Value] = deviation.Binary
+		// The shell runs in one of the two workloads, /app/checkout in both.
+		if rare := deviation.Binary == "/bin/sh"; rare != (deviation.Rarity > 0) {
+			t.Errorf("%s by %s rarity = %v", deviation.Value, deviation.Binary, deviation.Rarity)
+		}
+	}
+	want := map[string]string{
+		"no-shell-in-payments":             "/bin/sh",
+		"egress-443-from-trusted-registry": "/app/checkout",
+		"checkout-children":                "/bin/sh",
+	}
+	if len(got) != len(want) {
+		t.Errorf("violations = %v; want %v", got, want)
+	}
+	for rule, binary := range want {
+		if got[rule] != binary {
+			t.Errorf("violation of %s by %q; want %q", rule, got[rule], binary)
+		}
+	}
+}
+
+func TestPodNameOfEventAndProfile(t *testing.T) {
+	policy, err := NewPolicy([]*Rule{{Name: "checkout-pods", Condition: `pod.name == "checkout"`}})
+	if err != nil {
+		t.Fatalf("failed to compile policy: %v", err)
+	}
+	cluster := eventtype.NewCluster("prod")
+	cluster.LearningPeriod = 0
+
+	event := shellEvent("payments")
+	event.pod = "checkout-7d9c8f6b5c-x2x9k"
+	result, err := NewSink(cluster, policy).SinkEvent(event)
+	if err != nil {
+		t.Fatalf("failed to sink event: %v", err)
+	}
+	profiled, err := policy.EvaluateCluster(cluster)
+	if err != nil {
+		t.Fatalf("EvaluateCluster() error: %v", err)
+	}
+
+	// A rule matches the same pod name whether it is evaluated against the event or the profile.
+	deviations := append(result.Deviations, profiled...)
+	if len(deviations) != 3 {
+		t.Fatalf("deviations = %+v; want the event and its two profiled processes", deviations)
+	}
+	for _, deviation := range deviations {
+		if deviation.Pod != "checkout" {
+			t.Errorf("deviation pod = %q; want checkout", deviation.Pod)
+		}
+	}
+}
+
+func TestSinkTagsViolationsWithTheClusterMapping(t *testing.T) {
+	mapping, err := eventtype.ParseAttackMapping([]byte(`{
+		"tactics": {"Execution": "TA0002", "Persistence": "TA0003"},
+		"techniques": {
+			"T1059.004": {"name": "Unix Shell", "tactics": ["Execution"]},
+			"T1505.003": {"name": "Web Shell", "tactics": ["Persistence"]}
+		},
+		"deviations": {"POLICY_VIOLATION": ["T1505.003"]}
+	}`))
+	if err != nil {
+		t.Fatalf("failed to parse mapping: %v", err)
+	}
+	policy, err := NewPolicy([]*Rule{{Name: "shells", Condition: `process.name == "sh"`, Techniques: []string{"T1059.004"}}})
+	if err != nil {
+		t.Fatalf("failed to compile policy: %v", err)
+	}
+	cluster := eventtype.NewCluster("prod")
+	cluster.Attack = mapping
+
+	result, err := NewSink(cluster, policy).SinkEvent(shellEvent("payments"))
+	if err != nil {
+		t.Fatalf("failed to sink event: %v", err)
+	}
+	profiled, err := policy.EvaluateCluster(cluster)
+	if err != nil {
+		t.Fatalf("EvaluateCluster() error: %v", err)
+	}
+
+	deviations := append(result.Deviations, profiled...)
+	if len(deviations) != 2 {
+		t.Fatalf("deviations = %+v; want the event and its profiled process", deviations)
+	}
+	for _, deviation := range deviations {
+		if got := strings.Join(deviation.Techniques, ","); got != "T1059.004,T1505.003" {
+			t.Errorf("%s techniques = %s; want those of the rule and of the deviation type", deviation.Value, got)
+		}
+		if got := strings.Join(deviation.Tactics, ","); got != "Execution,Persistence" {
+			t.Errorf("%s tactics = %s; want Execution,Persistence", deviation.Value, got)
+		}
+	}
+}
+
+func TestNewPolicyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule *Rule
+		want string
+	}{
+		{"syntax", &Rule{Name: "syntax", Condition: `process.name ==`}, "rule syntax"},
+		{"not a bool", &Rule{Name: "string", Condition: `process.binary`}, "not a bool"},
+		{"unknown variable", &Rule{Name: "unknown", Condition: `image == "nginx"`}, "undeclared reference"},
+		{"scope", &Rule{Name: "scope", Scope: "host", Condition: `true`}, "invalid scope"},
+		{"no name", &Rule{Condition: `true`}, "has no name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPolicy([]*Rule{tt.rule})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewPolicy() error = %v; want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	data := `{"rules": [{"name": "no-shell", "condition": "process.name == \"sh\""}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("failed to write policy: %v", err)
+	}
+
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadPolicy() error: %v", err)
+	}
+	if len(policy.Rules) != 1 || policy.Rules[0].Name != "no-shell" {
+		t.Errorf("rules = %+v; want no-shell", policy.Rules)
+	}
+}
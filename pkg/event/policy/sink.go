@@ -0,0 +1,52 @@
+package eventpolicy
+
+import (
+	"log"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+)
+
+// Sink evaluates the policy against every event sunk into Next, violations are appended to
+// the deviations of the sink result, scored by Next when it is an IDeviationScorer and tagged
+// with its ATT&CK mapping when it is an IAttackMapper.
+type Sink struct {
+	Next   eventtype.IEventSink
+	Policy *Policy
+}
+
+func NewSink(next eventtype.IEventSink, policy *Policy) *Sink {
+	return &Sink{
+		Next:   next,
+		Policy: policy,
+	}
+}
+
+// SinkEvent implements eventtype.IEventSink. A rule failing to evaluate is logged and does
+// not fail the event, which is already part of the profile.
+func (sink *Sink) SinkEvent(rawEvent eventtype.IEvent) (*eventtype.SinkResult, error) {
+	result, err := sink.Next.SinkEvent(rawEvent)
+	if err != nil || rawEvent == nil || result.Operation == eventtype.SinkOperationIgnored {
+		return result, err
+	}
+
+	deviations, err := sink.Policy.EvaluateEvent(rawEvent)
+	if err != nil {
+		log.Printf("failed to evaluate policy: %v", err)
+	}
+	if scorer, ok := sink.Next.(eventtype.IDeviationScorer); ok {
+		for _, deviation := range deviations {
+			scorer.ScoreDeviation(deviation)
+		}
+	}
+	for _, deviation := range deviations {
+		sink.AttackMappingOf(deviation).TagDeviation(deviation)
+	}
+	result.Deviations = append(result.Deviations, deviations...)
+
+	return result, nil
+}
+
+// AttackMappingOf implements eventtype.IAttackMapper with the mapping of Next, nil when Next
+// does not tag its deviations.
+func (sink *Sink) AttackMappingOf(deviation *eventtype.Deviation) *eventtype.AttackMapping {
+	return eventtype.AttackMappingOf(sink.Next, deviation)
+}
@@ -0,0 +1,276 @@
+package eventprocessorkafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	eventprocessorocsftype "runtime-behavior-profiler/pkg/event/processor/ocsf/type"
+	eventprocessortetragon "runtime-behavior-profiler/pkg/event/processor/tetragon"
+	eventprocessortetragontype "runtime-behavior-profiler/pkg/event/processor/tetragon/type"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"strings"
+	"sync"
+
+	"github.com/cilium/tetragon/api/v1/tetragon"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// FormatAuto detects the format of every record, OCSF when its top level keys name an
+	// OCSF schema version and Tetragon otherwise.
+	FormatAuto = ""
+	// FormatOCSF reads records as OCSF events.
+	FormatOCSF = "ocsf"
+	// FormatTetragon reads records as Tetragon GetEventsResponse JSON, as exported by tetragon.
+	FormatTetragon = "tetragon"
+)
+
+type KafkaConsumerOptions struct {
+	Brokers []string
+	Topics  []string
+	Group   string
+	Format  string
+	// Output selects how deviations are printed, see eventprocessortetragontype.OutputOCSF.
+	Output string
+	// Host profiles the Tetragon events of processes that do not run in a pod, they are
+	// dropped otherwise.
+	Host bool
+	// DeadLetters receives the records that cannot be sunk as NDJSON. When nil, consumption
+	// stops at the first such record so it is delivered again once the problem is fixed.
+	DeadLetters io.Writer
+}
+
+// DeadLetter is a record that could not be sunk, with the reason it was rejected.
+type DeadLetter struct {
+	Topic     string          `json:"topic"`
+	Partition int32           `json:"partition"`
+	Offset    int64           `json:"offset"`
+	Reason    string          `json:"reason"`
+	Event     json.RawMessage `json:"event,omitempty"`
+	// Text holds the record when it is not valid JSON.
+	Text string `json:"text,omitempty"`
+}
+
+// kafkaConsumer sinks the events published to Kafka topics. The partitions of a fetch are
+// consumed concurrently and the records of a partition in order, producers keying records by
+// container therefore get the events of each container sunk in the order they were produced.
+// The offset of a record is committed only once it has been sunk or written to the dead letters.
+type kafkaConsumer struct {
+	Options KafkaConsumerOptions
+	Sink    eventtype.IEventSink
+
+	mu sync.Mutex
+	// hosts are the host profiles by node name, only used when host events are profiled.
+	hosts map[string]*eventprocessortetragontype.Host
+}
+
+func GetDefaultOptions() KafkaConsumerOptions {
+	return KafkaConsumerOptions{
+		Brokers: []string{"localhost:9092"},
+		Topics:  []string{"runtime-events"},
+		Group:   "runtime-behavior-profiler",
+	}
+}
+
+func NewConsumer(sink eventtype.IEventSink) *kafkaConsumer {
+	return NewConsumerWithOptions(sink, GetDefaultOptions())
+}
+
+func NewConsumerWithOptions(sink eventtype.IEventSink, options KafkaConsumerOptions) *kafkaConsumer {
+	return &kafkaConsumer{
+		Options: options,
+		Sink:    sink,
+		hosts:   map[string]*eventprocessortetragontype.Host{},
+	}
+}
+
+// Consume sinks the records of the topics until ctx is done or a record can neither be sunk
+// nor written to the dead letters.
+func (consumer *kafkaConsumer) Consume(ctx context.Context) error {
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(consumer.Options.Brokers...),
+		kgo.ConsumerGroup(consumer.Options.Group),
+		kgo.ConsumeTopics(consumer.Options.Topics...),
+		kgo.DisableAutoCommit(),
+		// Partitions are not revoked while a fetch is being sunk, so its offsets can still be committed.
+		kgo.BlockRebalanceOnPoll(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create Kafka client for %s: %w", strings.Join(consumer.Options.Brokers, ","), err)
+	}
+	defer client.Close()
+
+	fmt.Fprintf(os.Stderr, "Consuming Kafka topics %s as group %s\n", strings.Join(consumer.Options.Topics, ","), consumer.Options.Group)
+
+	for {
+		fetches := client.PollFetches(ctx)
+		if ctx.Err() != nil || fetches.IsClientClosed() {
+			return nil
+		}
+
+		var fetchErr error
+		fetches.EachError(func(topic string, partition int32, err error) {
+			fetchErr = fmt.Errorf("failed to fetch %s partition %d: %w", topic, partition, err)
+		})
+		if fetchErr != nil {
+			return fetchErr
+		}
+
+		var wg sync.WaitGroup
+		var sinkErr error
+		var committable []*kgo.Record
+		fetches.EachPartition(func(partition kgo.FetchTopicPartition) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				sunk, err := consumer.consumePartition(partition.Records)
+
+				consumer.mu.Lock()
+				defer consumer.mu.Unlock()
+				committable = append(committable, sunk...)
+				if err != nil && sinkErr == nil {
+					sinkErr = err
+				}
+			}()
+		})
+		wg.Wait()
+
+		if len(committable) > 0 {
+			// The records were sunk, commit them even when ctx is done meanwhile.
+			if err := client.CommitRecords(context.WithoutCancel(ctx), committable...); err != nil {
+				return fmt.Errorf("failed to commit offsets: %w", err)
+			}
+		}
+		client.AllowRebalance()
+
+		if sinkErr != nil {
+			return sinkErr
+		}
+	}
+}
+
+// consumePartition sinks the records of one partition in order and returns the records that
+// were handled, it stops at the first record that cannot be handled.
+func (consumer *kafkaConsumer) consumePartition(records []*kgo.Record) ([]*kgo.Record, error) {
+	for i, record := range records {
+		if err := consumer.consumeRecord(record); err != nil {
+			return records[:i], err
+		}
+	}
+	return records, nil
+}
+
+func (consumer *kafkaConsumer) consumeRecord(record *kgo.Record) error {
+	event, err := consumer.decode(record.Value)
+	if err != nil {
+		return consumer.deadLetter(record, fmt.Sprintf("malformed event: %v", err))
+	}
+
+	sinkResult, err := consumer.Sink.SinkEvent(event)
+	if err != nil {
+		return consumer.deadLetter(record, fmt.Sprintf("incomplete event: %v", err))
+	}
+
+	for _, deviation := range sinkResult.Deviations {
+		consumer.printDeviation(deviation, event)
+	}
+
+	return nil
+}
+
+// decode returns the event of a record, nil for Tetragon events that are not profiled.
+func (consumer *kafkaConsumer) decode(value []byte) (eventtype.IEvent, error) {
+	format := consumer.Options.Format
+	if format == FormatAuto {
+		format = detectFormat(value)
+	}
+
+	switch format {
+	case FormatOCSF:
+		event := &eventprocessorocsftype.OCSFEvent{}
+		if err := json.Unmarshal(value, event); err != nil {
+			return nil, err
+		}
+		return event, nil
+	case FormatTetragon:
+		response := &tetragon.GetEventsResponse{}
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(value, response); err != nil {
+			return nil, err
+		}
+		return eventprocessortetragon.ProcessResponse(response, consumer.getHost(response.GetNodeName())), nil
+	}
+
+	return nil, fmt.Errorf("unknown format %s", format)
+}
+
+// getHost returns the host profile of the node, or nil when host events are not profiled.
+// The records come from other nodes, the local proc filesystem cannot tell their units.
+func (consumer *kafkaConsumer) getHost(nodeName string) *eventprocessortetragontype.Host {
+	if !consumer.Options.Host {
+		return nil
+	}
+
+	consumer.mu.Lock()
+	defer consumer.mu.Unlock()
+
+	host, ok := consumer.hosts[nodeName]
+	if !ok {
+		host = eventprocessortetragontype.NewHost(nodeName, "")
+		consumer.hosts[nodeName] = host
+	}
+	return host
+}
+
+// detectFormat returns FormatOCSF when a top level key of the record names an OCSF schema version.
+func detectFormat(value []byte) string {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(value, &keys); err == nil {
+		for key := range keys {
+			if strings.HasPrefix(key, "ocsf_") {
+				return FormatOCSF
+			}
+		}
+	}
+	return FormatTetragon
+}
+
+func (consumer *kafkaConsumer) deadLetter(record *kgo.Record, reason string) error {
+	if consumer.Options.DeadLetters == nil {
+		return fmt.Errorf("%s partition %d offset %d: %s", record.Topic, record.Partition, record.Offset, reason)
+	}
+
+	letter := &DeadLetter{
+		Topic:     record.Topic,
+		Partition: record.Partition,
+		Offset:    record.Offset,
+		Reason:    reason,
+	}
+	if json.Valid(record.Value) {
+		letter.Event = record.Value
+	} else {
+		letter.Text = string(record.Value)
+	}
+
+	consumer.mu.Lock()
+	defer consumer.mu.Unlock()
+
+	return json.NewEncoder(consumer.Options.DeadLetters).Encode(letter)
+}
+
+func (consumer *kafkaConsumer) printDeviation(deviation *eventtype.Deviation, event eventtype.IEvent) {
+	var deviationJSON []byte
+	if consumer.Options.Output == eventprocessortetragontype.OutputOCSF {
+		evidence, ok := event.(*eventprocessorocsftype.OCSFEvent)
+		if !ok {
+			evidence = eventprocessortetragontype.ToOCSF(event)
+		}
+		deviationJSON, _ = json.Marshal(eventprocessorocsftype.NewSecurityFindingWithAttack(deviation, evidence, eventtype.AttackMappingOf(consumer.Sink, deviation)))
+	} else {
+		deviationJSON, _ = json.Marshal(deviation)
+	}
+	fmt.Println(string(deviationJSON))
+}
@@ -0,0 +1,263 @@
+package eventprocessorkafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	eventprocessortetragontype "runtime-behavior-profiler/pkg/event/processor/tetragon/type"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cilium/tetragon/api/v1/tetragon"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const testTopic = "runtime-events"
+
+// recordingSink sinks events into a cluster and reports every sink on a channel.
+type recordingSink struct {
+	cluster *eventtype.Cluster
+	sunk    chan string
+}
+
+func (sink *recordingSink) SinkEvent(rawEvent eventtype.IEvent) (*eventtype.SinkResult, error) {
+	result, err := sink.cluster.SinkEvent(rawEvent)
+	if err == nil && rawEvent != nil {
+		process, _ := rawEvent.GetProcess()
+		sink.sunk <- process.Binary
+	}
+	return result, err
+}
+
+func newRecordingSink() *recordingSink {
+	return &recordingSink{cluster: eventtype.NewCluster("test-cluster"), sunk: make(chan string, 16)}
+}
+
+func (sink *recordingSink) wait(t *testing.T, want ...string) {
+	t.Helper()
+	for _, binary := range want {
+		select {
+		case got := <-sink.sunk:
+			if got != binary {
+				t.Fatalf("sunk %s; want %s", got, binary)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for %s", binary)
+		}
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for the concurrent writes of the consumer.
+type syncBuffer struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (buffer *syncBuffer) Write(p []byte) (int, error) {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	return buffer.buffer.Write(p)
+}
+
+func (buffer *syncBuffer) String() string {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	return buffer.buffer.String()
+}
+
+func newTestCluster(t *testing.T) *kfake.Cluster {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(2, testTopic))
+	if err != nil {
+		t.Fatalf("failed to start fake Kafka cluster: %v", err)
+	}
+	t.Cleanup(cluster.Close)
+	return cluster
+}
+
+func produce(t *testing.T, brokers []string, key string, values ...string) {
+	t.Helper()
+	client, err := kgo.NewClient(kgo.SeedBrokers(brokers...), kgo.DefaultProduceTopic(testTopic))
+	if err != nil {
+		t.Fatalf("failed to create producer: %v", err)
+	}
+	defer client.Close()
+
+	for _, value := range values {
+		record := &kgo.Record{Key: []byte(key), Value: []byte(value)}
+		if err := client.ProduceSync(context.Background(), record).FirstErr(); err != nil {
+			t.Fatalf("failed to produce record: %v", err)
+		}
+	}
+}
+
+func ocsfRecord(binary string) string {
+	return `{"ocsf_1_0_0": {"process_activity": {
+		"actor": {"process": {"name": "` + binary + `", "uid": "` + binary + `", "container": {"name": "nginx", "image": {"name": "nginx"}}}},
+		"resources": [{"type": "kubernetes.namespace", "name": "default"}, {"type": "kubernetes.pod", "name": "nginx"}]
+	}}}`
+}
+
+func tetragonRecord(t *testing.T, binary string) string {
+	response := &tetragon.GetEventsResponse{
+		NodeName: "node-1",
+		Event: &tetragon.GetEventsResponse_ProcessExec{ProcessExec: &tetragon.ProcessExec{
+			Process: &tetragon.Process{
+				Binary: binary,
+				ExecId: binary,
+				Pod: &tetragon.Pod{
+					Namespace: "default",
+					Name:      "nginx",
+					Container: &tetragon.Container{Name: "nginx", Image: &tetragon.Image{Name: "nginx"}},
+				},
+			},
+		}},
+	}
+	value, err := protojson.Marshal(response)
+	if err != nil {
+		t.Fatalf("failed to marshal tetragon event: %v", err)
+	}
+	return string(value)
+}
+
+func TestConsume(t *testing.T) {
+	cluster := newTestCluster(t)
+	brokers := cluster.ListenAddrs()
+
+	produce(t, brokers, "nginx",
+		ocsfRecord("/bin/sh"),
+		`{"ocsf_1_0_0": {`,
+		`{"ocsf_1_0_0": {"process_activity": {"resources": []}}}`,
+		tetragonRecord(t, "/usr/bin/curl"),
+	)
+
+	options := GetDefaultOptions()
+	options.Brokers = brokers
+	options.Topics = []string{testTopic}
+	options.Group = "test"
+	deadLetters := &syncBuffer{}
+	options.DeadLetters = deadLetters
+
+	sink := newRecordingSink()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- NewConsumerWithOptions(sink, options).Consume(ctx) }()
+
+	// The records share a key, they are sunk in the order they were produced.
+	sink.wait(t, "/bin/sh", "/usr/bin/curl")
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Consume() error: %v", err)
+	}
+
+	var letters []*DeadLetter
+	for _, line := range strings.Split(strings.TrimSpace(deadLetters.String()), "\n") {
+		letter := &DeadLetter{}
+		if err := json.Unmarshal([]byte(line), letter); err != nil {
+			t.Fatalf("failed to unmarshal dead letter %q: %v", line, err)
+		}
+		letters = append(letters, letter)
+	}
+	if len(letters) != 2 {
+		t.Fatalf("dead letters = %d; want 2", len(letters))
+	}
+	if letters[0].Offset != 1 || !strings.HasPrefix(letters[0].Reason, "malformed event") || letters[0].Text == "" {
+		t.Errorf("dead letter = %+v; want the malformed record at offset 1", letters[0])
+	}
+	if letters[1].Offset != 2 || !strings.HasPrefix(letters[1].Reason, "incomplete event") {
+		t.Errorf("dead letter = %+v; want the incomplete record at offset 2", letters[1])
+	}
+
+	// The handled records were committed, a new consumer of the group only sees new records.
+	produce(t, brokers, "nginx", ocsfRecord("/bin/ls"))
+
+	sink = newRecordingSink()
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() { done <- NewConsumerWithOptions(sink, options).Consume(ctx) }()
+
+	sink.wait(t, "/bin/ls")
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Consume() error: %v", err)
+	}
+}
+
+func TestConsumeStopsWithoutDeadLetters(t *testing.T) {
+	cluster := newTestCluster(t)
+	brokers := cluster.ListenAddrs()
+
+	produce(t, brokers, "nginx", ocsfRecord("/bin/sh"), `not json`)
+
+	options := GetDefaultOptions()
+	options.Brokers = brokers
+	options.Topics = []string{testTopic}
+	options.Group = "test"
+
+	sink := newRecordingSink()
+	err := NewConsumerWithOptions(sink, options).Consume(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "offset 1: malformed event") {
+		t.Fatalf("Consume() = %v; want the malformed record at offset 1", err)
+	}
+	sink.wait(t, "/bin/sh")
+
+	// The malformed record was not committed and is delivered again.
+	err = NewConsumerWithOptions(newRecordingSink(), options).Consume(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "offset 1: malformed event") {
+		t.Errorf("Consume() = %v; want the malformed record delivered again", err)
+	}
+}
+
+func TestDecodeHostEvents(t *testing.T) {
+	response := &tetragon.GetEventsResponse{
+		NodeName: "node-2",
+		Event: &tetragon.GetEventsResponse_ProcessExec{ProcessExec: &tetragon.ProcessExec{
+			Process: &tetragon.Process{Binary: "/usr/sbin/sshd", ExecId: "sshd"},
+		}},
+	}
+	value, err := protojson.Marshal(response)
+	if err != nil {
+		t.Fatalf("failed to marshal tetragon event: %v", err)
+	}
+
+	options := GetDefaultOptions()
+	options.Format = FormatTetragon
+	if event, err := NewConsumerWithOptions(newRecordingSink(), options).decode(value); err != nil || event != nil {
+		t.Fatalf("decode() = %v, %v; want the host event dropped", event, err)
+	}
+
+	options.Host = true
+	event, err := NewConsumerWithOptions(newRecordingSink(), options).decode(value)
+	if err != nil || event == nil {
+		t.Fatalf("decode() = %v, %v; want the host event", event, err)
+	}
+	namespace, err := event.GetNamespace()
+	if err != nil || namespace.Name != eventprocessortetragontype.HostNamespace {
+		t.Errorf("GetNamespace() = %v, %v; want %s", namespace, err, eventprocessortetragontype.HostNamespace)
+	}
+	container, err := event.GetContainer()
+	if err != nil || container.Name != eventprocessortetragontype.HostContainer {
+		t.Errorf("GetContainer() = %v, %v; want %s", container, err, eventprocessortetragontype.HostContainer)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{`{"ocsf_1_0_0": {}}`, FormatOCSF},
+		{`{"ocsf_1_2_0": {}}`, FormatOCSF},
+		{`{"process_exec": {}, "node_name": "node-1"}`, FormatTetragon},
+		{`not json`, FormatTetragon},
+	}
+
+	for _, tt := range tests {
+		if got := detectFormat([]byte(tt.value)); got != tt.want {
+			t.Errorf("detectFormat(%s) = %q; want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	eventprocessorocsftype "runtime-behavior-profiler/pkg/event/processor/ocsf/type"
+	eventprocessortetragontype "runtime-behavior-profiler/pkg/event/processor/tetragon/type"
 	eventtype "runtime-behavior-profiler/pkg/event/type"
 )
 
@@ -11,7 +12,14 @@ import (
 // It extracts and organizes the event data into a hierarchical structure of namespaces,
 // pods, containers, and processes. If any of these entities do not exist in the profile,
 // they are created and added to the appropriate parent entity.
-func ProcessEvent(event *eventprocessorocsftype.OCSFEvent, cluster *eventtype.Cluster) (*eventtype.SinkResult, error) {
+func ProcessEvent(event *eventprocessorocsftype.OCSFEvent, cluster eventtype.IEventSink) (*eventtype.SinkResult, error) {
+	return ProcessEventWithOutput(event, cluster, "")
+}
+
+// ProcessEventWithOutput processes the event like ProcessEvent and prints the result in the
+// given output mode, the deviations as OCSF findings with the event as evidence for
+// eventprocessortetragontype.OutputOCSF and the sink result otherwise.
+func ProcessEventWithOutput(event *eventprocessorocsftype.OCSFEvent, cluster eventtype.IEventSink, output string) (*eventtype.SinkResult, error) {
 
 	// Add the event to the ClusterBehaviourProfile
 	sinkResult, err := cluster.SinkEvent(event)
@@ -20,6 +28,15 @@ func ProcessEvent(event *eventprocessorocsftype.OCSFEvent, cluster *eventtype.Cl
 		return nil, err
 	}
 
+	if output == eventprocessortetragontype.OutputOCSF {
+		for _, deviation := range sinkResult.Deviations {
+			finding := eventprocessorocsftype.NewSecurityFindingWithAttack(deviation, event, eventtype.AttackMappingOf(cluster, deviation))
+			findingJSON, _ := json.Marshal(finding)
+			fmt.Println(string(findingJSON))
+		}
+		return sinkResult, nil
+	}
+
 	// Pring the sinkResult in JSON
 	sinkResultJSON, _ := json.Marshal(sinkResult)
 
@@ -2,9 +2,10 @@ package eventprocessorocsf
 
 import (
 	"encoding/json"
+	"io"
 	"os"
-	eventprocessorocsftype "runtime-behavior-profiler/pkg/event/processor/ocsf/type"
 	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"strings"
 	"testing"
 )
 
@@ -12,35 +13,30 @@ func TestProcessEvent(t *testing.T) {
 
 	path := "../../../../testdata/raw_events.json"
 
-	// Read events from the JSON file
+	// Stream events from the JSON file
 	file, err := os.Open(path)
 	if err != nil {
 		t.Fatalf("failed to open file: %v", err)
 	}
 	defer file.Close()
 
-	var events []eventprocessorocsftype.OCSFEvent
-	data, err := os.ReadFile(path)
-	if err != nil {
-		t.Fatalf("failed to read file: %v", err)
-	}
-	if err := json.Unmarshal(data, &events); err != nil {
-		t.Fatalf("failed to unmarshal JSON: %v", err)
-	}
-
-	if len(events) == 0 {
-		t.Fatalf("no events found in the file")
-	}
-
 	cluster := eventtype.Cluster{
 		Name:       "test-cluster",
 		Namespaces: map[string]*eventtype.Namespace{},
 	}
 
 	// Process each event
-	for _, event := range events {
+	reader := NewEventReader(file)
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
 
-		_, err := ProcessEvent(&event, &cluster)
+		_, err = ProcessEvent(event, &cluster)
 
 		// fail test if error
 		if err != nil {
@@ -48,11 +44,45 @@ func TestProcessEvent(t *testing.T) {
 		}
 	}
 
+	if reader.Record() == 0 {
+		t.Fatalf("no events found in the file")
+	}
+
 	// Print
-	json, err := json.MarshalIndent(cluster, "", "  ")
+	json, err := json.MarshalIndent(&cluster, "", "  ")
 	if err != nil {
 		t.Fatalf("failed to marshal cluster behaviour profile: %v", err)
 	}
 
 	t.Log("\n" + string(json))
 }
+
+func TestProcessEventCountsExecutions(t *testing.T) {
+	// Sensors without process uids, e.g. file integrity monitors, report every activity of a
+	// process without telling its executions apart.
+	actor := `"time": 1733140800000, "actor": {"process": {"name": "/usr/bin/app", "container": {"name": "app", "image": {"name": "app"}}}},
+		"resources": [{"type": "kubernetes.namespace", "name": "default"}, {"type": "kubernetes.pod", "name": "app-7d9c"}]`
+	launch := `{"ocsf_1_0_0": {"process_activity": {"activity_id": 1, ` + actor + `}}}`
+	file := `{"ocsf_1_0_0": {"file_activity": {"activity_id": 2, "file": {"path": "/etc/app.conf"}, ` + actor + `}}}`
+	newerLaunch := `{"ocsf_1_2_0": {"process_activity": {"activity_id": 1, ` + actor + `}}}`
+
+	cluster := eventtype.NewCluster("test-cluster")
+	for _, event := range []string{launch, file, file, file, newerLaunch, file} {
+		if _, err := ProcessStream(strings.NewReader(compact(t, event)), cluster, nil); err != nil {
+			t.Fatalf("ProcessStream() error: %v", err)
+		}
+	}
+
+	var executions *eventtype.Rate
+	cluster.ForEachContainer(func(namespace *eventtype.Namespace, pod *eventtype.Pod, container *eventtype.Container) {
+		// The process has no parent process either, it is placed under the root node.
+		for _, root := range container.Processes {
+			for _, process := range root.ChildProcesses {
+				executions = process.Executions
+			}
+		}
+	})
+	if executions == nil || executions.MinuteCount != 2 {
+		t.Errorf("executions = %+v; want the two launches, not the file activities", executions)
+	}
+}
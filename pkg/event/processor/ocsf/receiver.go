@@ -0,0 +1,294 @@
+package eventprocessorocsf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EventsPath is the path of the HTTP endpoint receiving pushed events.
+	EventsPath = "/v1/events"
+	// DefaultMaxBodyBytes limits the decompressed size of a pushed request body.
+	DefaultMaxBodyBytes = 64 << 20
+	// maxRejected limits the rejected records listed in a response, all are counted.
+	maxRejected = 100
+)
+
+// Receiver processes the OCSF events pushed by sensors that cannot be polled, over HTTP or
+// as NDJSON over TCP, e.g. from a syslog forwarder.
+type Receiver struct {
+	Sink eventtype.IEventSink
+	// Token is the bearer token HTTP clients must present, empty disables authentication.
+	Token string
+	// MaxBodyBytes limits the decompressed size of HTTP request bodies, defaults to DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+	// MaxRecordBytes limits the size of a single event over HTTP and TCP, defaults to
+	// DefaultMaxRecordBytes.
+	MaxRecordBytes int
+	// DeadLetters receives the rejected records as NDJSON, may be nil.
+	DeadLetters io.Writer
+	// Output selects how the result of each event is printed, see ProcessEventWithOutput.
+	Output string
+
+	mu sync.Mutex
+}
+
+// ReceiverResponse is the JSON body answered to an HTTP push.
+type ReceiverResponse struct {
+	StreamResult
+	// Rejected lists the first rejected records of the request, without their event.
+	Rejected []*DeadLetter `json:"rejected,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+func NewReceiver(sink eventtype.IEventSink, token string) *Receiver {
+	return &Receiver{
+		Sink:           sink,
+		Token:          token,
+		MaxBodyBytes:   DefaultMaxBodyBytes,
+		MaxRecordBytes: DefaultMaxRecordBytes,
+	}
+}
+
+// Handler returns the HTTP handler serving EventsPath.
+func (receiver *Receiver) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(EventsPath, receiver)
+	return mux
+}
+
+// ServeHTTP processes the events of a POST request. The body is a single OCSF event, a JSON
+// array of events or NDJSON, optionally gzip compressed.
+func (receiver *Receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeResponse(w, http.StatusMethodNotAllowed, &ReceiverResponse{Error: "method not allowed"})
+		return
+	}
+
+	if !receiver.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="runtime-behavior-profiler"`)
+		writeResponse(w, http.StatusUnauthorized, &ReceiverResponse{Error: "invalid or missing bearer token"})
+		return
+	}
+
+	mediaType := ""
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			writeResponse(w, http.StatusUnsupportedMediaType, &ReceiverResponse{Error: fmt.Sprintf("invalid content type: %v", err)})
+			return
+		}
+	}
+	switch mediaType {
+	case "", "application/json", "application/x-ndjson", "application/ndjson", "text/plain":
+	default:
+		writeResponse(w, http.StatusUnsupportedMediaType, &ReceiverResponse{Error: fmt.Sprintf("unsupported content type %s", mediaType)})
+		return
+	}
+
+	maxBodyBytes := receiver.MaxBodyBytes
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = DefaultMaxBodyBytes
+	}
+
+	var body io.ReadCloser = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	switch strings.ToLower(r.Header.Get("Content-Encoding")) {
+	case "", "identity":
+	case "gzip":
+		decompressed, err := gzip.NewReader(body)
+		if err != nil {
+			writeResponse(w, http.StatusBadRequest, &ReceiverResponse{Error: fmt.Sprintf("invalid gzip body: %v", err)})
+			return
+		}
+		defer decompressed.Close()
+		body = http.MaxBytesReader(w, decompressed, maxBodyBytes)
+	default:
+		writeResponse(w, http.StatusUnsupportedMediaType, &ReceiverResponse{Error: fmt.Sprintf("unsupported content encoding %s", r.Header.Get("Content-Encoding"))})
+		return
+	}
+
+	response := &ReceiverResponse{}
+	var input io.Reader = body
+	var err error
+	if mediaType == "application/json" {
+		input, err = singleEventLine(body)
+	}
+
+	if err == nil {
+		var result *StreamResult
+		result, err = processStream(receiver.newEventReader(input), receiver.Sink, receiver.Output, func(letter *DeadLetter) error {
+			if len(response.Rejected) < maxRejected {
+				response.Rejected = append(response.Rejected, &DeadLetter{Record: letter.Record, Reason: letter.Reason})
+			}
+			return receiver.deadLetter(letter)
+		})
+		response.StreamResult = *result
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		response.Error = fmt.Sprintf("request body larger than %d bytes", maxBodyBytes)
+		writeResponse(w, http.StatusRequestEntityTooLarge, response)
+	case err != nil:
+		response.Error = err.Error()
+		writeResponse(w, http.StatusBadRequest, response)
+	case response.Records == 0:
+		response.Error = "no events in request body"
+		writeResponse(w, http.StatusBadRequest, response)
+	case response.Processed == 0:
+		response.Error = "no event could be processed"
+		writeResponse(w, http.StatusUnprocessableEntity, response)
+	default:
+		writeResponse(w, http.StatusOK, response)
+	}
+}
+
+// ListenAndServe serves the HTTP endpoint on address until ctx is done.
+func (receiver *Receiver) ListenAndServe(ctx context.Context, address string) error {
+	server := &http.Server{
+		Addr:              address,
+		Handler:           receiver.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Receiving OCSF events on http://%s%s\n", address, EventsPath)
+
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// ListenTCP processes the NDJSON events, or syslog messages carrying them, sent by the
+// connections accepted on address until ctx is done. TCP connections are not authenticated,
+// address should only be reachable by trusted forwarders, e.g. a loopback address.
+func (receiver *Receiver) ListenTCP(ctx context.Context, address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Receiving OCSF events on tcp://%s\n", listener.Addr())
+
+	return receiver.ServeTCP(ctx, listener)
+}
+
+// ServeTCP processes the events sent by the connections accepted by listener until it is closed.
+func (receiver *Receiver) ServeTCP(ctx context.Context, listener net.Listener) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+
+			result, err := processStream(receiver.newEventReader(conn), receiver.Sink, receiver.Output, receiver.deadLetter)
+			if err != nil && !errors.Is(err, net.ErrClosed) {
+				fmt.Fprintf(os.Stderr, "failed to receive events from %s after %d records: %v\n", conn.RemoteAddr(), result.Records, err)
+			}
+		}()
+	}
+}
+
+// newEventReader returns a reader of the events of input limited to MaxRecordBytes per event.
+func (receiver *Receiver) newEventReader(input io.Reader) *EventReader {
+	reader := NewEventReader(input)
+	if receiver.MaxRecordBytes > 0 {
+		reader.MaxRecordBytes = receiver.MaxRecordBytes
+	}
+	return reader
+}
+
+func (receiver *Receiver) authorized(r *http.Request) bool {
+	if receiver.Token == "" {
+		return true
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(receiver.Token)) == 1
+}
+
+func (receiver *Receiver) deadLetter(letter *DeadLetter) error {
+	if receiver.DeadLetters == nil {
+		return nil
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	return json.NewEncoder(receiver.DeadLetters).Encode(letter)
+}
+
+// singleEventLine compacts a JSON body holding a single, possibly indented, event onto one
+// line so it is read as NDJSON. Arrays are streamed, and bodies that are not a single JSON
+// value are left to the event reader which reports them per record.
+func singleEventLine(body io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(body)
+	for {
+		next, err := buffered.Peek(1)
+		if err != nil || next[0] == '[' {
+			return buffered, nil
+		}
+		if next[0] != ' ' && next[0] != '\t' && next[0] != '\r' && next[0] != '\n' {
+			break
+		}
+		buffered.ReadByte()
+	}
+
+	data, err := io.ReadAll(buffered)
+	if err != nil {
+		return nil, err
+	}
+
+	var line bytes.Buffer
+	if err := json.Compact(&line, data); err != nil {
+		return bytes.NewReader(data), nil
+	}
+	return &line, nil
+}
+
+func writeResponse(w http.ResponseWriter, status int, response *ReceiverResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
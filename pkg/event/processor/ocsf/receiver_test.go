@@ -0,0 +1,199 @@
+package eventprocessorocsf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"strings"
+	"testing"
+	"time"
+)
+
+func receiverEvent(pod string) string {
+	return strings.Replace(streamEvent, "nginx-7d9c", pod, 1)
+}
+
+func push(t *testing.T, server *httptest.Server, token string, contentType string, encoding string, body []byte) (int, *ReceiverResponse) {
+	t.Helper()
+
+	request, err := http.NewRequest(http.MethodPost, server.URL+EventsPath, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if encoding != "" {
+		request.Header.Set("Content-Encoding", encoding)
+	}
+
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatalf("failed to push events: %v", err)
+	}
+	defer response.Body.Close()
+
+	receiverResponse := &ReceiverResponse{}
+	if err := json.NewDecoder(response.Body).Decode(receiverResponse); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return response.StatusCode, receiverResponse
+}
+
+func gzipped(t *testing.T, data string) []byte {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write([]byte(data)); err != nil {
+		t.Fatalf("failed to compress body: %v", err)
+	}
+	writer.Close()
+	return buffer.Bytes()
+}
+
+func TestReceiverHTTP(t *testing.T) {
+	cluster := eventtype.NewCluster("test-cluster")
+	receiver := NewReceiver(cluster, "secret")
+	var deadLetters bytes.Buffer
+	receiver.DeadLetters = &deadLetters
+
+	server := httptest.NewServer(receiver.Handler())
+	defer server.Close()
+
+	ndjson := compact(t, receiverEvent("pod-1")) + "\n" + `{"ocsf_1_0_0": {` + "\n" + compact(t, receiverEvent("pod-2"))
+
+	tests := []struct {
+		name        string
+		token       string
+		contentType string
+		encoding    string
+		body        []byte
+		status      int
+		processed   int
+		rejected    int
+	}{
+		{"single event", "secret", "application/json", "", []byte(receiverEvent("pod-1")), http.StatusOK, 1, 0},
+		{"array", "secret", "application/json", "", []byte("[" + receiverEvent("pod-1") + "," + receiverEvent("pod-2") + "]"), http.StatusOK, 2, 0},
+		{"ndjson", "secret", "application/x-ndjson", "", []byte(ndjson), http.StatusOK, 2, 1},
+		{"gzip ndjson", "secret", "application/x-ndjson", "gzip", gzipped(t, ndjson), http.StatusOK, 2, 1},
+		{"missing token", "", "application/json", "", []byte(receiverEvent("pod-1")), http.StatusUnauthorized, 0, 0},
+		{"wrong token", "guess", "application/json", "", []byte(receiverEvent("pod-1")), http.StatusUnauthorized, 0, 0},
+		{"unsupported content type", "secret", "application/xml", "", []byte("<event/>"), http.StatusUnsupportedMediaType, 0, 0},
+		{"invalid gzip", "secret", "application/json", "gzip", []byte(receiverEvent("pod-1")), http.StatusBadRequest, 0, 0},
+		{"empty body", "secret", "application/json", "", nil, http.StatusBadRequest, 0, 0},
+		{"only malformed events", "secret", "application/x-ndjson", "", []byte("{\nnot json\n"), http.StatusUnprocessableEntity, 0, 2},
+		{"truncated array", "secret", "application/json", "", []byte("[" + receiverEvent("pod-1") + ", {"), http.StatusBadRequest, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, response := push(t, server, tt.token, tt.contentType, tt.encoding, tt.body)
+
+			if status != tt.status {
+				t.Errorf("status = %d; want %d (%s)", status, tt.status, response.Error)
+			}
+			if response.Processed != tt.processed || len(response.Rejected) != tt.rejected {
+				t.Errorf("response = %+v; want %d processed and %d rejected", response, tt.processed, tt.rejected)
+			}
+			if tt.status != http.StatusOK && response.Error == "" {
+				t.Errorf("response has no error")
+			}
+		})
+	}
+
+	if got := strings.Count(deadLetters.String(), "\n"); got != 4 {
+		t.Errorf("dead letters = %d; want 4", got)
+	}
+	if _, ok := cluster.Namespaces["namespace:default"].Pods["pod:pod-2"]; !ok {
+		t.Errorf("pod-2 not profiled")
+	}
+}
+
+func TestReceiverHTTPMethodAndSize(t *testing.T) {
+	receiver := NewReceiver(eventtype.NewCluster("test-cluster"), "")
+	receiver.MaxBodyBytes = 64
+
+	server := httptest.NewServer(receiver.Handler())
+	defer server.Close()
+
+	response, err := server.Client().Get(server.URL + EventsPath)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d; want 405", response.StatusCode)
+	}
+
+	status, _ := push(t, server, "", "application/x-ndjson", "", []byte(compact(t, streamEvent)))
+	if status != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d; want 413 for a body over the limit", status)
+	}
+}
+
+func TestReceiverTCP(t *testing.T) {
+	cluster := eventtype.NewCluster("test-cluster")
+	receiver := NewReceiver(cluster, "")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- receiver.ServeTCP(ctx, listener) }()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	fmt.Fprintln(conn, compact(t, receiverEvent("pod-1")))
+	fmt.Fprintln(conn, "<14>1 2024-12-02T12:00:00Z node-1 sensor - - - "+compact(t, receiverEvent("pod-2")))
+	conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		pods := podNames(cluster)
+		if pods["pod:pod-1"] && pods["pod:pod-2"] {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pods = %v; want pod-1 and pod-2", pods)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	listener.Close()
+	if err := <-done; err != nil {
+		t.Errorf("ServeTCP() error: %v", err)
+	}
+}
+
+// podNames returns the pod keys of the default namespace, reading the cluster as JSON so
+// that the profile is locked while it is read.
+func podNames(cluster *eventtype.Cluster) map[string]bool {
+	data, _ := json.Marshal(cluster)
+
+	var profile struct {
+		Namespaces map[string]struct {
+			Pods map[string]json.RawMessage `json:"pods"`
+		} `json:"namespaces"`
+	}
+	json.Unmarshal(data, &profile)
+
+	pods := map[string]bool{}
+	for key := range profile.Namespaces["namespace:default"].Pods {
+		pods[key] = true
+	}
+	return pods
+}
@@ -0,0 +1,309 @@
+package eventprocessorocsf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	eventprocessorocsftype "runtime-behavior-profiler/pkg/event/processor/ocsf/type"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+)
+
+// DefaultMaxRecordBytes limits the size of a single record read by an EventReader.
+const DefaultMaxRecordBytes = 1 << 20
+
+// errRecordTooLarge is the error of a record larger than the MaxRecordBytes of the reader.
+var errRecordTooLarge = errors.New("record too large")
+
+// EventReader reads OCSF events one at a time from an NDJSON stream or from a JSON array,
+// without loading the whole input in memory.
+type EventReader struct {
+	// MaxRecordBytes limits the size of a record, zero disables the limit. A longer NDJSON
+	// line is skipped and reported as a *RecordError, a longer array element ends reading.
+	MaxRecordBytes int
+
+	input *bufio.Reader
+	// decoder decodes the records of a JSON array from arrayInput, nil for NDJSON input.
+	decoder    *json.Decoder
+	arrayInput *limitedInput
+	started    bool
+	record     int
+	raw        json.RawMessage
+}
+
+// limitedInput fails reads past limit, the JSON array decoder reads from it so that a single
+// element cannot grow its buffer without bounds.
+type limitedInput struct {
+	input io.Reader
+	read  int64
+	limit int64
+}
+
+func (input *limitedInput) Read(p []byte) (int, error) {
+	if input.limit > 0 {
+		if input.read >= input.limit {
+			return 0, errRecordTooLarge
+		}
+		if remaining := input.limit - input.read; int64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+	n, err := input.input.Read(p)
+	input.read += int64(n)
+	return n, err
+}
+
+// RecordError is returned by EventReader.Next for a record that could not be decoded,
+// reading can continue with the next record.
+type RecordError struct {
+	Record int
+	Err    error
+}
+
+// DeadLetter is a record that could not be ingested, with the reason it was rejected.
+type DeadLetter struct {
+	Record int             `json:"record"`
+	Reason string          `json:"reason"`
+	Event  json.RawMessage `json:"event,omitempty"`
+	// Text holds the record when it is not valid JSON.
+	Text string `json:"text,omitempty"`
+}
+
+// StreamResult counts the records of a stream processed by ProcessStream.
+type StreamResult struct {
+	Records     int `json:"records"`
+	Processed   int `json:"processed"`
+	DeadLetters int `json:"dead_letters"`
+}
+
+func (err *RecordError) Error() string {
+	return fmt.Sprintf("record %d: %v", err.Record, err.Err)
+}
+
+func (err *RecordError) Unwrap() error {
+	return err.Err
+}
+
+// NewEventReader returns a reader of the events of input. The format is detected from the
+// first byte of the input, a JSON array when it is '[' and NDJSON otherwise.
+func NewEventReader(input io.Reader) *EventReader {
+	return &EventReader{input: bufio.NewReader(input), MaxRecordBytes: DefaultMaxRecordBytes}
+}
+
+// Next returns the next event. It returns io.EOF at the end of the input, a *RecordError for
+// a malformed record and any other error when the input cannot be read any further.
+func (reader *EventReader) Next() (*eventprocessorocsftype.OCSFEvent, error) {
+	if !reader.started {
+		reader.started = true
+		if err := reader.detectFormat(); err != nil {
+			return nil, err
+		}
+	}
+
+	var raw json.RawMessage
+	var err error
+	if reader.decoder != nil {
+		raw, err = reader.nextArrayRecord()
+	} else {
+		raw, err = reader.nextLine()
+	}
+	if errors.Is(err, errRecordTooLarge) && reader.decoder == nil {
+		reader.record++
+		reader.raw = nil
+		return nil, &RecordError{Record: reader.record, Err: fmt.Errorf("%w, longer than %d bytes", err, reader.MaxRecordBytes)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reader.record++
+	reader.raw = raw
+
+	event := &eventprocessorocsftype.OCSFEvent{}
+	if err := json.Unmarshal(raw, event); err != nil {
+		return nil, &RecordError{Record: reader.record, Err: err}
+	}
+
+	return event, nil
+}
+
+// Record returns the position of the last record read, starting at 1.
+func (reader *EventReader) Record() int {
+	return reader.record
+}
+
+// Raw returns the last record read as found in the input.
+func (reader *EventReader) Raw() json.RawMessage {
+	return reader.raw
+}
+
+func (reader *EventReader) detectFormat() error {
+	for {
+		next, err := reader.input.Peek(1)
+		if err != nil {
+			return err
+		}
+
+		switch next[0] {
+		case ' ', '\t', '\r', '\n':
+			reader.input.ReadByte()
+			continue
+		case '[':
+			reader.arrayInput = &limitedInput{input: reader.input}
+			reader.decoder = json.NewDecoder(reader.arrayInput)
+			_, err := reader.decoder.Token()
+			return err
+		}
+
+		return nil
+	}
+}
+
+// nextArrayRecord returns the next element of the JSON array. A syntax error cannot be
+// recovered from since the end of the broken element is unknown.
+func (reader *EventReader) nextArrayRecord() (json.RawMessage, error) {
+	if !reader.decoder.More() {
+		_, err := reader.decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("unterminated JSON array after record %d: %w", reader.record, io.ErrUnexpectedEOF)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	// The decoder may read ahead of the element up to the size of its buffer, twice the
+	// limit leaves room for it.
+	if reader.MaxRecordBytes > 0 {
+		reader.arrayInput.limit = reader.decoder.InputOffset() + 2*int64(reader.MaxRecordBytes)
+	}
+
+	var raw json.RawMessage
+	if err := reader.decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode record %d: %w", reader.record+1, err)
+	}
+
+	return raw, nil
+}
+
+// nextLine returns the next non empty line of the NDJSON input. The header of syslog messages,
+// e.g. "<14>1 2024-12-02T12:00:00Z node-1 sensor - - - ", is stripped so the event they carry
+// can be decoded.
+func (reader *EventReader) nextLine() (json.RawMessage, error) {
+	for {
+		line, err := reader.readLine()
+		if errors.Is(err, errRecordTooLarge) {
+			return nil, err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] == '<' {
+			if start := bytes.IndexAny(line, "{["); start > 0 {
+				line = line[start:]
+			}
+		}
+		if len(line) > 0 {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readLine reads the input up to the next newline. A line longer than MaxRecordBytes is
+// discarded as it is read and errRecordTooLarge returned.
+func (reader *EventReader) readLine() ([]byte, error) {
+	var line []byte
+	tooLarge := false
+	for {
+		fragment, err := reader.input.ReadSlice('\n')
+		if !tooLarge {
+			if reader.MaxRecordBytes > 0 && len(line)+len(fragment) > reader.MaxRecordBytes {
+				tooLarge, line = true, nil
+			} else {
+				line = append(line, fragment...)
+			}
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if tooLarge && (err == nil || err == io.EOF) {
+			return nil, errRecordTooLarge
+		}
+		return line, err
+	}
+}
+
+// ProcessStream processes every event of an NDJSON stream or JSON array. Records that are
+// malformed or that cannot be sunk, e.g. because they miss their namespace or container, are
+// written to deadLetters as NDJSON and do not stop the ingestion. deadLetters may be nil.
+func ProcessStream(input io.Reader, cluster eventtype.IEventSink, deadLetters io.Writer) (*StreamResult, error) {
+	return ProcessStreamWithOutput(input, cluster, deadLetters, "")
+}
+
+// ProcessStreamWithOutput processes the stream like ProcessStream and prints the result of
+// every event in the given output mode, see ProcessEventWithOutput.
+func ProcessStreamWithOutput(input io.Reader, cluster eventtype.IEventSink, deadLetters io.Writer, output string) (*StreamResult, error) {
+	var encoder *json.Encoder
+	if deadLetters != nil {
+		encoder = json.NewEncoder(deadLetters)
+	}
+
+	return processStream(NewEventReader(input), cluster, output, func(letter *DeadLetter) error {
+		if encoder == nil {
+			return nil
+		}
+		return encoder.Encode(letter)
+	})
+}
+
+// processStream processes every event of the reader in the given output mode and passes the
+// rejected records to deadLetter, ingestion stops when deadLetter fails.
+func processStream(reader *EventReader, cluster eventtype.IEventSink, output string, deadLetter func(letter *DeadLetter) error) (*StreamResult, error) {
+	result := &StreamResult{}
+
+	reject := func(reason string) error {
+		result.DeadLetters++
+		letter := &DeadLetter{
+			Record: reader.Record(),
+			Reason: reason,
+		}
+		if json.Valid(reader.Raw()) {
+			letter.Event = reader.Raw()
+		} else {
+			letter.Text = string(reader.Raw())
+		}
+		return deadLetter(letter)
+	}
+
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+
+		var recordErr *RecordError
+		if errors.As(err, &recordErr) {
+			result.Records++
+			if err := reject(fmt.Sprintf("malformed event: %v", recordErr.Err)); err != nil {
+				return result, err
+			}
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+
+		result.Records++
+		if _, err := ProcessEventWithOutput(event, cluster, output); err != nil {
+			if err := reject(fmt.Sprintf("incomplete event: %v", err)); err != nil {
+				return result, err
+			}
+			continue
+		}
+		result.Processed++
+	}
+}
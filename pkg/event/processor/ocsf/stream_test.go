@@ -0,0 +1,144 @@
+package eventprocessorocsf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"strings"
+	"testing"
+)
+
+const streamEvent = `{"ocsf_1_0_0": {"process_activity": {
+	"actor": {"process": {"name": "/bin/sh", "uid": "exec-1", "container": {"name": "nginx", "image": {"name": "nginx"}}}},
+	"resources": [{"type": "kubernetes.namespace", "name": "default"}, {"type": "kubernetes.pod", "name": "nginx-7d9c"}]
+}}}`
+
+func compact(t *testing.T, event string) string {
+	var buffer bytes.Buffer
+	if err := json.Compact(&buffer, []byte(event)); err != nil {
+		t.Fatalf("failed to compact event: %v", err)
+	}
+	return buffer.String()
+}
+
+func readDeadLetters(t *testing.T, output *bytes.Buffer) []*DeadLetter {
+	var letters []*DeadLetter
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		letter := &DeadLetter{}
+		if err := json.Unmarshal(scanner.Bytes(), letter); err != nil {
+			t.Fatalf("failed to unmarshal dead letter %q: %v", scanner.Text(), err)
+		}
+		letters = append(letters, letter)
+	}
+	return letters
+}
+
+func TestProcessStreamNDJSON(t *testing.T) {
+	event := compact(t, streamEvent)
+	input := strings.Join([]string{
+		event,
+		`{"ocsf_1_0_0": {`,
+		``,
+		`{"ocsf_1_0_0": {"process_activity": {"resources": []}}}`,
+		`{"ocsf_1_0_0": "not an object"}`,
+		event,
+	}, "\n")
+
+	cluster := eventtype.NewCluster("test-cluster")
+	var deadLetters bytes.Buffer
+
+	result, err := ProcessStream(strings.NewReader(input), cluster, &deadLetters)
+	if err != nil {
+		t.Fatalf("ProcessStream() error: %v", err)
+	}
+	if result.Records != 5 || result.Processed != 2 || result.DeadLetters != 3 {
+		t.Errorf("result = %+v; want 5 records, 2 processed and 3 dead letters", result)
+	}
+
+	letters := readDeadLetters(t, &deadLetters)
+	if len(letters) != 3 {
+		t.Fatalf("dead letters = %d; want 3", len(letters))
+	}
+	if letters[0].Record != 2 || !strings.HasPrefix(letters[0].Reason, "malformed event") || letters[0].Text != `{"ocsf_1_0_0": {` {
+		t.Errorf("dead letter = %+v; want the truncated record 2 as text", letters[0])
+	}
+	if letters[1].Record != 3 || !strings.HasPrefix(letters[1].Reason, "incomplete event") || len(letters[1].Event) == 0 {
+		t.Errorf("dead letter = %+v; want the incomplete record 3", letters[1])
+	}
+	if letters[2].Record != 4 || !strings.HasPrefix(letters[2].Reason, "malformed event") {
+		t.Errorf("dead letter = %+v; want the mistyped record 4", letters[2])
+	}
+
+	if _, ok := cluster.Namespaces["namespace:default"]; !ok {
+		t.Errorf("namespace default not profiled")
+	}
+}
+
+func TestProcessStreamJSONArray(t *testing.T) {
+	input := `[` + streamEvent + `, {"ocsf_1_0_0": []}, ` + streamEvent + `]`
+
+	var deadLetters bytes.Buffer
+	result, err := ProcessStream(strings.NewReader(input), eventtype.NewCluster("test-cluster"), &deadLetters)
+	if err != nil {
+		t.Fatalf("ProcessStream() error: %v", err)
+	}
+	if result.Records != 3 || result.Processed != 2 || result.DeadLetters != 1 {
+		t.Errorf("result = %+v; want 3 records, 2 processed and 1 dead letter", result)
+	}
+
+	letters := readDeadLetters(t, &deadLetters)
+	if len(letters) != 1 || letters[0].Record != 2 || string(letters[0].Event) != `{"ocsf_1_0_0":[]}` {
+		t.Errorf("dead letters = %+v; want record 2", letters)
+	}
+}
+
+func TestEventReaderTruncatedArray(t *testing.T) {
+	reader := NewEventReader(strings.NewReader(`[` + streamEvent + `, {"ocsf_1_0_0"`))
+
+	if _, err := reader.Next(); err != nil {
+		t.Fatalf("Next() error: %v", err)
+	}
+
+	_, err := reader.Next()
+	var recordErr *RecordError
+	if err == nil || err == io.EOF || errors.As(err, &recordErr) {
+		t.Errorf("Next() = %v; want an error ending the stream", err)
+	}
+}
+
+func TestEventReaderEmpty(t *testing.T) {
+	for _, input := range []string{"", "\n\n", "[]", " [ ] "} {
+		if _, err := NewEventReader(strings.NewReader(input)).Next(); err != io.EOF {
+			t.Errorf("Next() on %q = %v; want io.EOF", input, err)
+		}
+	}
+}
+
+func TestEventReaderRecordSize(t *testing.T) {
+	event := compact(t, streamEvent)
+	huge := `{"ocsf_1_0_0": "` + strings.Repeat("x", 8192) + `"}`
+
+	reader := NewEventReader(strings.NewReader(huge + "\n" + event + "\n"))
+	reader.MaxRecordBytes = len(event) + 1
+	_, err := reader.Next()
+	var recordErr *RecordError
+	if !errors.As(err, &recordErr) || recordErr.Record != 1 {
+		t.Fatalf("Next() = %v; want record 1 rejected as too large", err)
+	}
+	if _, err := reader.Next(); err != nil || reader.Record() != 2 {
+		t.Errorf("Next() = %v at record %d; want record 2 read after the oversized line", err, reader.Record())
+	}
+
+	reader = NewEventReader(strings.NewReader(`[` + event + `, ` + huge + `]`))
+	reader.MaxRecordBytes = len(event) + 1
+	if _, err := reader.Next(); err != nil {
+		t.Fatalf("Next() error: %v", err)
+	}
+	if _, err := reader.Next(); !errors.Is(err, errRecordTooLarge) {
+		t.Errorf("Next() = %v; want the oversized element to end the stream", err)
+	}
+}
@@ -0,0 +1,158 @@
+package eventprocessorocsftype
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/valllabh/ocsf-schema-golang/ocsf/v1_0_0/objects"
+)
+
+// OCSFActivities is a payload of a schema version newer than 1.0.0 keyed by class name,
+// e.g. process_activity. Only the fields of the activity view are decoded from it.
+type OCSFActivities map[string]json.RawMessage
+
+// activityClasses are the supported OCSF classes with the event type reported by GetType.
+var activityClasses = []struct {
+	class     string
+	eventType string
+}{
+	{"file_activity", "FILE_EVENT"},
+	{"network_activity", "NETWORK_EVENT"},
+	{"process_activity", "PROCESS_EVENT"},
+	{"dns_activity", "DNS_EVENT"},
+	{"http_activity", "HTTP_EVENT"},
+	{"module_activity", "MODULE_EVENT"},
+	{"kernel_activity", "KERNEL_EVENT"},
+	{"authentication", "AUTHENTICATION_EVENT"},
+}
+
+// activityView is the part of an OCSF event the profiler reads, whatever its class and
+// schema version. The attributes it decodes kept the same shape since OCSF 1.0.0.
+type activityView struct {
+	// Time is when the event occurred, in milliseconds since the epoch.
+	Time      int64           `json:"time"`
+	Actor     *activityActor  `json:"actor"`
+	Device    *activityDevice `json:"device"`
+	Resources []*Resource     `json:"resources"`
+}
+
+type activityActor struct {
+	Process *activityProcess `json:"process"`
+}
+
+type activityDevice struct {
+	Hostname string `json:"hostname"`
+}
+
+type activityProcess struct {
+	Name          string             `json:"name"`
+	CmdLine       string             `json:"cmd_line"`
+	Uid           string             `json:"uid"`
+	ParentProcess *activityProcess   `json:"parent_process"`
+	Container     *activityContainer `json:"container"`
+}
+
+type activityContainer struct {
+	Name  string `json:"name"`
+	Image struct {
+		Name string `json:"name"`
+	} `json:"image"`
+}
+
+// actorProcess returns the process of the actor, nil when the event has none.
+func (view *activityView) actorProcess() *activityProcess {
+	if view.Actor == nil {
+		return nil
+	}
+	return view.Actor.Process
+}
+
+// versionedActivities returns the newest payload of a schema version newer than 1.0.0.
+func (e *OCSFEvent) versionedActivities() (string, OCSFActivities) {
+	switch {
+	case e.OCSF_1_2_0 != nil:
+		return "1.2.0", e.OCSF_1_2_0
+	case e.OCSF_1_1_0 != nil:
+		return "1.1.0", e.OCSF_1_1_0
+	}
+	return "", nil
+}
+
+// activity returns the activity view of the event, decoded from the typed 1.0.0 classes or
+// from the raw payload of newer schema versions.
+func (e *OCSFEvent) activity() (*activityView, error) {
+	if e.OCSF_1_0_0 != nil {
+		return e.activity_1_0_0()
+	}
+
+	version, activities := e.versionedActivities()
+	for _, activityClass := range activityClasses {
+		raw, ok := activities[activityClass.class]
+		if !ok {
+			continue
+		}
+
+		view := &activityView{}
+		if err := json.Unmarshal(raw, view); err != nil {
+			return nil, fmt.Errorf("failed to decode OCSF %s %s: %w", version, activityClass.class, err)
+		}
+		return view, nil
+	}
+
+	return nil, fmt.Errorf("event type not found")
+}
+
+func (e *OCSFEvent) activity_1_0_0() (*activityView, error) {
+	event := e.OCSF_1_0_0
+	switch e.GetType() {
+	case "FILE_EVENT":
+		return newActivityView(event.FileActivity.Time, event.FileActivity.Actor, event.FileActivity.Device, event.FileActivity.Resources), nil
+	case "NETWORK_EVENT":
+		return newActivityView(event.NetworkActivity.Time, event.NetworkActivity.Actor, event.NetworkActivity.Device, event.NetworkActivity.Resources), nil
+	case "PROCESS_EVENT":
+		return newActivityView(event.ProcessActivity.Time, event.ProcessActivity.Actor, event.ProcessActivity.Device, event.ProcessActivity.Resources), nil
+	case "DNS_EVENT":
+		return newActivityView(event.DnsActivity.Time, event.DnsActivity.Actor, event.DnsActivity.Device, event.DnsActivity.Resources), nil
+	case "HTTP_EVENT":
+		return newActivityView(event.HttpActivity.Time, event.HttpActivity.Actor, event.HttpActivity.Device, event.HttpActivity.Resources), nil
+	case "MODULE_EVENT":
+		return newActivityView(event.ModuleActivity.Time, event.ModuleActivity.Actor, event.ModuleActivity.Device, event.ModuleActivity.Resources), nil
+	case "KERNEL_EVENT":
+		return newActivityView(event.KernelActivity.Time, event.KernelActivity.Actor, event.KernelActivity.Device, event.KernelActivity.Resources), nil
+	case "AUTHENTICATION_EVENT":
+		return newActivityView(event.Authentication.Time, event.Authentication.Actor, event.Authentication.Device, event.Authentication.Resources), nil
+	}
+
+	return nil, fmt.Errorf("event type not found")
+}
+
+func newActivityView(timestamp int64, actor *objects.Actor, device *objects.Device, resources []*Resource) *activityView {
+	view := &activityView{Time: timestamp, Resources: resources}
+	if actor != nil {
+		view.Actor = &activityActor{Process: newActivityProcess(actor.Process)}
+	}
+	if device != nil {
+		view.Device = &activityDevice{Hostname: device.Hostname}
+	}
+	return view
+}
+
+func newActivityProcess(process *objects.Process) *activityProcess {
+	if process == nil {
+		return nil
+	}
+
+	converted := &activityProcess{
+		Name:          process.Name,
+		CmdLine:       process.CmdLine,
+		Uid:           process.Uid,
+		ParentProcess: newActivityProcess(process.ParentProcess),
+	}
+	if process.Container != nil {
+		converted.Container = &activityContainer{Name: process.Container.Name}
+		if process.Container.Image != nil {
+			converted.Container.Image.Name = process.Container.Image.Name
+		}
+	}
+	return converted
+}
@@ -0,0 +1,113 @@
+package eventprocessorocsftype
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+const testResources = `"resources": [
+	{"type": "kubernetes.cluster", "name": "prod"},
+	{"type": "kubernetes.namespace", "name": "default"},
+	{"type": "kubernetes.pod", "name": "nginx-7d9c"}
+]`
+
+const testActor = `"time": 1733140800000,
+"actor": {"process": {
+	"name": "/usr/bin/curl", "cmd_line": "-s example.com", "uid": "exec-2",
+	"container": {"name": "nginx", "image": {"name": "docker.io/library/nginx"}},
+	"parent_process": {"name": "/bin/sh", "uid": "exec-1"}
+}},
+"device": {"hostname": "node-1"}`
+
+func TestActivityClasses(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   string
+		eventType string
+	}{
+		{"1.0.0 dns", `{"ocsf_1_0_0": {"dns_activity": {` + testActor + `, ` + testResources + `}}}`, "DNS_EVENT"},
+		{"1.0.0 http", `{"ocsf_1_0_0": {"http_activity": {` + testActor + `, ` + testResources + `}}}`, "HTTP_EVENT"},
+		{"1.0.0 module", `{"ocsf_1_0_0": {"module_activity": {` + testActor + `, ` + testResources + `}}}`, "MODULE_EVENT"},
+		{"1.0.0 kernel", `{"ocsf_1_0_0": {"kernel_activity": {` + testActor + `, ` + testResources + `}}}`, "KERNEL_EVENT"},
+		{"1.0.0 authentication", `{"ocsf_1_0_0": {"authentication": {` + testActor + `, ` + testResources + `}}}`, "AUTHENTICATION_EVENT"},
+		{"1.1.0 process", `{"ocsf_1_1_0": {"process_activity": {` + testActor + `, ` + testResources + `}}}`, "PROCESS_EVENT"},
+		{"1.2.0 dns", `{"ocsf_1_2_0": {"dns_activity": {` + testActor + `, ` + testResources + `}}}`, "DNS_EVENT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &OCSFEvent{}
+			if err := json.Unmarshal([]byte(tt.payload), event); err != nil {
+				t.Fatalf("failed to unmarshal event: %v", err)
+			}
+
+			if eventType := event.GetType(); eventType != tt.eventType {
+				t.Errorf("GetType() = %q; want %q", eventType, tt.eventType)
+			}
+
+			namespace, err := event.GetNamespace()
+			if err != nil || namespace.Name != "default" {
+				t.Errorf("GetNamespace() = %v, %v; want default", namespace, err)
+			}
+			pod, err := event.GetPod()
+			if err != nil || pod.Name != "nginx-7d9c" {
+				t.Errorf("GetPod() = %v, %v; want nginx-7d9c", pod, err)
+			}
+			container, err := event.GetContainer()
+			if err != nil || container.Name != "nginx" || container.Image.Repo != "docker.io/library/nginx" {
+				t.Errorf("GetContainer() = %v, %v; want nginx", container, err)
+			}
+
+			process, err := event.GetProcess()
+			if err != nil {
+				t.Fatalf("GetProcess() error: %v", err)
+			}
+			if process.Binary != "/usr/bin/curl" || process.Arguments != "-s example.com" || process.ParentExecID != "exec-1" {
+				t.Errorf("GetProcess() = %+v", process)
+			}
+			parent, err := event.GetParentProcess()
+			if err != nil || parent.Binary != "/bin/sh" {
+				t.Errorf("GetParentProcess() = %v, %v; want /bin/sh", parent, err)
+			}
+
+			source, err := event.GetSource()
+			if err != nil || source.NodeName != "node-1" || source.ClusterName != "prod" {
+				t.Errorf("GetSource() = %v, %v; want node-1 in prod", source, err)
+			}
+			if want := time.Date(2024, 12, 2, 12, 0, 0, 0, time.UTC); !source.Time.Equal(want) {
+				t.Errorf("GetSource() time = %s; want %s", source.Time, want)
+			}
+		})
+	}
+}
+
+func TestActivityNewestVersion(t *testing.T) {
+	event := &OCSFEvent{}
+	payload := `{
+		"ocsf_1_1_0": {"process_activity": {"actor": {"process": {"name": "/bin/old"}}}},
+		"ocsf_1_2_0": {"process_activity": {"actor": {"process": {"name": "/bin/new"}}}}
+	}`
+	if err := json.Unmarshal([]byte(payload), event); err != nil {
+		t.Fatalf("failed to unmarshal event: %v", err)
+	}
+
+	process, err := event.GetProcess()
+	if err != nil || process.Binary != "/bin/new" {
+		t.Errorf("GetProcess() = %v, %v; want the 1.2.0 process", process, err)
+	}
+}
+
+func TestActivityUnknownClass(t *testing.T) {
+	event := &OCSFEvent{}
+	if err := json.Unmarshal([]byte(`{"ocsf_1_1_0": {"inventory_info": {}}}`), event); err != nil {
+		t.Fatalf("failed to unmarshal event: %v", err)
+	}
+
+	if eventType := event.GetType(); eventType != "" {
+		t.Errorf("GetType() = %q; want empty for an unsupported class", eventType)
+	}
+	if _, err := event.GetProcess(); err == nil {
+		t.Errorf("GetProcess() succeeded for an unsupported class")
+	}
+}
@@ -0,0 +1,170 @@
+package eventprocessorocsftype
+
+import (
+	"encoding/json"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/valllabh/ocsf-schema-golang/ocsf/v1_0_0/events/findings"
+	"github.com/valllabh/ocsf-schema-golang/ocsf/v1_0_0/events/findings/enums"
+	"github.com/valllabh/ocsf-schema-golang/ocsf/v1_0_0/events/system"
+	"github.com/valllabh/ocsf-schema-golang/ocsf/v1_0_0/objects"
+)
+
+const (
+	// ProductName and ProductVendor identify the profiler in the metadata of the events it produces.
+	ProductName   = "Runtime Behavior Profiler"
+	ProductVendor = "runtime-behavior-profiler"
+
+	// OCSFVersion is the schema version of the events produced by the profiler.
+	OCSFVersion = "1.0.0"
+)
+
+// deviationSeverities maps deviation types to the severity of their finding, other types are medium.
+var deviationSeverities = map[eventtype.DeviationType]enums.SECURITY_FINDING_SEVERITY_ID{
+	eventtype.DeviationAbnormalTermination:    enums.SECURITY_FINDING_SEVERITY_ID_SECURITY_FINDING_SEVERITY_ID_LOW,
+	eventtype.DeviationNewLibrary:             enums.SECURITY_FINDING_SEVERITY_ID_SECURITY_FINDING_SEVERITY_ID_MEDIUM,
+	eventtype.DeviationNewCapability:          enums.SECURITY_FINDING_SEVERITY_ID_SECURITY_FINDING_SEVERITY_ID_HIGH,
+	eventtype.DeviationNewPrivilegeTransition: enums.SECURITY_FINDING_SEVERITY_ID_SECURITY_FINDING_SEVERITY_ID_HIGH,
+	eventtype.DeviationHostNamespace:          enums.SECURITY_FINDING_SEVERITY_ID_SECURITY_FINDING_SEVERITY_ID_CRITICAL,
+	eventtype.DeviationUserNamespaceCreated:   enums.SECURITY_FINDING_SEVERITY_ID_SECURITY_FINDING_SEVERITY_ID_HIGH,
+	eventtype.DeviationSuspiciousBehavior:     enums.SECURITY_FINDING_SEVERITY_ID_SECURITY_FINDING_SEVERITY_ID_HIGH,
+	eventtype.DeviationFrequencyAnomaly:       enums.SECURITY_FINDING_SEVERITY_ID_SECURITY_FINDING_SEVERITY_ID_MEDIUM,
+}
+
+// NewSecurityFinding converts a deviation into an OCSF 1.0.0 Security Finding. The activity of
+// the evidence event is embedded as evidence, when evidence is nil a Process Activity describing
+// the deviating process is embedded instead.
+func NewSecurityFinding(deviation *eventtype.Deviation, evidence *OCSFEvent) *findings.SecurityFinding {
+	return NewSecurityFindingWithAttack(deviation, evidence, nil)
+}
+
+// NewSecurityFindingWithAttack converts the deviation like NewSecurityFinding and names its
+// MITRE ATT&CK techniques and tactics after mapping, the mapping of the cluster the deviation
+// was reported in, see eventtype.IAttackMapper.
+func NewSecurityFindingWithAttack(deviation *eventtype.Deviation, evidence *OCSFEvent, mapping *eventtype.AttackMapping) *findings.SecurityFinding {
+	severity, ok := deviationSeverities[deviation.Type]
+	if !ok {
+		severity = enums.SECURITY_FINDING_SEVERITY_ID_SECURITY_FINDING_SEVERITY_ID_MEDIUM
+	}
+
+	timestamp := deviation.Time.UnixMilli()
+	process := &objects.Process{
+		Name:    deviation.Binary,
+		CmdLine: deviation.Arguments,
+		Container: &objects.Container{
+			Name: deviation.Container,
+		},
+	}
+	if evidence == nil {
+		evidence = newProcessEvidence(process, timestamp)
+	}
+	evidenceJSON, _ := json.Marshal(evidence.getActivity())
+
+	return &findings.SecurityFinding{
+		Metadata: &objects.Metadata{
+			Version: OCSFVersion,
+			Product: &objects.Product{
+				Name:       ProductName,
+				VendorName: ProductVendor,
+			},
+		},
+		Time:         timestamp,
+		CategoryUid:  enums.SECURITY_FINDING_CATEGORY_UID_SECURITY_FINDING_CATEGORY_UID_FINDINGS,
+		CategoryName: "Findings",
+		ClassUid:     enums.SECURITY_FINDING_CLASS_UID_SECURITY_FINDING_CLASS_UID_SECURITY_FINDING,
+		ClassName:    "Security Finding",
+		ActivityId:   enums.SECURITY_FINDING_ACTIVITY_ID_SECURITY_FINDING_ACTIVITY_ID_CREATE,
+		ActivityName: "Create",
+		TypeUid:      enums.SECURITY_FINDING_TYPE_UID_SECURITY_FINDING_TYPE_UID_SECURITY_FINDING_CREATE,
+		TypeName:     "Security Finding: Create",
+		SeverityId:   severity,
+		Severity:     severityName(severity),
+		StateId:      enums.SECURITY_FINDING_STATE_ID_SECURITY_FINDING_STATE_ID_NEW,
+		State:        "New",
+		Message:      deviation.Message,
+		Finding: &objects.Finding{
+			Uid:           findingUid(deviation),
+			Title:         string(deviation.Type),
+			Desc:          deviation.Message,
+			Types:         []string{string(deviation.Type)},
+			CreatedTime:   timestamp,
+			FirstSeenTime: timestamp,
+			LastSeenTime:  timestamp,
+		},
+		Attacks:   deviationAttacks(deviation, mapping),
+		Process:   process,
+		Evidence:  string(evidenceJSON),
+		Resources: deviationResources(deviation),
+	}
+}
+
+// deviationAttacks returns the MITRE ATT&CK techniques of the deviation, named after the
+// mapping they were tagged with. Without it the techniques are only known by their ID.
+func deviationAttacks(deviation *eventtype.Deviation, mapping *eventtype.AttackMapping) []*objects.Attack {
+	var attacks []*objects.Attack
+	for _, technique := range deviation.Techniques {
+		attack := &objects.Attack{Technique: &objects.Technique{Uid: technique}}
+		if mapping != nil {
+			attack.Version = mapping.AttackVersion
+		}
+		if tags := mapping.Tags([]string{technique}); len(tags) > 0 {
+			attack.Technique.Name = tags[0].Name
+			for _, tactic := range tags[0].Tactics {
+				attack.Tactics = append(attack.Tactics, &objects.Tactic{Name: tactic, Uid: mapping.Tactics[tactic]})
+			}
+		}
+		attacks = append(attacks, attack)
+	}
+	return attacks
+}
+
+// findingUid identifies the deviation independently of the time it was observed, so the
+// same deviation reported twice yields the same finding.
+func findingUid(deviation *eventtype.Deviation) string {
+	name := strings.Join([]string{string(deviation.Type), deviation.Cluster, deviation.Namespace, deviation.Pod,
+		deviation.Container, deviation.Binary, deviation.Arguments, deviation.Value}, ":")
+	return uuid.NewV5(uuid.NamespaceURL, name).String()
+}
+
+// deviationResources returns the Kubernetes resources of the deviation, using the same resource
+// types as the events read by the profiler.
+func deviationResources(deviation *eventtype.Deviation) []*objects.ResourceDetails {
+	var resources []*objects.ResourceDetails
+	for _, resource := range []struct{ resourceType, name string }{
+		{"kubernetes.cluster", deviation.Cluster},
+		{"kubernetes.node", deviation.Node},
+		{"kubernetes.namespace", deviation.Namespace},
+		{"kubernetes.pod", deviation.Pod},
+		{"kubernetes.container", deviation.Container},
+	} {
+		if resource.name != "" {
+			resources = append(resources, &objects.ResourceDetails{Type: resource.resourceType, Name: resource.name})
+		}
+	}
+	return resources
+}
+
+// newProcessEvidence returns a Process Activity of the deviating process.
+func newProcessEvidence(process *objects.Process, timestamp int64) *OCSFEvent {
+	return &OCSFEvent{
+		OCSF_1_0_0: &OCSF_1_0_0{
+			ProcessActivity: &ProcessActivity{
+				ProcessActivity: system.ProcessActivity{
+					Time:    timestamp,
+					Process: process,
+				},
+			},
+		},
+	}
+}
+
+func severityName(severity enums.SECURITY_FINDING_SEVERITY_ID) string {
+	name := enums.SECURITY_FINDING_SEVERITY_ID_name[int32(severity)]
+	name = strings.TrimPrefix(name, "SECURITY_FINDING_SEVERITY_ID_")
+	if name == "" {
+		return ""
+	}
+	return name[:1] + strings.ToLower(name[1:])
+}
@@ -0,0 +1,110 @@
+package eventprocessorocsftype
+
+import (
+	"encoding/json"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valllabh/ocsf-schema-golang/ocsf/v1_0_0/events/findings/enums"
+)
+
+func TestNewSecurityFinding(t *testing.T) {
+	deviation := &eventtype.Deviation{
+		Type:      eventtype.DeviationHostNamespace,
+		Cluster:   "prod",
+		Namespace: "default",
+		Pod:       "nginx",
+		Container: "nginx",
+		Binary:    "/usr/bin/nsenter",
+		Arguments: "-t 1 -m",
+		Value:     "mnt",
+		Message:   "/usr/bin/nsenter entered the host mnt namespace",
+		Time:      time.Date(2024, 12, 2, 12, 0, 0, 0, time.UTC),
+	}
+
+	finding := NewSecurityFinding(deviation, nil)
+
+	if finding.ClassUid != enums.SECURITY_FINDING_CLASS_UID_SECURITY_FINDING_CLASS_UID_SECURITY_FINDING {
+		t.Errorf("class uid = %d; want 2001", finding.ClassUid)
+	}
+	if finding.SeverityId != enums.SECURITY_FINDING_SEVERITY_ID_SECURITY_FINDING_SEVERITY_ID_CRITICAL || finding.Severity != "Critical" {
+		t.Errorf("severity = %d %q; want Critical", finding.SeverityId, finding.Severity)
+	}
+	if finding.Time != deviation.Time.UnixMilli() {
+		t.Errorf("time = %d; want %d", finding.Time, deviation.Time.UnixMilli())
+	}
+	if finding.Process.Name != "/usr/bin/nsenter" || finding.Process.CmdLine != "-t 1 -m" {
+		t.Errorf("process cmd line = %q", finding.Process.CmdLine)
+	}
+
+	resources := map[string]string{}
+	for _, resource := range finding.Resources {
+		resources[resource.Type] = resource.Name
+	}
+	if resources["kubernetes.cluster"] != "prod" || resources["kubernetes.pod"] != "nginx" {
+		t.Errorf("resources = %v; want the cluster and pod of the deviation", resources)
+	}
+	if _, ok := resources["kubernetes.node"]; ok {
+		t.Errorf("resources = %v; want no node when the deviation has none", resources)
+	}
+
+	if !strings.Contains(finding.Evidence, `"cmd_line":"-t 1 -m"`) {
+		t.Errorf("evidence = %s; want the deviating process activity", finding.Evidence)
+	}
+
+	later := *deviation
+	later.Time = deviation.Time.Add(time.Hour)
+	if NewSecurityFinding(&later, nil).Finding.Uid != finding.Finding.Uid {
+		t.Errorf("finding uid changed with the deviation time")
+	}
+
+	if _, err := json.Marshal(finding); err != nil {
+		t.Errorf("failed to marshal finding: %v", err)
+	}
+}
+
+func TestNewSecurityFindingWithEvidence(t *testing.T) {
+	var event OCSFEvent
+	if err := json.Unmarshal([]byte(`{"ocsf_1_0_0":{"file_activity":{"activity_name":"Open","file":{"path":"/etc/shadow"}}}}`), &event); err != nil {
+		t.Fatalf("failed to unmarshal event: %v", err)
+	}
+
+	finding := NewSecurityFinding(&eventtype.Deviation{Type: eventtype.DeviationNewLibrary, Binary: "/bin/cat"}, &event)
+
+	if !strings.Contains(finding.Evidence, "/etc/shadow") {
+		t.Errorf("evidence = %s; want the file activity", finding.Evidence)
+	}
+	if finding.Severity != "Medium" {
+		t.Errorf("severity = %q; want Medium", finding.Severity)
+	}
+}
+
+func TestNewSecurityFindingAttacks(t *testing.T) {
+	deviation := &eventtype.Deviation{
+		Type:       eventtype.DeviationSuspiciousBehavior,
+		Binary:     "/bin/sh",
+		Value:      "shell-spawned-by-web-server",
+		Techniques: []string{"T1505.003", "T1059.004"},
+	}
+
+	finding := NewSecurityFindingWithAttack(deviation, nil, eventtype.DefaultAttackMapping())
+
+	if finding.SeverityId != enums.SECURITY_FINDING_SEVERITY_ID_SECURITY_FINDING_SEVERITY_ID_HIGH {
+		t.Errorf("severity = %q; want High", finding.Severity)
+	}
+	if len(finding.Attacks) != 2 || finding.Attacks[0].Technique.Uid != "T1505.003" || finding.Attacks[1].Technique.Uid != "T1059.004" {
+		t.Errorf("attacks = %v; want T1505.003 and T1059.004", finding.Attacks)
+	}
+	shell := finding.Attacks[1]
+	if shell.Version == "" || shell.Technique.Name != "Unix Shell" || len(shell.Tactics) != 1 || shell.Tactics[0].Uid != "TA0002" {
+		t.Errorf("T1059.004 attack = %v; want Unix Shell in Execution", shell)
+	}
+
+	// Without the mapping of the cluster the techniques are only known by their ID.
+	unnamed := NewSecurityFinding(deviation, nil).Attacks
+	if len(unnamed) != 2 || unnamed[1].Technique.Uid != "T1059.004" || unnamed[1].Technique.Name != "" || len(unnamed[1].Tactics) != 0 {
+		t.Errorf("attacks without mapping = %v; want T1505.003 and T1059.004 by ID", unnamed)
+	}
+}
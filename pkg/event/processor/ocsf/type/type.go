@@ -1,22 +1,34 @@
 package eventprocessorocsftype
 
 import (
+	"encoding/json"
 	"fmt"
 	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"time"
 
+	"github.com/valllabh/ocsf-schema-golang/ocsf/v1_0_0/events/iam"
 	"github.com/valllabh/ocsf-schema-golang/ocsf/v1_0_0/events/network"
 	"github.com/valllabh/ocsf-schema-golang/ocsf/v1_0_0/events/system"
-	"github.com/valllabh/ocsf-schema-golang/ocsf/v1_0_0/objects"
+	systemenums "github.com/valllabh/ocsf-schema-golang/ocsf/v1_0_0/events/system/enums"
 )
 
 type OCSFEvent struct {
 	OCSF_1_0_0 *OCSF_1_0_0 `json:"ocsf_1_0_0,omitempty"`
+	// OCSF_1_1_0 and OCSF_1_2_0 hold payloads of newer schema versions, they are read
+	// through the version independent activity view.
+	OCSF_1_1_0 OCSFActivities `json:"ocsf_1_1_0,omitempty"`
+	OCSF_1_2_0 OCSFActivities `json:"ocsf_1_2_0,omitempty"`
 }
 
 type OCSF_1_0_0 struct {
 	FileActivity    *FileActivity    `json:"file_activity,omitempty"`
 	NetworkActivity *NetworkActivity `json:"network_activity,omitempty"`
 	ProcessActivity *ProcessActivity `json:"process_activity,omitempty"`
+	DnsActivity     *DnsActivity     `json:"dns_activity,omitempty"`
+	HttpActivity    *HttpActivity    `json:"http_activity,omitempty"`
+	ModuleActivity  *ModuleActivity  `json:"module_activity,omitempty"`
+	KernelActivity  *KernelActivity  `json:"kernel_activity,omitempty"`
+	Authentication  *Authentication  `json:"authentication,omitempty"`
 }
 
 type FileActivity struct {
@@ -34,6 +46,31 @@ type ProcessActivity struct {
 	Resources []*Resource `json:"resources"`
 }
 
+type DnsActivity struct {
+	network.DnsActivity
+	Resources []*Resource `json:"resources"`
+}
+
+type HttpActivity struct {
+	network.HttpActivity
+	Resources []*Resource `json:"resources"`
+}
+
+type ModuleActivity struct {
+	system.ModuleActivity
+	Resources []*Resource `json:"resources"`
+}
+
+type KernelActivity struct {
+	system.KernelActivity
+	Resources []*Resource `json:"resources"`
+}
+
+type Authentication struct {
+	iam.Authentication
+	Resources []*Resource `json:"resources"`
+}
+
 type Resource struct {
 	Type string `json:"type"`
 	Name string `json:"name"`
@@ -73,20 +110,17 @@ func (e *OCSFEvent) GetPod() (*eventtype.Pod, error) {
 
 // func to get container from Event
 func (e *OCSFEvent) GetContainer() (*eventtype.Container, error) {
-	var container *objects.Container
-	switch e.GetType() {
-	case "FILE_EVENT":
-		container = e.OCSF_1_0_0.FileActivity.Actor.Process.Container
-	case "NETWORK_EVENT":
-		container = e.OCSF_1_0_0.NetworkActivity.Actor.Process.Container
-	case "PROCESS_EVENT":
-		container = e.OCSF_1_0_0.ProcessActivity.Actor.Process.Container
+	activity, err := e.activity()
+	if err != nil {
+		return nil, err
 	}
 
-	if container == nil {
+	process := activity.actorProcess()
+	if process == nil || process.Container == nil {
 		return nil, fmt.Errorf("container not found")
 	}
 
+	container := process.Container
 	return &eventtype.Container{
 		Name: container.Name,
 		Image: &eventtype.Image{
@@ -98,91 +132,190 @@ func (e *OCSFEvent) GetContainer() (*eventtype.Container, error) {
 
 // func to get Process from Event
 func (e *OCSFEvent) GetProcess() (*eventtype.Process, error) {
-	var process *objects.Process
-	switch e.GetType() {
-	case "FILE_EVENT":
-		process = e.OCSF_1_0_0.FileActivity.Actor.Process
-	case "NETWORK_EVENT":
-		process = e.OCSF_1_0_0.NetworkActivity.Actor.Process
-	case "PROCESS_EVENT":
-		process = e.OCSF_1_0_0.ProcessActivity.Actor.Process
-
+	activity, err := e.activity()
+	if err != nil {
+		return nil, err
 	}
 
+	process := activity.actorProcess()
 	if process == nil {
 		return nil, fmt.Errorf("process not found")
 	}
 
-	return &eventtype.Process{
-		Binary:         process.Name,
-		Arguments:      process.CmdLine,
-		ChildProcesses: map[string]*eventtype.Process{},
-	}, nil
+	return newProcess(process), nil
 }
 
 // func to get Parent from Event
 func (e *OCSFEvent) GetParentProcess() (*eventtype.Process, error) {
-	var process *objects.Process
-	var tyepee string = e.GetType()
+	activity, err := e.activity()
+	if err != nil {
+		return nil, err
+	}
 
-	if tyepee == "" {
-		return nil, fmt.Errorf("event type not found")
+	process := activity.actorProcess()
+	if process == nil || process.ParentProcess == nil {
+		return &eventtype.Process{
+			Binary: "root",
+		}, nil
 	}
 
-	switch tyepee {
-	case "FILE_EVENT":
-		process = e.OCSF_1_0_0.FileActivity.Actor.Process.ParentProcess
-	case "NETWORK_EVENT":
-		process = e.OCSF_1_0_0.NetworkActivity.Actor.Process.ParentProcess
-	case "PROCESS_EVENT":
-		process = e.OCSF_1_0_0.ProcessActivity.Actor.Process.ParentProcess
+	return newProcess(process.ParentProcess), nil
+}
+
+// func to get the parent_process chain of the actor process, oldest ancestor first
+func (e *OCSFEvent) GetAncestors() ([]*eventtype.Process, error) {
+	activity, err := e.activity()
+	if err != nil {
+		return nil, err
 	}
 
+	process := activity.actorProcess()
 	if process == nil {
-		return &eventtype.Process{
-			Binary: "root",
-		}, nil
+		return nil, fmt.Errorf("process not found")
+	}
+
+	var ancestors []*eventtype.Process
+	for parent := process.ParentProcess; parent != nil; parent = parent.ParentProcess {
+		ancestors = append([]*eventtype.Process{newProcess(parent)}, ancestors...)
+	}
+
+	return ancestors, nil
+}
+
+// func to get the node and cluster that produced the Event, from the device hostname
+// and the kubernetes.cluster resource when present
+func (e *OCSFEvent) GetSource() (*eventtype.EventSource, error) {
+	activity, err := e.activity()
+	if err != nil {
+		return nil, err
+	}
+
+	source := &eventtype.EventSource{}
+	if activity.Time > 0 {
+		source.Time = time.UnixMilli(activity.Time).UTC()
+	}
+	if activity.Device != nil {
+		source.NodeName = activity.Device.Hostname
+	}
+	if resource, err := e.getResource("kubernetes.cluster"); err == nil {
+		source.ClusterName = resource.Name
+	}
+
+	return source, nil
+}
+
+// newProcess converts an OCSF process, the process uid is used as its exec id.
+func newProcess(process *activityProcess) *eventtype.Process {
+	parentExecID := ""
+	if process.ParentProcess != nil {
+		parentExecID = process.ParentProcess.Uid
 	}
 
 	return &eventtype.Process{
 		Binary:         process.Name,
 		Arguments:      process.CmdLine,
 		ChildProcesses: map[string]*eventtype.Process{},
-	}, nil
+		ExecID:         process.Uid,
+		ParentExecID:   parentExecID,
+	}
 }
 
-func (e *OCSFEvent) GetType() string {
-	if e.OCSF_1_0_0 == nil {
-		return ""
+// IsExec implements eventtype.IExecEvent, only a process activity launching its process is an
+// execution.
+func (e *OCSFEvent) IsExec() bool {
+	if e.GetType() != "PROCESS_EVENT" {
+		return false
+	}
+	if e.OCSF_1_0_0 != nil {
+		return e.OCSF_1_0_0.ProcessActivity.ActivityId == systemenums.PROCESS_ACTIVITY_ACTIVITY_ID_PROCESS_ACTIVITY_ACTIVITY_ID_LAUNCH
 	}
 
-	if e.OCSF_1_0_0.FileActivity != nil {
-		return "FILE_EVENT"
+	_, activities := e.versionedActivities()
+	var activity struct {
+		ActivityId systemenums.PROCESS_ACTIVITY_ACTIVITY_ID `json:"activity_id"`
 	}
+	return json.Unmarshal(activities["process_activity"], &activity) == nil &&
+		activity.ActivityId == systemenums.PROCESS_ACTIVITY_ACTIVITY_ID_PROCESS_ACTIVITY_ACTIVITY_ID_LAUNCH
+}
 
-	if e.OCSF_1_0_0.NetworkActivity != nil {
-		return "NETWORK_EVENT"
+func (e *OCSFEvent) GetType() string {
+	if e.OCSF_1_0_0 != nil {
+		switch {
+		case e.OCSF_1_0_0.FileActivity != nil:
+			return "FILE_EVENT"
+		case e.OCSF_1_0_0.NetworkActivity != nil:
+			return "NETWORK_EVENT"
+		case e.OCSF_1_0_0.ProcessActivity != nil:
+			return "PROCESS_EVENT"
+		case e.OCSF_1_0_0.DnsActivity != nil:
+			return "DNS_EVENT"
+		case e.OCSF_1_0_0.HttpActivity != nil:
+			return "HTTP_EVENT"
+		case e.OCSF_1_0_0.ModuleActivity != nil:
+			return "MODULE_EVENT"
+		case e.OCSF_1_0_0.KernelActivity != nil:
+			return "KERNEL_EVENT"
+		case e.OCSF_1_0_0.Authentication != nil:
+			return "AUTHENTICATION_EVENT"
+		}
+		return ""
 	}
 
-	if e.OCSF_1_0_0.ProcessActivity != nil {
-		return "PROCESS_EVENT"
+	_, activities := e.versionedActivities()
+	for _, activityClass := range activityClasses {
+		if _, ok := activities[activityClass.class]; ok {
+			return activityClass.eventType
+		}
 	}
 
 	return ""
 }
 
-func (e *OCSFEvent) getResource(t string) (*Resource, error) {
+// getActivity returns the activity carried by the event, nil for an unknown type.
+func (e *OCSFEvent) getActivity() interface{} {
+	if e.OCSF_1_0_0 == nil {
+		_, activities := e.versionedActivities()
+		for _, activityClass := range activityClasses {
+			if raw, ok := activities[activityClass.class]; ok {
+				return raw
+			}
+		}
+		return nil
+	}
+
 	switch e.GetType() {
 	case "FILE_EVENT":
-		return e.FileActivityGetResource(t)
-
+		return e.OCSF_1_0_0.FileActivity
 	case "NETWORK_EVENT":
-		return e.NetworkActivityGetResource(t)
-
+		return e.OCSF_1_0_0.NetworkActivity
 	case "PROCESS_EVENT":
-		return e.ProcessActivityGetResource(t)
+		return e.OCSF_1_0_0.ProcessActivity
+	case "DNS_EVENT":
+		return e.OCSF_1_0_0.DnsActivity
+	case "HTTP_EVENT":
+		return e.OCSF_1_0_0.HttpActivity
+	case "MODULE_EVENT":
+		return e.OCSF_1_0_0.ModuleActivity
+	case "KERNEL_EVENT":
+		return e.OCSF_1_0_0.KernelActivity
+	case "AUTHENTICATION_EVENT":
+		return e.OCSF_1_0_0.Authentication
 	}
 
+	return nil
+}
+
+func (e *OCSFEvent) getResource(t string) (*Resource, error) {
+	activity, err := e.activity()
+	if err != nil {
+		return nil, fmt.Errorf("resource of type %s not found", t)
+	}
+
+	for _, resource := range activity.Resources {
+		if resource.Type == t {
+			return resource, nil
+		}
+	}
 	return nil, fmt.Errorf("resource of type %s not found", t)
 }
 
@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"os/signal"
+	eventprocessorocsftype "runtime-behavior-profiler/pkg/event/processor/ocsf/type"
 	eventprocessortetragontype "runtime-behavior-profiler/pkg/event/processor/tetragon/type"
 	eventtype "runtime-behavior-profiler/pkg/event/type"
 	"syscall"
@@ -20,9 +22,14 @@ import (
 )
 
 type tetragonEventListener struct {
-	Options               eventprocessortetragontype.TetragonEventListerOptions
-	Cluster               *eventtype.Cluster
+	Options  eventprocessortetragontype.TetragonEventListerOptions
+	Registry *eventtype.ClusterRegistry
+	// Sink receives the events, defaults to Registry.
+	Sink                  eventtype.IEventSink
 	GRPCClientWithContext *eventprocessortetragontype.ClientWithContext
+
+	// hosts are the host profiles by node name, only used when host events are listened to.
+	hosts map[string]*eventprocessortetragontype.Host
 }
 
 // gRGC A6 - gRPC Retry Design (a.k.a. built in backoff retry)
@@ -152,10 +159,30 @@ func (tel *tetragonEventListener) getFilter() *tetragon.Filter {
 
 	return &filter
 }
+
+// getHost returns the host profile of the node, or nil when host events are not profiled.
+func (tel *tetragonEventListener) getHost(nodeName string) *eventprocessortetragontype.Host {
+	if !tel.Options.Host {
+		return nil
+	}
+
+	host, ok := tel.hosts[nodeName]
+	if !ok {
+		// The local proc filesystem only tells the units of the node the profiler runs on.
+		procRoot := ""
+		if nodeName != "" && nodeName == tel.Options.HostNodeName {
+			procRoot = tel.Options.HostProcRoot
+		}
+		host = eventprocessortetragontype.NewHost(nodeName, procRoot)
+		tel.hosts[nodeName] = host
+	}
+	return host
+}
+
 func (tel *tetragonEventListener) OnEndListeningEvent() {
 	println("Tetragon Event Listener is done listening to events")
 
-	json, _ := json.MarshalIndent(tel.Cluster, "", "  ")
+	json, _ := json.MarshalIndent(tel.Registry, "", "  ")
 	println("\n" + string(json))
 
 	tel.GRPCClientWithContext.Conn.Close()
@@ -164,12 +191,13 @@ func (tel *tetragonEventListener) OnEndListeningEvent() {
 
 func (tel *tetragonEventListener) ListenToEvents() error {
 
-	fmt.Printf("Listening to tetragon events on %s\n", tel.Options.ServerAddress)
+	// Progress goes to stderr so stdout only carries the events of the selected output.
+	fmt.Fprintf(os.Stderr, "Listening to tetragon events on %s\n", tel.Options.ServerAddress)
 
 	err := tel.initGRPCClientWithContext(context.Background())
 
 	if err != nil {
-		fmt.Printf("failed to create gRPC client: %v\n", err)
+		fmt.Fprintf(os.Stderr, "failed to create gRPC client: %v\n", err)
 		return err
 	}
 	defer tel.OnEndListeningEvent()
@@ -179,7 +207,7 @@ func (tel *tetragonEventListener) ListenToEvents() error {
 	stream, err := tel.GRPCClientWithContext.Client.GetEvents(tel.GRPCClientWithContext.Ctx, request)
 
 	if err != nil {
-		fmt.Errorf("failed to call GetEvents: %v", err)
+		fmt.Fprintf(os.Stderr, "failed to call GetEvents: %v\n", err)
 		return err
 	}
 
@@ -187,35 +215,51 @@ func (tel *tetragonEventListener) ListenToEvents() error {
 		response, err := stream.Recv()
 		if err != nil {
 			if !errors.Is(err, context.Canceled) && status.Code(err) != codes.Canceled && !errors.Is(err, io.EOF) {
-				fmt.Errorf("failed to receive events: %w", err)
+				fmt.Fprintf(os.Stderr, "failed to receive events: %v\n", err)
 			}
 			return err // if not returned will go in infinite loop
 		}
-		eventType := response.EventType()
-
-		var iEvent eventtype.IEvent
-
-		switch eventType {
-		case tetragon.EventType_PROCESS_EXEC:
-			iEvent = ProcessProcessExec(response.GetProcessExec())
-		case tetragon.EventType_PROCESS_EXIT:
-			iEvent = ProcessProcessExit(response.GetProcessExit())
-		case tetragon.EventType_PROCESS_LOADER:
-			iEvent = ProcessProcessLoader(response.GetProcessLoader())
-		case tetragon.EventType_PROCESS_KPROBE:
-			iEvent = ProcessProcessKprobe(response.GetProcessKprobe())
-		case tetragon.EventType_PROCESS_TRACEPOINT:
-			iEvent = ProcessProcessTracepoint(response.GetProcessTracepoint())
-		case tetragon.EventType_PROCESS_UPROBE:
-			iEvent = ProcessProcessUprobe(response.GetProcessUprobe())
-		case tetragon.EventType_PROCESS_LSM:
-			iEvent = ProcessProcessLsm(response.GetProcessLsm())
+		iEvent := ProcessResponse(response, tel.getHost(response.GetNodeName()))
+
+		if tel.Options.Output == eventprocessortetragontype.OutputOCSFEvents {
+			tel.streamOCSF(iEvent)
+			continue
+		}
+
+		sinkResult, err := tel.Sink.SinkEvent(iEvent)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to sink event: %v\n", err)
+			continue
+		}
+
+		for _, deviation := range sinkResult.Deviations {
+			var deviationJSON []byte
+			if tel.Options.Output == eventprocessortetragontype.OutputOCSF {
+				finding := eventprocessorocsftype.NewSecurityFindingWithAttack(deviation, eventprocessortetragontype.ToOCSF(iEvent), eventtype.AttackMappingOf(tel.Sink, deviation))
+				deviationJSON, _ = json.Marshal(finding)
+			} else {
+				deviationJSON, _ = json.Marshal(deviation)
+			}
+			fmt.Println(string(deviationJSON))
 		}
+	}
 
-		tel.Cluster.SinkEvent(iEvent)
+}
 
+// streamOCSF prints the event as an OCSF activity on a single line, events without an
+// OCSF counterpart are dropped.
+func (tel *tetragonEventListener) streamOCSF(event eventtype.IEvent) {
+	ocsfEvent := eventprocessortetragontype.ToOCSF(event)
+	if ocsfEvent == nil {
+		return
 	}
 
+	ocsfJSON, err := json.Marshal(ocsfEvent)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to marshal OCSF event: %v\n", err)
+		return
+	}
+	fmt.Println(string(ocsfJSON))
 }
 
 func GetDefaultOptions() eventprocessortetragontype.TetragonEventListerOptions {
@@ -223,16 +267,30 @@ func GetDefaultOptions() eventprocessortetragontype.TetragonEventListerOptions {
 		Retries:       5,
 		ServerAddress: "localhost:54321",
 		Namespaces:    []string{},
+		HostNodeName:  localNodeName(),
+		HostProcRoot:  eventprocessortetragontype.DefaultProcRoot,
+	}
+}
+
+// localNodeName returns the name of the node the profiler runs on, the NODE_NAME variable
+// usually set from the downward API or the hostname.
+func localNodeName() string {
+	if name := os.Getenv("NODE_NAME"); name != "" {
+		return name
 	}
+	name, _ := os.Hostname()
+	return name
 }
 
-func NewEventListener(cluster *eventtype.Cluster) *tetragonEventListener {
-	return NewEventListenerWithOptions(cluster, GetDefaultOptions())
+func NewEventListener(registry *eventtype.ClusterRegistry) *tetragonEventListener {
+	return NewEventListenerWithOptions(registry, GetDefaultOptions())
 }
 
-func NewEventListenerWithOptions(cluster *eventtype.Cluster, options eventprocessortetragontype.TetragonEventListerOptions) *tetragonEventListener {
+func NewEventListenerWithOptions(registry *eventtype.ClusterRegistry, options eventprocessortetragontype.TetragonEventListerOptions) *tetragonEventListener {
 	return &tetragonEventListener{
-		Options: options,
-		Cluster: cluster,
+		Options:  options,
+		Registry: registry,
+		Sink:     registry,
+		hosts:    map[string]*eventprocessortetragontype.Host{},
 	}
 }
@@ -7,72 +7,111 @@ import (
 	"github.com/cilium/tetragon/api/v1/tetragon"
 )
 
-func ProcessProcessExec(e *tetragon.ProcessExec) eventtype.IEvent {
-	if eventprocessortetragontype.IsHostEvent(e.Process) {
+// ProcessResponse converts a tetragon response into an event, nil for the event types that
+// are not profiled and for host events when host is nil.
+func ProcessResponse(response *tetragon.GetEventsResponse, host *eventprocessortetragontype.Host) eventtype.IEvent {
+	source := eventprocessortetragontype.NewSource(response)
+
+	switch response.EventType() {
+	case tetragon.EventType_PROCESS_EXEC:
+		return ProcessProcessExec(response.GetProcessExec(), source, host)
+	case tetragon.EventType_PROCESS_EXIT:
+		return ProcessProcessExit(response.GetProcessExit(), source, host)
+	case tetragon.EventType_PROCESS_LOADER:
+		return ProcessProcessLoader(response.GetProcessLoader(), source, host)
+	case tetragon.EventType_PROCESS_KPROBE:
+		return ProcessProcessKprobe(response.GetProcessKprobe(), source, host)
+	case tetragon.EventType_PROCESS_TRACEPOINT:
+		return ProcessProcessTracepoint(response.GetProcessTracepoint(), source, host)
+	case tetragon.EventType_PROCESS_UPROBE:
+		return ProcessProcessUprobe(response.GetProcessUprobe(), source, host)
+	case tetragon.EventType_PROCESS_LSM:
+		return ProcessProcessLsm(response.GetProcessLsm(), source, host)
+	}
+
+	return nil
+}
+
+func ProcessProcessExec(e *tetragon.ProcessExec, source eventprocessortetragontype.Source, host *eventprocessortetragontype.Host) eventtype.IEvent {
+	if eventprocessortetragontype.IsHostEvent(e.Process) && host == nil {
 		return nil
 	}
 
 	return &eventprocessortetragontype.ProcessExec{
 		ProcessExec: e,
+		Source:      source,
+		Host:        host,
 	}
 }
 
-func ProcessProcessExit(e *tetragon.ProcessExit) eventtype.IEvent {
-	if eventprocessortetragontype.IsHostEvent(e.Process) {
+func ProcessProcessExit(e *tetragon.ProcessExit, source eventprocessortetragontype.Source, host *eventprocessortetragontype.Host) eventtype.IEvent {
+	if eventprocessortetragontype.IsHostEvent(e.Process) && host == nil {
 		return nil
 	}
 
 	return &eventprocessortetragontype.ProcessExit{
 		ProcessExit: e,
+		Source:      source,
+		Host:        host,
 	}
 }
 
-func ProcessProcessLoader(e *tetragon.ProcessLoader) eventtype.IEvent {
-	if eventprocessortetragontype.IsHostEvent(e.Process) {
+func ProcessProcessLoader(e *tetragon.ProcessLoader, source eventprocessortetragontype.Source, host *eventprocessortetragontype.Host) eventtype.IEvent {
+	if eventprocessortetragontype.IsHostEvent(e.Process) && host == nil {
 		return nil
 	}
 
 	return &eventprocessortetragontype.ProcessLoader{
 		ProcessLoader: e,
+		Source:        source,
+		Host:          host,
 	}
 }
 
-func ProcessProcessKprobe(e *tetragon.ProcessKprobe) eventtype.IEvent {
-	if eventprocessortetragontype.IsHostEvent(e.Process) {
+func ProcessProcessKprobe(e *tetragon.ProcessKprobe, source eventprocessortetragontype.Source, host *eventprocessortetragontype.Host) eventtype.IEvent {
+	if eventprocessortetragontype.IsHostEvent(e.Process) && host == nil {
 		return nil
 	}
 
 	return &eventprocessortetragontype.ProcessKprobe{
 		ProcessKprobe: e,
+		Source:        source,
+		Host:          host,
 	}
 }
 
-func ProcessProcessTracepoint(e *tetragon.ProcessTracepoint) eventtype.IEvent {
-	if eventprocessortetragontype.IsHostEvent(e.Process) {
+func ProcessProcessTracepoint(e *tetragon.ProcessTracepoint, source eventprocessortetragontype.Source, host *eventprocessortetragontype.Host) eventtype.IEvent {
+	if eventprocessortetragontype.IsHostEvent(e.Process) && host == nil {
 		return nil
 	}
 
 	return &eventprocessortetragontype.ProcessTracepoint{
 		ProcessTracepoint: e,
+		Source:            source,
+		Host:              host,
 	}
 }
 
-func ProcessProcessUprobe(e *tetragon.ProcessUprobe) eventtype.IEvent {
-	if eventprocessortetragontype.IsHostEvent(e.Process) {
+func ProcessProcessUprobe(e *tetragon.ProcessUprobe, source eventprocessortetragontype.Source, host *eventprocessortetragontype.Host) eventtype.IEvent {
+	if eventprocessortetragontype.IsHostEvent(e.Process) && host == nil {
 		return nil
 	}
 
 	return &eventprocessortetragontype.ProcessUprobe{
 		ProcessUprobe: e,
+		Source:        source,
+		Host:          host,
 	}
 }
 
-func ProcessProcessLsm(e *tetragon.ProcessLsm) eventtype.IEvent {
-	if eventprocessortetragontype.IsHostEvent(e.Process) {
+func ProcessProcessLsm(e *tetragon.ProcessLsm, source eventprocessortetragontype.Source, host *eventprocessortetragontype.Host) eventtype.IEvent {
+	if eventprocessortetragontype.IsHostEvent(e.Process) && host == nil {
 		return nil
 	}
 
 	return &eventprocessortetragontype.ProcessLsm{
 		ProcessLsm: e,
+		Source:     source,
+		Host:       host,
 	}
 }
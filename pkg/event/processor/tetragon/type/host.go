@@ -0,0 +1,113 @@
+package eventprocessortetragontype
+
+import (
+	"errors"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"runtime-behavior-profiler/pkg/util"
+	"sync"
+
+	"github.com/cilium/tetragon/api/v1/tetragon"
+)
+
+const (
+	// HostNamespace is the synthetic namespace host processes are profiled under.
+	HostNamespace = "host"
+	// HostContainer is the container of host processes whose systemd unit or cgroup is unknown.
+	HostContainer = "host"
+	// DefaultProcRoot is where the proc filesystem of the node is mounted.
+	DefaultProcRoot = "/proc"
+
+	// maxHostUnits is the number of exec ids whose unit is cached.
+	maxHostUnits = 4096
+)
+
+var errHostProfilingDisabled = errors.New("host event received while host profiling is disabled")
+
+// Host profiles processes that do not run in a pod under the synthetic host namespace,
+// with a pod per node and a container per systemd unit, or cgroup outside of systemd.
+type Host struct {
+	NodeName string
+	// ProcRoot is read to resolve the unit of a process, it must be the proc filesystem of the
+	// node. Empty for nodes other than the one the profiler runs on, their processes are
+	// profiled under HostContainer.
+	ProcRoot string
+
+	mu sync.Mutex
+	// units caches the unit of an exec id, the process may be gone by the time it exits.
+	units map[string]string
+}
+
+// NewHost returns the host profile of a node, procRoot is empty unless the node is the one
+// the profiler runs on.
+func NewHost(nodeName string, procRoot string) *Host {
+	return &Host{
+		NodeName: nodeName,
+		ProcRoot: procRoot,
+		units:    map[string]string{},
+	}
+}
+
+// GetNamespace returns the synthetic host namespace.
+func (host *Host) GetNamespace() (*eventtype.Namespace, error) {
+	if host == nil {
+		return nil, errHostProfilingDisabled
+	}
+
+	return &eventtype.Namespace{
+		Name: HostNamespace,
+		Pods: map[string]*eventtype.Pod{},
+	}, nil
+}
+
+// GetPod returns the synthetic pod of the node.
+func (host *Host) GetPod() (*eventtype.Pod, error) {
+	if host == nil {
+		return nil, errHostProfilingDisabled
+	}
+
+	return &eventtype.Pod{
+		Name:       host.NodeName,
+		Containers: map[string]*eventtype.Container{},
+	}, nil
+}
+
+// GetContainer returns the synthetic container of the systemd unit or cgroup the process runs in.
+func (host *Host) GetContainer(process *tetragon.Process) (*eventtype.Container, error) {
+	if host == nil {
+		return nil, errHostProfilingDisabled
+	}
+
+	return &eventtype.Container{
+		Name: host.unit(process),
+		Image: &eventtype.Image{
+			Repo: HostNamespace,
+		},
+		Processes: map[string]*eventtype.Process{},
+	}, nil
+}
+
+// unit resolves the systemd unit or cgroup of the process, falling back to HostContainer.
+func (host *Host) unit(process *tetragon.Process) string {
+	if host.ProcRoot == "" {
+		return HostContainer
+	}
+
+	host.mu.Lock()
+	defer host.mu.Unlock()
+
+	if unit, ok := host.units[process.ExecId]; ok {
+		return unit
+	}
+
+	unit := util.ReadCgroupUnit(host.ProcRoot, process.GetPid().GetValue())
+	if unit == "" {
+		unit = HostContainer
+	}
+
+	if len(host.units) >= maxHostUnits {
+		host.units = map[string]string{}
+	}
+	host.units[process.ExecId] = unit
+
+	return unit
+}
@@ -0,0 +1,86 @@
+package eventprocessortetragontype
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cilium/tetragon/api/v1/tetragon"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestHostEventIsProfiledUnderNode(t *testing.T) {
+	procRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(procRoot, "42"), 0o755); err != nil {
+		t.Fatalf("failed to create proc dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(procRoot, "42", "cgroup"), []byte("0::/system.slice/kubelet.service\n"), 0o644); err != nil {
+		t.Fatalf("failed to write cgroup file: %v", err)
+	}
+
+	event := &ProcessExec{
+		ProcessExec: &tetragon.ProcessExec{
+			Process: &tetragon.Process{Binary: "/usr/bin/kubelet", ExecId: "kubelet", Pid: wrapperspb.UInt32(42)},
+		},
+		Host: NewHost("worker-1", procRoot),
+	}
+
+	namespace, err := event.GetNamespace()
+	if err != nil || namespace.Name != HostNamespace {
+		t.Errorf("namespace = %+v, %v; want %s", namespace, err, HostNamespace)
+	}
+	pod, err := event.GetPod()
+	if err != nil || pod.Name != "worker-1" {
+		t.Errorf("pod = %+v, %v; want worker-1", pod, err)
+	}
+	container, err := event.GetContainer()
+	if err != nil || container.Name != "kubelet.service" {
+		t.Errorf("container = %+v, %v; want kubelet.service", container, err)
+	}
+
+	// The unit is cached by exec id, the process may be gone when later events arrive.
+	if err := os.RemoveAll(filepath.Join(procRoot, "42")); err != nil {
+		t.Fatalf("failed to remove proc dir: %v", err)
+	}
+	container, _ = event.GetContainer()
+	if container.Name != "kubelet.service" {
+		t.Errorf("cached container = %q; want kubelet.service", container.Name)
+	}
+
+	unknown := &ProcessExec{
+		ProcessExec: &tetragon.ProcessExec{
+			Process: &tetragon.Process{Binary: "/usr/bin/true", ExecId: "true", Pid: wrapperspb.UInt32(43)},
+		},
+		Host: event.Host,
+	}
+	container, _ = unknown.GetContainer()
+	if container.Name != HostContainer {
+		t.Errorf("container of unknown unit = %q; want %s", container.Name, HostContainer)
+	}
+}
+
+func TestHostEventOfAnotherNode(t *testing.T) {
+	event := &ProcessExec{
+		ProcessExec: &tetragon.ProcessExec{
+			Process: &tetragon.Process{Binary: "/usr/bin/kubelet", ExecId: "kubelet", Pid: wrapperspb.UInt32(1)},
+		},
+		Host: NewHost("worker-2", ""),
+	}
+
+	container, err := event.GetContainer()
+	if err != nil || container.Name != HostContainer {
+		t.Errorf("container = %+v, %v; want %s without a proc filesystem", container, err, HostContainer)
+	}
+}
+
+func TestHostEventWithoutHostProfiling(t *testing.T) {
+	event := &ProcessExec{
+		ProcessExec: &tetragon.ProcessExec{
+			Process: &tetragon.Process{Binary: "/usr/bin/kubelet", ExecId: "kubelet"},
+		},
+	}
+
+	if _, err := event.GetNamespace(); err == nil {
+		t.Errorf("expected an error for a host event without host profiling")
+	}
+}
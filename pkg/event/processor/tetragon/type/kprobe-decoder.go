@@ -0,0 +1,386 @@
+package eventprocessortetragontype
+
+import (
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"strings"
+	"sync"
+
+	"github.com/cilium/tetragon/api/v1/tetragon"
+)
+
+// KprobeDecoder decodes the arguments of a kernel function call into a typed kernel behaviour.
+// Decoders are lenient, arguments missing from the tracing policy leave fields empty.
+type KprobeDecoder func(args []*tetragon.KprobeArgument) *eventtype.KernelBehavior
+
+var (
+	kprobeDecodersMu sync.RWMutex
+	kprobeDecoders   = map[string]KprobeDecoder{
+		"commit_creds": decodeCommitCreds,
+
+		"cap_capable": decodeCapable,
+
+		"security_bprm_check": decodeBprmCheck,
+
+		"sys_mount":         decodeSysMount,
+		"security_sb_mount": decodeSecuritySbMount,
+
+		"bpf_check":              decodeBpfCheck,
+		"security_bpf_prog":      decodeBpfCheck,
+		"security_bpf_map_alloc": decodeBpfMap,
+		"bpf_map_alloc":          decodeBpfMap,
+
+		"sys_ptrace": decodePtrace,
+
+		"security_file_permission": decodeFilePermission,
+		"security_file_open":       decodeFile,
+		"security_mmap_file":       decodeFile,
+		"security_path_truncate":   decodeFile,
+		"fd_install":               decodeFile,
+
+		"tcp_connect":     decodeSock,
+		"tcp_close":       decodeSock,
+		"tcp_sendmsg":     decodeSock,
+		"udp_sendmsg":     decodeSock,
+		"inet_csk_accept": decodeSock,
+
+		"do_init_module":                 decodeModule,
+		"security_kernel_module_request": decodeModule,
+
+		"sys_unshare": decodeUnshare,
+		"sys_setns":   decodeSetns,
+	}
+)
+
+// cloneNamespaceFlags maps the CLONE_NEW* flags of unshare and setns to namespace types.
+var cloneNamespaceFlags = []struct {
+	flag   int64
+	nsType string
+}{
+	{0x00000080, "time"},
+	{0x00020000, "mnt"},
+	{0x02000000, "cgroup"},
+	{0x04000000, "uts"},
+	{0x08000000, "ipc"},
+	{0x10000000, "user"},
+	{0x20000000, "pid"},
+	{0x40000000, "net"},
+}
+
+// MAY_* bits of the mask of security_file_permission.
+const (
+	mayWrite  = 0x2
+	mayRead   = 0x4
+	mayAppend = 0x8
+)
+
+// RegisterKprobeDecoder adds or replaces the decoder of a kernel function.
+func RegisterKprobeDecoder(function string, decoder KprobeDecoder) {
+	kprobeDecodersMu.Lock()
+	defer kprobeDecodersMu.Unlock()
+
+	kprobeDecoders[eventtype.NormalizeFunctionName(function)] = decoder
+}
+
+// DecodeKprobe returns the kernel behaviour of a kernel function call, or nil when
+// no decoder is registered for the function.
+func DecodeKprobe(function string, args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+	kprobeDecodersMu.RLock()
+	decoder, ok := kprobeDecoders[eventtype.NormalizeFunctionName(function)]
+	kprobeDecodersMu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	behavior := decoder(args)
+	if behavior != nil {
+		behavior.Function = function
+	}
+
+	return behavior
+}
+
+// DecodeLsm returns the kernel behaviour of an LSM hook, decoded as the security_ function
+// implementing the hook, or nil when no decoder is registered for it.
+func DecodeLsm(hook string, args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+	return DecodeKprobe("security_"+strings.TrimPrefix(hook, "security_"), args)
+}
+
+func decodeCommitCreds(args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+	credentials := &eventtype.CredentialsBehavior{}
+
+	if arg := findArg(args, func(a *tetragon.KprobeArgument) bool { return a.GetProcessCredentialsArg() != nil }); arg != nil {
+		cred := arg.GetProcessCredentialsArg()
+		credentials.UID = cred.GetUid().GetValue()
+		credentials.GID = cred.GetGid().GetValue()
+		credentials.EUID = cred.GetEuid().GetValue()
+		credentials.EGID = cred.GetEgid().GetValue()
+		credentials.Capabilities = capabilityNames(cred.GetCaps().GetEffective())
+	}
+
+	return &eventtype.KernelBehavior{
+		Kind:        eventtype.KernelBehaviorCredentials,
+		Credentials: credentials,
+	}
+}
+
+func decodeCapable(args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+	capability := &eventtype.CapabilityBehavior{}
+
+	if arg := findArg(args, func(a *tetragon.KprobeArgument) bool { return a.GetCapabilityArg() != nil }); arg != nil {
+		capability.Name = arg.GetCapabilityArg().GetName()
+	} else if value, ok := numericArg(args, 0); ok {
+		capability.Name = tetragon.CapabilitiesType(value).String()
+	}
+
+	return &eventtype.KernelBehavior{
+		Kind:       eventtype.KernelBehaviorCapability,
+		Capability: capability,
+	}
+}
+
+func decodeBprmCheck(args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+	exec := &eventtype.ExecBehavior{}
+
+	if arg := findArg(args, func(a *tetragon.KprobeArgument) bool { return a.GetLinuxBinprmArg() != nil }); arg != nil {
+		exec.Path = arg.GetLinuxBinprmArg().GetPath()
+	} else if arg := findArg(args, func(a *tetragon.KprobeArgument) bool { return a.GetFileArg() != nil }); arg != nil {
+		exec.Path = arg.GetFileArg().GetPath()
+	}
+
+	return &eventtype.KernelBehavior{
+		Kind: eventtype.KernelBehaviorExec,
+		Exec: exec,
+	}
+}
+
+// decodeSysMount decodes mount(dev_name, dir_name, type, flags, data).
+func decodeSysMount(args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+	return &eventtype.KernelBehavior{
+		Kind: eventtype.KernelBehaviorMount,
+		Mount: &eventtype.MountBehavior{
+			Source: stringArg(args, 0),
+			Target: stringArg(args, 1),
+			FSType: stringArg(args, 2),
+		},
+	}
+}
+
+// decodeSecuritySbMount decodes security_sb_mount(dev_name, path, type, flags, data).
+func decodeSecuritySbMount(args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+	mount := &eventtype.MountBehavior{
+		Source: stringArg(args, 0),
+		FSType: stringArg(args, 1),
+	}
+	if arg := findArg(args, func(a *tetragon.KprobeArgument) bool { return a.GetPathArg() != nil }); arg != nil {
+		mount.Target = arg.GetPathArg().GetPath()
+	}
+
+	return &eventtype.KernelBehavior{
+		Kind:  eventtype.KernelBehaviorMount,
+		Mount: mount,
+	}
+}
+
+func decodeBpfCheck(args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+	bpf := &eventtype.BpfBehavior{}
+
+	if arg := findArg(args, func(a *tetragon.KprobeArgument) bool { return a.GetBpfAttrArg() != nil }); arg != nil {
+		bpf.ProgType = arg.GetBpfAttrArg().GetProgType()
+		bpf.ProgName = arg.GetBpfAttrArg().GetProgName()
+	}
+
+	return &eventtype.KernelBehavior{
+		Kind: eventtype.KernelBehaviorBpf,
+		Bpf:  bpf,
+	}
+}
+
+func decodeBpfMap(args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+	bpf := &eventtype.BpfBehavior{}
+
+	if arg := findArg(args, func(a *tetragon.KprobeArgument) bool { return a.GetBpfMapArg() != nil }); arg != nil {
+		bpf.MapType = arg.GetBpfMapArg().GetMapType()
+		bpf.MapName = arg.GetBpfMapArg().GetMapName()
+	}
+
+	return &eventtype.KernelBehavior{
+		Kind: eventtype.KernelBehaviorBpf,
+		Bpf:  bpf,
+	}
+}
+
+// decodePtrace decodes ptrace(request, pid, addr, data).
+func decodePtrace(args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+	request, _ := numericArg(args, 0)
+
+	return &eventtype.KernelBehavior{
+		Kind: eventtype.KernelBehaviorPtrace,
+		Ptrace: &eventtype.PtraceBehavior{
+			Request: request,
+		},
+	}
+}
+
+func decodeFile(args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+	file := &eventtype.FileBehavior{}
+
+	if arg := findArg(args, func(a *tetragon.KprobeArgument) bool { return a.GetFileArg() != nil }); arg != nil {
+		file.Path = arg.GetFileArg().GetPath()
+		file.Permission = arg.GetFileArg().GetPermission()
+		file.Flags = arg.GetFileArg().GetFlags()
+	} else if arg := findArg(args, func(a *tetragon.KprobeArgument) bool { return a.GetPathArg() != nil }); arg != nil {
+		file.Path = arg.GetPathArg().GetPath()
+		file.Permission = arg.GetPathArg().GetPermission()
+		file.Flags = arg.GetPathArg().GetFlags()
+	}
+
+	return &eventtype.KernelBehavior{
+		Kind: eventtype.KernelBehaviorFile,
+		File: file,
+	}
+}
+
+// decodeFilePermission decodes security_file_permission, whose MAY_* mask tells a read
+// from a write.
+func decodeFilePermission(args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+	behavior := decodeFile(args)
+
+	if mask, ok := numericArg(args, 0); ok {
+		var access []string
+		if mask&mayRead != 0 {
+			access = append(access, eventtype.FileAccessRead)
+		}
+		if mask&(mayWrite|mayAppend) != 0 {
+			access = append(access, eventtype.FileAccessWrite)
+		}
+		behavior.File.Access = strings.Join(access, ",")
+	}
+
+	return behavior
+}
+
+func decodeSock(args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+	network := &eventtype.NetworkBehavior{}
+
+	if arg := findArg(args, func(a *tetragon.KprobeArgument) bool { return a.GetSockArg() != nil }); arg != nil {
+		sock := arg.GetSockArg()
+		network.Family = sock.GetFamily()
+		network.Protocol = sock.GetProtocol()
+		network.DestinationAddr = sock.GetDaddr()
+		network.DestinationPort = sock.GetDport()
+	}
+
+	return &eventtype.KernelBehavior{
+		Kind:    eventtype.KernelBehaviorNetwork,
+		Network: network,
+	}
+}
+
+func decodeModule(args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+	module := &eventtype.ModuleBehavior{}
+
+	if arg := findArg(args, func(a *tetragon.KprobeArgument) bool { return a.GetModuleArg() != nil }); arg != nil {
+		module.Name = arg.GetModuleArg().GetName()
+	} else {
+		module.Name = stringArg(args, 0)
+	}
+
+	return &eventtype.KernelBehavior{
+		Kind:   eventtype.KernelBehaviorModule,
+		Module: module,
+	}
+}
+
+// decodeUnshare decodes unshare(flags).
+func decodeUnshare(args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+	flags, _ := numericArg(args, 0)
+
+	return &eventtype.KernelBehavior{
+		Kind: eventtype.KernelBehaviorNamespace,
+		Namespace: &eventtype.NamespaceBehavior{
+			Operation: "unshare",
+			Types:     namespaceTypes(flags),
+		},
+	}
+}
+
+// decodeSetns decodes setns(fd, nstype).
+func decodeSetns(args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+	nsType, _ := numericArg(args, 1)
+
+	return &eventtype.KernelBehavior{
+		Kind: eventtype.KernelBehaviorNamespace,
+		Namespace: &eventtype.NamespaceBehavior{
+			Operation: "setns",
+			Types:     namespaceTypes(nsType),
+		},
+	}
+}
+
+// namespaceTypes returns the namespace types selected by CLONE_NEW* flags.
+func namespaceTypes(flags int64) []string {
+	nsTypes := []string{}
+	for _, cloneFlag := range cloneNamespaceFlags {
+		if flags&cloneFlag.flag != 0 {
+			nsTypes = append(nsTypes, cloneFlag.nsType)
+		}
+	}
+	return nsTypes
+}
+
+// findArg returns the first argument matching the predicate.
+func findArg(args []*tetragon.KprobeArgument, match func(*tetragon.KprobeArgument) bool) *tetragon.KprobeArgument {
+	for _, arg := range args {
+		if match(arg) {
+			return arg
+		}
+	}
+	return nil
+}
+
+// stringArg returns the n-th string argument, or an empty string.
+func stringArg(args []*tetragon.KprobeArgument, n int) string {
+	for _, arg := range args {
+		if value, ok := arg.GetArg().(*tetragon.KprobeArgument_StringArg); ok {
+			if n == 0 {
+				return value.StringArg
+			}
+			n--
+		}
+	}
+	return ""
+}
+
+// numericArg returns the n-th integer argument whatever its width.
+func numericArg(args []*tetragon.KprobeArgument, n int) (int64, bool) {
+	for _, arg := range args {
+		var value int64
+		switch v := arg.GetArg().(type) {
+		case *tetragon.KprobeArgument_IntArg:
+			value = int64(v.IntArg)
+		case *tetragon.KprobeArgument_LongArg:
+			value = v.LongArg
+		case *tetragon.KprobeArgument_UintArg:
+			value = int64(v.UintArg)
+		case *tetragon.KprobeArgument_SizeArg:
+			value = int64(v.SizeArg)
+		default:
+			continue
+		}
+		if n == 0 {
+			return value, true
+		}
+		n--
+	}
+	return 0, false
+}
+
+// capabilityNames returns the names of the given capabilities.
+func capabilityNames(capabilities []tetragon.CapabilitiesType) []string {
+	names := make([]string, 0, len(capabilities))
+	for _, capability := range capabilities {
+		names = append(names, capability.String())
+	}
+	return names
+}
@@ -0,0 +1,223 @@
+package eventprocessortetragontype
+
+import (
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"testing"
+
+	"github.com/cilium/tetragon/api/v1/tetragon"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func newTestKprobe(function string, args ...*tetragon.KprobeArgument) *ProcessKprobe {
+	return &ProcessKprobe{
+		ProcessKprobe: &tetragon.ProcessKprobe{
+			Process:      &tetragon.Process{Binary: "/usr/bin/app"},
+			FunctionName: function,
+			Args:         args,
+		},
+	}
+}
+
+func TestProcessKprobeGetKernelBehavior(t *testing.T) {
+	tests := []struct {
+		name     string
+		kprobe   *ProcessKprobe
+		expected eventtype.KernelBehavior
+	}{
+		{
+			name: "commit_creds",
+			kprobe: newTestKprobe("commit_creds", &tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_ProcessCredentialsArg{
+				ProcessCredentialsArg: &tetragon.ProcessCredentials{
+					Uid:  wrapperspb.UInt32(0),
+					Gid:  wrapperspb.UInt32(0),
+					Euid: wrapperspb.UInt32(0),
+					Egid: wrapperspb.UInt32(0),
+					Caps: &tetragon.Capabilities{Effective: []tetragon.CapabilitiesType{tetragon.CapabilitiesType_CAP_SYS_ADMIN}},
+				},
+			}}),
+			expected: eventtype.KernelBehavior{
+				Kind:        eventtype.KernelBehaviorCredentials,
+				Function:    "commit_creds",
+				Credentials: &eventtype.CredentialsBehavior{Capabilities: []string{"CAP_SYS_ADMIN"}},
+			},
+		},
+		{
+			name: "cap_capable with capability argument",
+			kprobe: newTestKprobe("cap_capable", &tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_CapabilityArg{
+				CapabilityArg: &tetragon.KprobeCapability{Name: "CAP_NET_RAW"},
+			}}),
+			expected: eventtype.KernelBehavior{
+				Kind:       eventtype.KernelBehaviorCapability,
+				Function:   "cap_capable",
+				Capability: &eventtype.CapabilityBehavior{Name: "CAP_NET_RAW"},
+			},
+		},
+		{
+			name:   "cap_capable with int argument",
+			kprobe: newTestKprobe("cap_capable", &tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_IntArg{IntArg: 21}}),
+			expected: eventtype.KernelBehavior{
+				Kind:       eventtype.KernelBehaviorCapability,
+				Function:   "cap_capable",
+				Capability: &eventtype.CapabilityBehavior{Name: "CAP_SYS_ADMIN"},
+			},
+		},
+		{
+			name: "security_bprm_check",
+			kprobe: newTestKprobe("security_bprm_check", &tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_LinuxBinprmArg{
+				LinuxBinprmArg: &tetragon.KprobeLinuxBinprm{Path: "/tmp/payload"},
+			}}),
+			expected: eventtype.KernelBehavior{
+				Kind:     eventtype.KernelBehaviorExec,
+				Function: "security_bprm_check",
+				Exec:     &eventtype.ExecBehavior{Path: "/tmp/payload"},
+			},
+		},
+		{
+			name: "__x64_sys_mount",
+			kprobe: newTestKprobe("__x64_sys_mount",
+				&tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_StringArg{StringArg: "/dev/sda1"}},
+				&tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_StringArg{StringArg: "/host"}},
+				&tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_StringArg{StringArg: "ext4"}},
+				&tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_LongArg{LongArg: 0}},
+			),
+			expected: eventtype.KernelBehavior{
+				Kind:     eventtype.KernelBehaviorMount,
+				Function: "__x64_sys_mount",
+				Mount:    &eventtype.MountBehavior{Source: "/dev/sda1", Target: "/host", FSType: "ext4"},
+			},
+		},
+		{
+			name: "bpf_check",
+			kprobe: newTestKprobe("bpf_check", &tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_BpfAttrArg{
+				BpfAttrArg: &tetragon.KprobeBpfAttr{ProgType: "BPF_PROG_TYPE_KPROBE", ProgName: "hook"},
+			}}),
+			expected: eventtype.KernelBehavior{
+				Kind:     eventtype.KernelBehaviorBpf,
+				Function: "bpf_check",
+				Bpf:      &eventtype.BpfBehavior{ProgType: "BPF_PROG_TYPE_KPROBE", ProgName: "hook"},
+			},
+		},
+		{
+			name: "__arm64_sys_ptrace",
+			kprobe: newTestKprobe("__arm64_sys_ptrace",
+				&tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_LongArg{LongArg: 16}},
+				&tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_LongArg{LongArg: 1}},
+			),
+			expected: eventtype.KernelBehavior{
+				Kind:     eventtype.KernelBehaviorPtrace,
+				Function: "__arm64_sys_ptrace",
+				Ptrace:   &eventtype.PtraceBehavior{Request: 16},
+			},
+		},
+		{
+			name: "security_file_permission",
+			kprobe: newTestKprobe("security_file_permission",
+				&tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_FileArg{FileArg: &tetragon.KprobeFile{Path: "/etc/shadow", Permission: "-rw-r-----"}}},
+				&tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_IntArg{IntArg: 4}},
+			),
+			expected: eventtype.KernelBehavior{
+				Kind:     eventtype.KernelBehaviorFile,
+				Function: "security_file_permission",
+				File:     &eventtype.FileBehavior{Path: "/etc/shadow", Permission: "-rw-r-----", Access: "read"},
+			},
+		},
+		{
+			name: "security_file_permission append",
+			kprobe: newTestKprobe("security_file_permission",
+				&tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_FileArg{FileArg: &tetragon.KprobeFile{Path: "/var/log/app.log", Permission: "-rw-r-----"}}},
+				&tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_IntArg{IntArg: 0xa}},
+			),
+			expected: eventtype.KernelBehavior{
+				Kind:     eventtype.KernelBehaviorFile,
+				Function: "security_file_permission",
+				File:     &eventtype.FileBehavior{Path: "/var/log/app.log", Permission: "-rw-r-----", Access: "write"},
+			},
+		},
+		{
+			name: "tcp_connect",
+			kprobe: newTestKprobe("tcp_connect", &tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_SockArg{
+				SockArg: &tetragon.KprobeSock{Family: "AF_INET", Protocol: "IPPROTO_TCP", Daddr: "10.0.0.1", Dport: 443, Sport: 51234},
+			}}),
+			expected: eventtype.KernelBehavior{
+				Kind:     eventtype.KernelBehaviorNetwork,
+				Function: "tcp_connect",
+				Network:  &eventtype.NetworkBehavior{Family: "AF_INET", Protocol: "IPPROTO_TCP", DestinationAddr: "10.0.0.1", DestinationPort: 443},
+			},
+		},
+		{
+			name: "do_init_module",
+			kprobe: newTestKprobe("do_init_module", &tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_ModuleArg{
+				ModuleArg: &tetragon.KernelModule{Name: "rootkit"},
+			}}),
+			expected: eventtype.KernelBehavior{
+				Kind:     eventtype.KernelBehaviorModule,
+				Function: "do_init_module",
+				Module:   &eventtype.ModuleBehavior{Name: "rootkit"},
+			},
+		},
+		{
+			name:   "__x64_sys_unshare",
+			kprobe: newTestKprobe("__x64_sys_unshare", &tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_IntArg{IntArg: 0x10020000}}),
+			expected: eventtype.KernelBehavior{
+				Kind:      eventtype.KernelBehaviorNamespace,
+				Function:  "__x64_sys_unshare",
+				Namespace: &eventtype.NamespaceBehavior{Operation: "unshare", Types: []string{"mnt", "user"}},
+			},
+		},
+		{
+			name: "__x64_sys_setns",
+			kprobe: newTestKprobe("__x64_sys_setns",
+				&tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_IntArg{IntArg: 3}},
+				&tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_IntArg{IntArg: 0x40000000}},
+			),
+			expected: eventtype.KernelBehavior{
+				Kind:      eventtype.KernelBehaviorNamespace,
+				Function:  "__x64_sys_setns",
+				Namespace: &eventtype.NamespaceBehavior{Operation: "setns", Types: []string{"net"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		behavior, err := test.kprobe.GetKernelBehavior()
+		if err != nil {
+			t.Errorf("%s: failed to decode: %v", test.name, err)
+			continue
+		}
+		if behavior == nil {
+			t.Errorf("%s: no behavior decoded", test.name)
+			continue
+		}
+		if behavior.GetKey() != test.expected.GetKey() {
+			t.Errorf("%s: decoded %s; want %s", test.name, behavior.GetKey(), test.expected.GetKey())
+		}
+	}
+}
+
+func TestDecodeKprobeUnknownFunction(t *testing.T) {
+	behavior, err := newTestKprobe("vfs_read").GetKernelBehavior()
+	if err != nil || behavior != nil {
+		t.Errorf("unknown function decoded to %v, %v; want nil", behavior, err)
+	}
+}
+
+func TestRegisterKprobeDecoder(t *testing.T) {
+	t.Cleanup(func() {
+		kprobeDecodersMu.Lock()
+		defer kprobeDecodersMu.Unlock()
+		delete(kprobeDecoders, "sys_chroot")
+	})
+	RegisterKprobeDecoder("__x64_sys_chroot", func(args []*tetragon.KprobeArgument) *eventtype.KernelBehavior {
+		return &eventtype.KernelBehavior{
+			Kind: eventtype.KernelBehaviorFile,
+			File: &eventtype.FileBehavior{Path: stringArg(args, 0)},
+		}
+	})
+
+	behavior := DecodeKprobe("__arm64_sys_chroot", []*tetragon.KprobeArgument{
+		{Arg: &tetragon.KprobeArgument_StringArg{StringArg: "/host"}},
+	})
+	if behavior == nil || behavior.File.Path != "/host" {
+		t.Errorf("registered decoder not used, got %+v", behavior)
+	}
+}
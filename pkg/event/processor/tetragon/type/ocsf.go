@@ -0,0 +1,253 @@
+package eventprocessortetragontype
+
+import (
+	eventprocessorocsftype "runtime-behavior-profiler/pkg/event/processor/ocsf/type"
+	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"strings"
+	"time"
+
+	"github.com/cilium/tetragon/api/v1/tetragon"
+	networkenums "github.com/valllabh/ocsf-schema-golang/ocsf/v1_0_0/events/network/enums"
+	systemenums "github.com/valllabh/ocsf-schema-golang/ocsf/v1_0_0/events/system/enums"
+	"github.com/valllabh/ocsf-schema-golang/ocsf/v1_0_0/objects"
+)
+
+// fileActivities maps the file functions known to the kprobe decoder to OCSF file activities.
+var fileActivities = map[string]systemenums.FILE_ACTIVITY_ACTIVITY_ID{
+	"security_file_open":     systemenums.FILE_ACTIVITY_ACTIVITY_ID_FILE_ACTIVITY_ACTIVITY_ID_OPEN,
+	"fd_install":             systemenums.FILE_ACTIVITY_ACTIVITY_ID_FILE_ACTIVITY_ACTIVITY_ID_OPEN,
+	"security_mmap_file":     systemenums.FILE_ACTIVITY_ACTIVITY_ID_FILE_ACTIVITY_ACTIVITY_ID_READ,
+	"security_path_truncate": systemenums.FILE_ACTIVITY_ACTIVITY_ID_FILE_ACTIVITY_ACTIVITY_ID_UPDATE,
+}
+
+// networkActivities maps the socket functions known to the kprobe decoder to OCSF network activities.
+var networkActivities = map[string]networkenums.NETWORK_ACTIVITY_ACTIVITY_ID{
+	"tcp_connect":     networkenums.NETWORK_ACTIVITY_ACTIVITY_ID_NETWORK_ACTIVITY_ACTIVITY_ID_OPEN,
+	"inet_csk_accept": networkenums.NETWORK_ACTIVITY_ACTIVITY_ID_NETWORK_ACTIVITY_ACTIVITY_ID_OPEN,
+	"tcp_close":       networkenums.NETWORK_ACTIVITY_ACTIVITY_ID_NETWORK_ACTIVITY_ACTIVITY_ID_CLOSE,
+	"tcp_sendmsg":     networkenums.NETWORK_ACTIVITY_ACTIVITY_ID_NETWORK_ACTIVITY_ACTIVITY_ID_TRAFFIC,
+	"udp_sendmsg":     networkenums.NETWORK_ACTIVITY_ACTIVITY_ID_NETWORK_ACTIVITY_ACTIVITY_ID_TRAFFIC,
+}
+
+// ToOCSF converts a Tetragon event into an OCSF 1.0.0 activity. Process executions and exits
+// become Process Activities, socket kprobes Network Activities and file kprobes and LSM hooks
+// File System Activities. It returns nil for events without an OCSF counterpart.
+func ToOCSF(event eventtype.IEvent) *eventprocessorocsftype.OCSFEvent {
+	switch e := event.(type) {
+	case *ProcessExec:
+		return processActivity(e.Process, e.Parent, e.Source, systemenums.PROCESS_ACTIVITY_ACTIVITY_ID_PROCESS_ACTIVITY_ACTIVITY_ID_LAUNCH, nil)
+	case *ProcessExit:
+		return processActivity(e.Process, e.Parent, e.Source, systemenums.PROCESS_ACTIVITY_ACTIVITY_ID_PROCESS_ACTIVITY_ACTIVITY_ID_TERMINATE, e.ProcessExit)
+	case *ProcessKprobe:
+		return kernelActivity(e.Process, e.Parent, e.Source, DecodeKprobe(e.FunctionName, e.Args))
+	case *ProcessLsm:
+		return kernelActivity(e.Process, e.Parent, e.Source, DecodeLsm(e.FunctionName, e.Args))
+	}
+
+	return nil
+}
+
+func processActivity(process *tetragon.Process, parent *tetragon.Process, source Source, activity systemenums.PROCESS_ACTIVITY_ACTIVITY_ID, exit *tetragon.ProcessExit) *eventprocessorocsftype.OCSFEvent {
+	actor := ocsfProcess(process, parent)
+
+	processActivity := &eventprocessorocsftype.ProcessActivity{
+		Resources: ocsfResources(process, source),
+	}
+	processActivity.Metadata = ocsfMetadata()
+	processActivity.Time = ocsfTime(source.Time)
+	processActivity.Device = ocsfDevice(source)
+	processActivity.CategoryUid = systemenums.PROCESS_ACTIVITY_CATEGORY_UID_PROCESS_ACTIVITY_CATEGORY_UID_SYSTEM_ACTIVITY
+	processActivity.CategoryName = "System Activity"
+	processActivity.ClassUid = systemenums.PROCESS_ACTIVITY_CLASS_UID_PROCESS_ACTIVITY_CLASS_UID_PROCESS_ACTIVITY
+	processActivity.ClassName = "Process Activity"
+	processActivity.ActivityId = activity
+	processActivity.ActivityName = activityName(systemenums.PROCESS_ACTIVITY_ACTIVITY_ID_name[int32(activity)], "PROCESS_ACTIVITY_ACTIVITY_ID_")
+	processActivity.TypeUid = systemenums.PROCESS_ACTIVITY_TYPE_UID(1007*100 + int32(activity))
+	processActivity.Process = actor
+	processActivity.Actor = &objects.Actor{Process: actor}
+
+	if exit != nil {
+		processActivity.ExitCode = int32(exit.Status)
+		if exit.Time != nil {
+			processActivity.Time = exit.Time.AsTime().UnixMilli()
+			actor.TerminatedTime = processActivity.Time
+		}
+	}
+
+	return &eventprocessorocsftype.OCSFEvent{
+		OCSF_1_0_0: &eventprocessorocsftype.OCSF_1_0_0{ProcessActivity: processActivity},
+	}
+}
+
+func kernelActivity(process *tetragon.Process, parent *tetragon.Process, source Source, behavior *eventtype.KernelBehavior) *eventprocessorocsftype.OCSFEvent {
+	if behavior == nil {
+		return nil
+	}
+	function := eventtype.NormalizeFunctionName(behavior.Function)
+
+	switch {
+	case behavior.File != nil:
+		return fileActivity(process, parent, source, function, behavior)
+	case behavior.Network != nil:
+		return networkActivity(process, parent, source, function, behavior.Network)
+	}
+
+	return nil
+}
+
+func fileActivity(process *tetragon.Process, parent *tetragon.Process, source Source, function string, behavior *eventtype.KernelBehavior) *eventprocessorocsftype.OCSFEvent {
+	file := behavior.File
+	activity, ok := fileActivities[function]
+	if !ok {
+		activity = systemenums.FILE_ACTIVITY_ACTIVITY_ID_FILE_ACTIVITY_ACTIVITY_ID_OTHER
+		// A permission check is a read or an update by its access mask, without it the mode
+		// of the file does not tell which.
+		if function == "security_file_permission" && file.Access != "" {
+			activity = systemenums.FILE_ACTIVITY_ACTIVITY_ID_FILE_ACTIVITY_ACTIVITY_ID_READ
+			if strings.Contains(file.Access, eventtype.FileAccessWrite) {
+				activity = systemenums.FILE_ACTIVITY_ACTIVITY_ID_FILE_ACTIVITY_ACTIVITY_ID_UPDATE
+			}
+		}
+	}
+
+	name := file.Path
+	if index := strings.LastIndex(file.Path, "/"); index >= 0 {
+		name = file.Path[index+1:]
+	}
+
+	fileActivity := &eventprocessorocsftype.FileActivity{
+		Resources: ocsfResources(process, source),
+	}
+	fileActivity.Metadata = ocsfMetadata()
+	fileActivity.Time = ocsfTime(source.Time)
+	fileActivity.Device = ocsfDevice(source)
+	fileActivity.CategoryUid = systemenums.FILE_ACTIVITY_CATEGORY_UID_FILE_ACTIVITY_CATEGORY_UID_SYSTEM_ACTIVITY
+	fileActivity.CategoryName = "System Activity"
+	fileActivity.ClassUid = systemenums.FILE_ACTIVITY_CLASS_UID_FILE_ACTIVITY_CLASS_UID_FILE_SYSTEM_ACTIVITY
+	fileActivity.ClassName = "File System Activity"
+	fileActivity.ActivityId = activity
+	fileActivity.ActivityName = activityName(systemenums.FILE_ACTIVITY_ACTIVITY_ID_name[int32(activity)], "FILE_ACTIVITY_ACTIVITY_ID_")
+	fileActivity.TypeUid = systemenums.FILE_ACTIVITY_TYPE_UID(1001*100 + int32(activity))
+	fileActivity.Actor = &objects.Actor{Process: ocsfProcess(process, parent)}
+	fileActivity.File = &objects.File{
+		Name: name,
+		Path: file.Path,
+	}
+
+	return &eventprocessorocsftype.OCSFEvent{
+		OCSF_1_0_0: &eventprocessorocsftype.OCSF_1_0_0{FileActivity: fileActivity},
+	}
+}
+
+func networkActivity(process *tetragon.Process, parent *tetragon.Process, source Source, function string, network *eventtype.NetworkBehavior) *eventprocessorocsftype.OCSFEvent {
+	activity, ok := networkActivities[function]
+	if !ok {
+		activity = networkenums.NETWORK_ACTIVITY_ACTIVITY_ID_NETWORK_ACTIVITY_ACTIVITY_ID_OTHER
+	}
+
+	networkActivity := &eventprocessorocsftype.NetworkActivity{
+		Resources: ocsfResources(process, source),
+	}
+	networkActivity.Metadata = ocsfMetadata()
+	networkActivity.Time = ocsfTime(source.Time)
+	networkActivity.Device = ocsfDevice(source)
+	networkActivity.CategoryUid = networkenums.NETWORK_ACTIVITY_CATEGORY_UID_NETWORK_ACTIVITY_CATEGORY_UID_NETWORK_ACTIVITY
+	networkActivity.CategoryName = "Network Activity"
+	networkActivity.ClassUid = networkenums.NETWORK_ACTIVITY_CLASS_UID_NETWORK_ACTIVITY_CLASS_UID_NETWORK_ACTIVITY
+	networkActivity.ClassName = "Network Activity"
+	networkActivity.ActivityId = activity
+	networkActivity.ActivityName = activityName(networkenums.NETWORK_ACTIVITY_ACTIVITY_ID_name[int32(activity)], "NETWORK_ACTIVITY_ACTIVITY_ID_")
+	networkActivity.TypeUid = networkenums.NETWORK_ACTIVITY_TYPE_UID(4001*100 + int32(activity))
+	networkActivity.Actor = &objects.Actor{Process: ocsfProcess(process, parent)}
+	networkActivity.DstEndpoint = &objects.NetworkEndpoint{
+		Ip:   network.DestinationAddr,
+		Port: int32(network.DestinationPort),
+	}
+	networkActivity.ConnectionInfo = &objects.NetworkConnectionInfo{
+		ProtocolName: network.Protocol,
+	}
+
+	return &eventprocessorocsftype.OCSFEvent{
+		OCSF_1_0_0: &eventprocessorocsftype.OCSF_1_0_0{NetworkActivity: networkActivity},
+	}
+}
+
+// ocsfProcess converts a Tetragon process, the exec ids are used as OCSF process uids so the
+// profiler places converted events in the same lineage as the Tetragon ones.
+func ocsfProcess(process *tetragon.Process, parent *tetragon.Process) *objects.Process {
+	converted := &objects.Process{
+		Name:    process.Binary,
+		CmdLine: process.Arguments,
+		Pid:     int32(process.GetPid().GetValue()),
+		Uid:     process.ExecId,
+	}
+	if process.StartTime != nil {
+		converted.CreatedTime = process.StartTime.AsTime().UnixMilli()
+	}
+	if process.Pod != nil && process.Pod.Container != nil {
+		converted.Container = &objects.Container{
+			Name: process.Pod.Container.Name,
+			Uid:  process.Pod.Container.Id,
+			Image: &objects.Image{
+				Name: process.Pod.Container.GetImage().GetName(),
+				Uid:  process.Pod.Container.GetImage().GetId(),
+			},
+		}
+	}
+	if parent != nil {
+		converted.ParentProcess = ocsfProcess(parent, nil)
+	}
+
+	return converted
+}
+
+// ocsfResources returns the Kubernetes resources of the process, read back by OCSFEvent.getResource.
+func ocsfResources(process *tetragon.Process, source Source) []*eventprocessorocsftype.Resource {
+	var resources []*eventprocessorocsftype.Resource
+	if source.ClusterName != "" {
+		resources = append(resources, &eventprocessorocsftype.Resource{Type: "kubernetes.cluster", Name: source.ClusterName})
+	}
+	if process.Pod != nil {
+		resources = append(resources,
+			&eventprocessorocsftype.Resource{Type: "kubernetes.namespace", Name: process.Pod.Namespace},
+			&eventprocessorocsftype.Resource{Type: "kubernetes.pod", Name: process.Pod.Name},
+		)
+	}
+	return resources
+}
+
+func ocsfMetadata() *objects.Metadata {
+	return &objects.Metadata{
+		Version: eventprocessorocsftype.OCSFVersion,
+		Product: &objects.Product{
+			Name:       "Tetragon",
+			VendorName: "Cilium",
+		},
+	}
+}
+
+// ocsfTime returns the OCSF timestamp in milliseconds, zero when the time is unknown.
+func ocsfTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func ocsfDevice(source Source) *objects.Device {
+	if source.NodeName == "" {
+		return nil
+	}
+	return &objects.Device{Hostname: source.NodeName}
+}
+
+// activityName turns an OCSF enum name such as FILE_ACTIVITY_ACTIVITY_ID_SET_ATTRIBUTES into Set Attributes.
+func activityName(enumName string, prefix string) string {
+	words := strings.Split(strings.TrimPrefix(enumName, prefix), "_")
+	for i, word := range words {
+		if word != "" {
+			words[i] = word[:1] + strings.ToLower(word[1:])
+		}
+	}
+	return strings.Join(words, " ")
+}
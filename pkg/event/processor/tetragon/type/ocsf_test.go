@@ -0,0 +1,177 @@
+package eventprocessortetragontype
+
+import (
+	"encoding/json"
+	eventprocessorocsftype "runtime-behavior-profiler/pkg/event/processor/ocsf/type"
+	"testing"
+	"time"
+
+	"github.com/cilium/tetragon/api/v1/tetragon"
+	networkenums "github.com/valllabh/ocsf-schema-golang/ocsf/v1_0_0/events/network/enums"
+	systemenums "github.com/valllabh/ocsf-schema-golang/ocsf/v1_0_0/events/system/enums"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newTestPodProcess(binary string, execID string) *tetragon.Process {
+	return &tetragon.Process{
+		Binary:    binary,
+		Arguments: "https://example.com",
+		ExecId:    execID,
+		Pod: &tetragon.Pod{
+			Namespace: "default",
+			Name:      "nginx-554b9c67f9-c5cv4",
+			Container: &tetragon.Container{
+				Name:  "nginx",
+				Image: &tetragon.Image{Name: "docker.io/library/nginx:latest"},
+			},
+		},
+	}
+}
+
+func TestToOCSFProcessExec(t *testing.T) {
+	observed := time.Date(2024, 12, 2, 12, 0, 0, 0, time.UTC)
+	event := &ProcessExec{
+		ProcessExec: &tetragon.ProcessExec{
+			Process: newTestPodProcess("/usr/bin/curl", "curl"),
+			Parent:  newTestPodProcess("/bin/sh", "sh"),
+		},
+		Source: Source{NodeName: "worker-1", ClusterName: "prod", Time: observed},
+	}
+
+	ocsfEvent := ToOCSF(event)
+	if ocsfEvent == nil || ocsfEvent.GetType() != "PROCESS_EVENT" {
+		t.Fatalf("converted event = %+v; want a process activity", ocsfEvent)
+	}
+	activity := ocsfEvent.OCSF_1_0_0.ProcessActivity
+	if activity.ActivityId != systemenums.PROCESS_ACTIVITY_ACTIVITY_ID_PROCESS_ACTIVITY_ACTIVITY_ID_LAUNCH || activity.ActivityName != "Launch" {
+		t.Errorf("activity = %d %q; want Launch", activity.ActivityId, activity.ActivityName)
+	}
+	if activity.TypeUid != systemenums.PROCESS_ACTIVITY_TYPE_UID_PROCESS_ACTIVITY_TYPE_UID_PROCESS_ACTIVITY_LAUNCH {
+		t.Errorf("type uid = %d; want 100701", activity.TypeUid)
+	}
+	if activity.Time != observed.UnixMilli() || activity.Device.Hostname != "worker-1" {
+		t.Errorf("time = %d, device = %+v; want the response time and node", activity.Time, activity.Device)
+	}
+
+	// The converted event must be readable by the OCSF processor.
+	data, err := json.Marshal(ocsfEvent)
+	if err != nil {
+		t.Fatalf("failed to marshal event: %v", err)
+	}
+	var decoded eventprocessorocsftype.OCSFEvent
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal event: %v", err)
+	}
+
+	namespace, err := decoded.GetNamespace()
+	if err != nil || namespace.Name != "default" {
+		t.Errorf("namespace = %+v, %v; want default", namespace, err)
+	}
+	pod, err := decoded.GetPod()
+	if err != nil || pod.Name != "nginx-554b9c67f9-c5cv4" {
+		t.Errorf("pod = %+v, %v; want nginx-554b9c67f9-c5cv4", pod, err)
+	}
+	process, err := decoded.GetProcess()
+	if err != nil || process.Binary != "/usr/bin/curl" || process.ExecID != "curl" || process.ParentExecID != "sh" {
+		t.Errorf("process = %+v, %v; want curl started by sh", process, err)
+	}
+	source, err := decoded.GetSource()
+	if err != nil || source.NodeName != "worker-1" || source.ClusterName != "prod" {
+		t.Errorf("source = %+v, %v; want worker-1 in prod", source, err)
+	}
+}
+
+func TestToOCSFProcessExit(t *testing.T) {
+	exited := time.Date(2024, 12, 2, 12, 0, 5, 0, time.UTC)
+	event := &ProcessExit{
+		ProcessExit: &tetragon.ProcessExit{
+			Process: newTestPodProcess("/usr/bin/curl", "curl"),
+			Status:  7,
+			Time:    timestamppb.New(exited),
+		},
+	}
+
+	activity := ToOCSF(event).OCSF_1_0_0.ProcessActivity
+	if activity.ActivityId != systemenums.PROCESS_ACTIVITY_ACTIVITY_ID_PROCESS_ACTIVITY_ACTIVITY_ID_TERMINATE {
+		t.Errorf("activity = %d; want Terminate", activity.ActivityId)
+	}
+	if activity.ExitCode != 7 || activity.Time != exited.UnixMilli() {
+		t.Errorf("exit code = %d, time = %d; want 7 at the exit time", activity.ExitCode, activity.Time)
+	}
+}
+
+func TestToOCSFKernelEvents(t *testing.T) {
+	connect := &ProcessKprobe{
+		ProcessKprobe: &tetragon.ProcessKprobe{
+			Process:      newTestPodProcess("/usr/bin/curl", "curl"),
+			FunctionName: "tcp_connect",
+			Args: []*tetragon.KprobeArgument{{Arg: &tetragon.KprobeArgument_SockArg{SockArg: &tetragon.KprobeSock{
+				Family: "AF_INET", Protocol: "IPPROTO_TCP", Daddr: "10.0.0.1", Dport: 443,
+			}}}},
+		},
+	}
+	network := ToOCSF(connect)
+	if network == nil || network.GetType() != "NETWORK_EVENT" {
+		t.Fatalf("converted tcp_connect = %+v; want a network activity", network)
+	}
+	activity := network.OCSF_1_0_0.NetworkActivity
+	if activity.ActivityId != networkenums.NETWORK_ACTIVITY_ACTIVITY_ID_NETWORK_ACTIVITY_ACTIVITY_ID_OPEN {
+		t.Errorf("activity = %d; want Open", activity.ActivityId)
+	}
+	if activity.DstEndpoint.Ip != "10.0.0.1" || activity.DstEndpoint.Port != 443 {
+		t.Errorf("destination = %+v; want 10.0.0.1:443", activity.DstEndpoint)
+	}
+
+	open := &ProcessLsm{
+		ProcessLsm: &tetragon.ProcessLsm{
+			Process:      newTestPodProcess("/bin/cat", "cat"),
+			FunctionName: "file_open",
+			Args:         []*tetragon.KprobeArgument{{Arg: &tetragon.KprobeArgument_FileArg{FileArg: &tetragon.KprobeFile{Path: "/etc/shadow"}}}},
+		},
+	}
+	file := ToOCSF(open)
+	if file == nil || file.GetType() != "FILE_EVENT" {
+		t.Fatalf("converted file_open = %+v; want a file activity", file)
+	}
+	if file.OCSF_1_0_0.FileActivity.File.Path != "/etc/shadow" || file.OCSF_1_0_0.FileActivity.File.Name != "shadow" {
+		t.Errorf("file = %+v; want /etc/shadow", file.OCSF_1_0_0.FileActivity.File)
+	}
+	if file.OCSF_1_0_0.FileActivity.ActivityId != systemenums.FILE_ACTIVITY_ACTIVITY_ID_FILE_ACTIVITY_ACTIVITY_ID_OPEN {
+		t.Errorf("activity = %d; want Open", file.OCSF_1_0_0.FileActivity.ActivityId)
+	}
+
+	for _, tt := range []struct {
+		mask uint64
+		want systemenums.FILE_ACTIVITY_ACTIVITY_ID
+	}{
+		{0, systemenums.FILE_ACTIVITY_ACTIVITY_ID_FILE_ACTIVITY_ACTIVITY_ID_OTHER},
+		{mayRead, systemenums.FILE_ACTIVITY_ACTIVITY_ID_FILE_ACTIVITY_ACTIVITY_ID_READ},
+		{mayWrite, systemenums.FILE_ACTIVITY_ACTIVITY_ID_FILE_ACTIVITY_ACTIVITY_ID_UPDATE},
+	} {
+		// The file is writable whether or not it is written.
+		args := []*tetragon.KprobeArgument{{Arg: &tetragon.KprobeArgument_FileArg{FileArg: &tetragon.KprobeFile{Path: "/var/log/app.log", Permission: "-rw-r-----"}}}}
+		if tt.mask != 0 {
+			args = append(args, &tetragon.KprobeArgument{Arg: &tetragon.KprobeArgument_IntArg{IntArg: int32(tt.mask)}})
+		}
+		permission := ToOCSF(&ProcessKprobe{
+			ProcessKprobe: &tetragon.ProcessKprobe{
+				Process:      newTestPodProcess("/app/server", "server"),
+				FunctionName: "security_file_permission",
+				Args:         args,
+			},
+		})
+		if permission == nil || permission.OCSF_1_0_0.FileActivity.ActivityId != tt.want {
+			t.Errorf("converted security_file_permission with mask %d = %+v; want activity %s", tt.mask, permission, tt.want)
+		}
+	}
+
+	capable := &ProcessKprobe{
+		ProcessKprobe: &tetragon.ProcessKprobe{
+			Process:      newTestPodProcess("/bin/cat", "cat"),
+			FunctionName: "cap_capable",
+		},
+	}
+	if converted := ToOCSF(capable); converted != nil {
+		t.Errorf("converted cap_capable = %+v; want nil", converted)
+	}
+}
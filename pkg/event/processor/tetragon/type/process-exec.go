@@ -8,6 +8,10 @@ import (
 
 type ProcessExec struct {
 	*tetragon.ProcessExec
+	// Source is the metadata of the response the event was received in.
+	Source Source
+	// Host profiles the event under its node when the process does not run in a pod.
+	Host *Host
 }
 
 // IsHostEvent checks if the event is a host event.
@@ -17,25 +21,35 @@ func (e *ProcessExec) IsHostEvent() bool {
 
 // GetContainer implements eventtype.IEvent.
 func (e *ProcessExec) GetContainer() (*eventtype.Container, error) {
-	return GetContainer(e.Process)
+	return GetContainer(e.Process, e.Host)
 }
 
 // GetNamespace implements eventtype.IEvent.
 func (e *ProcessExec) GetNamespace() (*eventtype.Namespace, error) {
-	return GetNamespace(e.Process)
+	return GetNamespace(e.Process, e.Host)
 }
 
 // GetParentProcess implements eventtype.IEvent.
 func (e *ProcessExec) GetParentProcess() (*eventtype.Process, error) {
-	return GetProcess(e.Process)
+	return GetParentProcess(e.Process, e.Parent)
+}
+
+// GetAncestors implements eventtype.IAncestryEvent.
+func (e *ProcessExec) GetAncestors() ([]*eventtype.Process, error) {
+	return GetAncestors(e.Parent, e.Ancestors)
 }
 
 // GetPod implements eventtype.IEvent.
 func (e *ProcessExec) GetPod() (*eventtype.Pod, error) {
-	return GetPod(e.Process)
+	return GetPod(e.Process, e.Host)
 }
 
 // GetProcess implements eventtype.IEvent.
 func (e *ProcessExec) GetProcess() (*eventtype.Process, error) {
 	return GetProcess(e.Process)
 }
+
+// GetSource implements eventtype.IEvent.
+func (e *ProcessExec) GetSource() (*eventtype.EventSource, error) {
+	return GetSource(e.Source, "")
+}
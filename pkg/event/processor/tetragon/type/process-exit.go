@@ -8,6 +8,10 @@ import (
 
 type ProcessExit struct {
 	*tetragon.ProcessExit
+	// Source is the metadata of the response the event was received in.
+	Source Source
+	// Host profiles the event under its node when the process does not run in a pod.
+	Host *Host
 }
 
 // IsHostEvent checks if the event is a host event.
@@ -17,25 +21,47 @@ func (e *ProcessExit) IsHostEvent() bool {
 
 // GetContainer implements eventtype.IEvent.
 func (e *ProcessExit) GetContainer() (*eventtype.Container, error) {
-	return GetContainer(e.Process)
+	return GetContainer(e.Process, e.Host)
 }
 
 // GetNamespace implements eventtype.IEvent.
 func (e *ProcessExit) GetNamespace() (*eventtype.Namespace, error) {
-	return GetNamespace(e.Process)
+	return GetNamespace(e.Process, e.Host)
 }
 
 // GetParentProcess implements eventtype.IEvent.
 func (e *ProcessExit) GetParentProcess() (*eventtype.Process, error) {
-	return GetProcess(e.Process)
+	return GetParentProcess(e.Process, e.Parent)
+}
+
+// GetExit implements eventtype.IExitEvent.
+func (e *ProcessExit) GetExit() (*eventtype.ProcessExit, error) {
+	exit := &eventtype.ProcessExit{
+		ExecID: e.Process.ExecId,
+		Status: e.Status,
+		Signal: e.Signal,
+	}
+	if e.Process.StartTime != nil {
+		exit.StartTime = e.Process.StartTime.AsTime()
+	}
+	if e.Time != nil {
+		exit.ExitTime = e.Time.AsTime()
+	}
+
+	return exit, nil
 }
 
 // GetPod implements eventtype.IEvent.
 func (e *ProcessExit) GetPod() (*eventtype.Pod, error) {
-	return GetPod(e.Process)
+	return GetPod(e.Process, e.Host)
 }
 
 // GetProcess implements eventtype.IEvent.
 func (e *ProcessExit) GetProcess() (*eventtype.Process, error) {
 	return GetProcess(e.Process)
 }
+
+// GetSource implements eventtype.IEvent.
+func (e *ProcessExit) GetSource() (*eventtype.EventSource, error) {
+	return GetSource(e.Source, "")
+}
@@ -8,6 +8,10 @@ import (
 
 type ProcessKprobe struct {
 	*tetragon.ProcessKprobe
+	// Source is the metadata of the response the event was received in.
+	Source Source
+	// Host profiles the event under its node when the process does not run in a pod.
+	Host *Host
 }
 
 // IsHostEvent checks if the event is a host event.
@@ -17,25 +21,35 @@ func (e *ProcessKprobe) IsHostEvent() bool {
 
 // GetContainer implements eventtype.IEvent.
 func (e *ProcessKprobe) GetContainer() (*eventtype.Container, error) {
-	return GetContainer(e.Process)
+	return GetContainer(e.Process, e.Host)
+}
+
+// GetKernelBehavior implements eventtype.IKernelEvent.
+func (e *ProcessKprobe) GetKernelBehavior() (*eventtype.KernelBehavior, error) {
+	return DecodeKprobe(e.FunctionName, e.Args), nil
 }
 
 // GetNamespace implements eventtype.IEvent.
 func (e *ProcessKprobe) GetNamespace() (*eventtype.Namespace, error) {
-	return GetNamespace(e.Process)
+	return GetNamespace(e.Process, e.Host)
 }
 
 // GetParentProcess implements eventtype.IEvent.
 func (e *ProcessKprobe) GetParentProcess() (*eventtype.Process, error) {
-	return GetProcess(e.Process)
+	return GetParentProcess(e.Process, e.Parent)
 }
 
 // GetPod implements eventtype.IEvent.
 func (e *ProcessKprobe) GetPod() (*eventtype.Pod, error) {
-	return GetPod(e.Process)
+	return GetPod(e.Process, e.Host)
 }
 
 // GetProcess implements eventtype.IEvent.
 func (e *ProcessKprobe) GetProcess() (*eventtype.Process, error) {
 	return GetProcess(e.Process)
 }
+
+// GetSource implements eventtype.IEvent.
+func (e *ProcessKprobe) GetSource() (*eventtype.EventSource, error) {
+	return GetSource(e.Source, e.PolicyName)
+}
@@ -1,6 +1,7 @@
 package eventprocessortetragontype
 
 import (
+	"encoding/hex"
 	eventtype "runtime-behavior-profiler/pkg/event/type"
 
 	"github.com/cilium/tetragon/api/v1/tetragon"
@@ -8,6 +9,10 @@ import (
 
 type ProcessLoader struct {
 	*tetragon.ProcessLoader
+	// Source is the metadata of the response the event was received in.
+	Source Source
+	// Host profiles the event under its node when the process does not run in a pod.
+	Host *Host
 }
 
 // IsHostEvent checks if the event is a host event.
@@ -17,25 +22,38 @@ func (e *ProcessLoader) IsHostEvent() bool {
 
 // GetContainer implements eventtype.IEvent.
 func (e *ProcessLoader) GetContainer() (*eventtype.Container, error) {
-	return GetContainer(e.Process)
+	return GetContainer(e.Process, e.Host)
+}
+
+// GetLibrary implements eventtype.ILoaderEvent.
+func (e *ProcessLoader) GetLibrary() (*eventtype.Library, error) {
+	return &eventtype.Library{
+		Path:    e.Path,
+		BuildID: hex.EncodeToString(e.Buildid),
+	}, nil
 }
 
 // GetNamespace implements eventtype.IEvent.
 func (e *ProcessLoader) GetNamespace() (*eventtype.Namespace, error) {
-	return GetNamespace(e.Process)
+	return GetNamespace(e.Process, e.Host)
 }
 
 // GetParentProcess implements eventtype.IEvent.
 func (e *ProcessLoader) GetParentProcess() (*eventtype.Process, error) {
-	return GetProcess(e.Process)
+	return GetParentProcess(e.Process, nil)
 }
 
 // GetPod implements eventtype.IEvent.
 func (e *ProcessLoader) GetPod() (*eventtype.Pod, error) {
-	return GetPod(e.Process)
+	return GetPod(e.Process, e.Host)
 }
 
 // GetProcess implements eventtype.IEvent.
 func (e *ProcessLoader) GetProcess() (*eventtype.Process, error) {
 	return GetProcess(e.Process)
 }
+
+// GetSource implements eventtype.IEvent.
+func (e *ProcessLoader) GetSource() (*eventtype.EventSource, error) {
+	return GetSource(e.Source, "")
+}
@@ -8,6 +8,10 @@ import (
 
 type ProcessUprobe struct {
 	*tetragon.ProcessUprobe
+	// Source is the metadata of the response the event was received in.
+	Source Source
+	// Host profiles the event under its node when the process does not run in a pod.
+	Host *Host
 }
 
 // IsHostEvent checks if the event is a host event.
@@ -17,25 +21,38 @@ func (e *ProcessUprobe) IsHostEvent() bool {
 
 // GetContainer implements eventtype.IEvent.
 func (e *ProcessUprobe) GetContainer() (*eventtype.Container, error) {
-	return GetContainer(e.Process)
+	return GetContainer(e.Process, e.Host)
 }
 
 // GetNamespace implements eventtype.IEvent.
 func (e *ProcessUprobe) GetNamespace() (*eventtype.Namespace, error) {
-	return GetNamespace(e.Process)
+	return GetNamespace(e.Process, e.Host)
 }
 
 // GetParentProcess implements eventtype.IEvent.
 func (e *ProcessUprobe) GetParentProcess() (*eventtype.Process, error) {
-	return GetProcess(e.Process)
+	return GetParentProcess(e.Process, e.Parent)
+}
+
+// GetSymbol implements eventtype.IUprobeEvent.
+func (e *ProcessUprobe) GetSymbol() (*eventtype.Symbol, error) {
+	return &eventtype.Symbol{
+		Path:   e.Path,
+		Symbol: e.Symbol,
+	}, nil
 }
 
 // GetPod implements eventtype.IEvent.
 func (e *ProcessUprobe) GetPod() (*eventtype.Pod, error) {
-	return GetPod(e.Process)
+	return GetPod(e.Process, e.Host)
 }
 
 // GetProcess implements eventtype.IEvent.
 func (e *ProcessUprobe) GetProcess() (*eventtype.Process, error) {
 	return GetProcess(e.Process)
 }
+
+// GetSource implements eventtype.IEvent.
+func (e *ProcessUprobe) GetSource() (*eventtype.EventSource, error) {
+	return GetSource(e.Source, e.PolicyName)
+}
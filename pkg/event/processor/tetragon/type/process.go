@@ -2,6 +2,7 @@ package eventprocessortetragontype
 
 import (
 	eventtype "runtime-behavior-profiler/pkg/event/type"
+	"time"
 
 	"github.com/cilium/tetragon/api/v1/tetragon"
 )
@@ -12,7 +13,11 @@ func IsHostEvent(process *tetragon.Process) bool {
 }
 
 // GetContainer implements eventtype.IEvent.
-func GetContainer(process *tetragon.Process) (*eventtype.Container, error) {
+func GetContainer(process *tetragon.Process, host *Host) (*eventtype.Container, error) {
+	if IsHostEvent(process) {
+		return host.GetContainer(process)
+	}
+
 	return &eventtype.Container{
 		Name: process.Pod.Container.Name,
 		Image: &eventtype.Image{
@@ -23,7 +28,11 @@ func GetContainer(process *tetragon.Process) (*eventtype.Container, error) {
 }
 
 // GetNamespace implements eventtype.IEvent.
-func GetNamespace(process *tetragon.Process) (*eventtype.Namespace, error) {
+func GetNamespace(process *tetragon.Process, host *Host) (*eventtype.Namespace, error) {
+	if IsHostEvent(process) {
+		return host.GetNamespace()
+	}
+
 	return &eventtype.Namespace{
 		Name: process.Pod.Namespace,
 		Pods: map[string]*eventtype.Pod{},
@@ -31,7 +40,11 @@ func GetNamespace(process *tetragon.Process) (*eventtype.Namespace, error) {
 }
 
 // GetPod implements eventtype.IEvent.
-func GetPod(process *tetragon.Process) (*eventtype.Pod, error) {
+func GetPod(process *tetragon.Process, host *Host) (*eventtype.Pod, error) {
+	if IsHostEvent(process) {
+		return host.GetPod()
+	}
+
 	return &eventtype.Pod{
 		Name:       process.Pod.Name,
 		Containers: map[string]*eventtype.Container{},
@@ -44,5 +57,146 @@ func GetProcess(process *tetragon.Process) (*eventtype.Process, error) {
 		Binary:         process.Binary,
 		Arguments:      process.Arguments,
 		ChildProcesses: map[string]*eventtype.Process{},
+		ExecID:         process.ExecId,
+		ParentExecID:   process.ParentExecId,
+		Credentials:    GetCredentials(process),
+		Namespaces:     GetNamespaces(process),
+	}, nil
+}
+
+// Source is the metadata of the GetEventsResponse an event was received in, Time is
+// when the event was observed.
+type Source struct {
+	NodeName    string
+	ClusterName string
+	Time        time.Time
+}
+
+// NewSource returns the metadata of the response.
+func NewSource(response *tetragon.GetEventsResponse) Source {
+	source := Source{
+		NodeName:    response.GetNodeName(),
+		ClusterName: response.GetClusterName(),
+	}
+	if response.GetTime() != nil {
+		source.Time = response.GetTime().AsTime()
+	}
+	return source
+}
+
+// GetSource implements eventtype.IEvent, the policy name is empty for events that are
+// not generated by a tracing policy.
+func GetSource(source Source, policyName string) (*eventtype.EventSource, error) {
+	return &eventtype.EventSource{
+		NodeName:    source.NodeName,
+		ClusterName: source.ClusterName,
+		PolicyName:  policyName,
+		Time:        source.Time,
 	}, nil
 }
+
+// GetNamespaces returns the Linux namespaces of the process, or nil when Tetragon
+// did not report them.
+func GetNamespaces(process *tetragon.Process) eventtype.LinuxNamespaces {
+	ns := process.GetNs()
+	if ns == nil {
+		return nil
+	}
+
+	namespaces := eventtype.LinuxNamespaces{}
+	for nsType, namespace := range map[string]*tetragon.Namespace{
+		"uts":    ns.Uts,
+		"ipc":    ns.Ipc,
+		"mnt":    ns.Mnt,
+		"pid":    ns.Pid,
+		"net":    ns.Net,
+		"time":   ns.Time,
+		"cgroup": ns.Cgroup,
+		"user":   ns.User,
+	} {
+		if namespace != nil {
+			namespaces[nsType] = &eventtype.LinuxNamespace{
+				Inum:   namespace.Inum,
+				IsHost: namespace.IsHost,
+			}
+		}
+	}
+
+	return namespaces
+}
+
+// GetCredentials returns the privileges of the process, or nil when Tetragon
+// reported neither credentials nor capabilities for it.
+func GetCredentials(process *tetragon.Process) *eventtype.Credentials {
+	processCredentials := process.GetProcessCredentials()
+	if processCredentials == nil && process.GetCap() == nil {
+		return nil
+	}
+
+	credentials := &eventtype.Credentials{
+		UID:  process.GetUid().GetValue(),
+		EUID: process.GetUid().GetValue(),
+	}
+	if processCredentials != nil {
+		credentials.UID = processCredentials.GetUid().GetValue()
+		credentials.GID = processCredentials.GetGid().GetValue()
+		credentials.EUID = processCredentials.GetEuid().GetValue()
+		credentials.EGID = processCredentials.GetEgid().GetValue()
+	}
+
+	effective := process.GetCap().GetEffective()
+	if effective == nil {
+		effective = processCredentials.GetCaps().GetEffective()
+	}
+	credentials.Capabilities = capabilityNames(effective)
+
+	if setuid := process.GetBinaryProperties().GetSetuid(); setuid != nil {
+		value := setuid.GetValue()
+		credentials.Setuid = &value
+	}
+	if setgid := process.GetBinaryProperties().GetSetgid(); setgid != nil {
+		value := setgid.GetValue()
+		credentials.Setgid = &value
+	}
+
+	return credentials
+}
+
+// GetParentProcess implements eventtype.IEvent.
+// Events that do not carry the parent yield a root placeholder, the profile
+// still attaches them below the parent when its exec id is already known.
+func GetParentProcess(process *tetragon.Process, parent *tetragon.Process) (*eventtype.Process, error) {
+	if parent == nil {
+		return &eventtype.Process{
+			Binary:         "root",
+			ChildProcesses: map[string]*eventtype.Process{},
+		}, nil
+	}
+
+	return GetProcess(parent)
+}
+
+// GetAncestors implements eventtype.IAncestryEvent.
+// Tetragon reports ancestors beyond the immediate parent closest first, they are
+// reversed and followed by the parent to obtain the lineage oldest first.
+func GetAncestors(parent *tetragon.Process, ancestors []*tetragon.Process) ([]*eventtype.Process, error) {
+	if parent == nil || len(ancestors) == 0 {
+		return nil, nil
+	}
+
+	lineage := make([]*eventtype.Process, 0, len(ancestors)+1)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		ancestor, err := GetProcess(ancestors[i])
+		if err != nil {
+			return nil, err
+		}
+		lineage = append(lineage, ancestor)
+	}
+
+	parentProcess, err := GetProcess(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(lineage, parentProcess), nil
+}
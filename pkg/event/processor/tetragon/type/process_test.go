@@ -0,0 +1,148 @@
+package eventprocessortetragontype
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cilium/tetragon/api/v1/tetragon"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestProcessExecGetAncestors(t *testing.T) {
+	event := &ProcessExec{
+		ProcessExec: &tetragon.ProcessExec{
+			Process: &tetragon.Process{Binary: "/usr/bin/curl", ExecId: "curl", ParentExecId: "bash"},
+			Parent:  &tetragon.Process{Binary: "/bin/bash", ExecId: "bash", ParentExecId: "sh"},
+			Ancestors: []*tetragon.Process{
+				{Binary: "/bin/sh", ExecId: "sh", ParentExecId: "shim"},
+				{Binary: "/usr/bin/containerd-shim", ExecId: "shim"},
+			},
+		},
+	}
+
+	ancestors, err := event.GetAncestors()
+	if err != nil {
+		t.Fatalf("failed to get ancestors: %v", err)
+	}
+
+	expected := []string{"shim", "sh", "bash"}
+	if len(ancestors) != len(expected) {
+		t.Fatalf("got %d ancestors; want %d", len(ancestors), len(expected))
+	}
+	for i, execID := range expected {
+		if ancestors[i].ExecID != execID {
+			t.Errorf("ancestor %d = %q; want %q", i, ancestors[i].ExecID, execID)
+		}
+	}
+}
+
+func TestGetParentProcessWithoutParent(t *testing.T) {
+	event := &ProcessLoader{
+		ProcessLoader: &tetragon.ProcessLoader{
+			Process: &tetragon.Process{Binary: "/usr/bin/curl", ExecId: "curl", ParentExecId: "bash"},
+		},
+	}
+
+	parent, err := event.GetParentProcess()
+	if err != nil {
+		t.Fatalf("failed to get parent: %v", err)
+	}
+	if parent.Binary != "root" {
+		t.Errorf("parent binary = %q; want root placeholder", parent.Binary)
+	}
+}
+
+func TestProcessExitGetExit(t *testing.T) {
+	start := time.Date(2024, 12, 2, 12, 0, 0, 0, time.UTC)
+	event := &ProcessExit{
+		ProcessExit: &tetragon.ProcessExit{
+			Process: &tetragon.Process{Binary: "/usr/bin/curl", ExecId: "curl", StartTime: timestamppb.New(start)},
+			Signal:  "SIGKILL",
+			Status:  9,
+			Time:    timestamppb.New(start.Add(3 * time.Second)),
+		},
+	}
+
+	exit, err := event.GetExit()
+	if err != nil {
+		t.Fatalf("failed to get exit: %v", err)
+	}
+	if exit.ExecID != "curl" || exit.Signal != "SIGKILL" || exit.Status != 9 {
+		t.Errorf("exit = %+v; want curl killed by SIGKILL", exit)
+	}
+	if exit.Duration() != 3*time.Second {
+		t.Errorf("exit duration = %s; want 3s", exit.Duration())
+	}
+}
+
+func TestProcessLoaderGetLibrary(t *testing.T) {
+	event := &ProcessLoader{
+		ProcessLoader: &tetragon.ProcessLoader{
+			Process: &tetragon.Process{Binary: "/usr/sbin/nginx"},
+			Path:    "/usr/lib/libssl.so.3",
+			Buildid: []byte{0xde, 0xad, 0xbe, 0xef},
+		},
+	}
+
+	library, err := event.GetLibrary()
+	if err != nil {
+		t.Fatalf("failed to get library: %v", err)
+	}
+	if library.Path != "/usr/lib/libssl.so.3" || library.BuildID != "deadbeef" {
+		t.Errorf("library = %+v; want libssl with build id deadbeef", library)
+	}
+}
+
+func TestGetCredentials(t *testing.T) {
+	process := &tetragon.Process{
+		Uid: wrapperspb.UInt32(1000),
+		ProcessCredentials: &tetragon.ProcessCredentials{
+			Uid:  wrapperspb.UInt32(1000),
+			Gid:  wrapperspb.UInt32(1000),
+			Euid: wrapperspb.UInt32(0),
+			Egid: wrapperspb.UInt32(1000),
+		},
+		Cap: &tetragon.Capabilities{
+			Effective: []tetragon.CapabilitiesType{tetragon.CapabilitiesType_CAP_SETUID},
+		},
+		BinaryProperties: &tetragon.BinaryProperties{Setuid: wrapperspb.UInt32(0)},
+	}
+
+	credentials := GetCredentials(process)
+	if credentials == nil {
+		t.Fatalf("no credentials returned")
+	}
+	if credentials.UID != 1000 || credentials.EUID != 0 || credentials.Setuid == nil || *credentials.Setuid != 0 {
+		t.Errorf("credentials = %+v; want a setuid root execution by uid 1000", credentials)
+	}
+	if len(credentials.Capabilities) != 1 || credentials.Capabilities[0] != "CAP_SETUID" {
+		t.Errorf("capabilities = %v; want [CAP_SETUID]", credentials.Capabilities)
+	}
+
+	if GetCredentials(&tetragon.Process{}) != nil {
+		t.Errorf("credentials returned for a process without credentials")
+	}
+}
+
+func TestProcessKprobeGetSource(t *testing.T) {
+	observed := time.Date(2024, 12, 2, 12, 0, 0, 0, time.UTC)
+	event := &ProcessKprobe{
+		ProcessKprobe: &tetragon.ProcessKprobe{
+			Process:    &tetragon.Process{Binary: "/usr/bin/curl", ExecId: "curl"},
+			PolicyName: "network-monitoring",
+		},
+		Source: NewSource(&tetragon.GetEventsResponse{NodeName: "worker-1", ClusterName: "prod", Time: timestamppb.New(observed)}),
+	}
+
+	source, err := event.GetSource()
+	if err != nil {
+		t.Fatalf("failed to get source: %v", err)
+	}
+	if source.NodeName != "worker-1" || source.ClusterName != "prod" || source.PolicyName != "network-monitoring" {
+		t.Errorf("source = %+v; want worker-1 in prod from network-monitoring", source)
+	}
+	if !source.Time.Equal(observed) {
+		t.Errorf("source time = %s; want %s", source.Time, observed)
+	}
+}
@@ -7,6 +7,13 @@ import (
 	"google.golang.org/grpc"
 )
 
+const (
+	// OutputOCSF prints deviations as OCSF Security Finding events instead of profiler deviations.
+	OutputOCSF = "ocsf"
+	// OutputOCSFEvents streams every event as an OCSF activity in NDJSON without profiling it.
+	OutputOCSFEvents = "ocsf-events"
+)
+
 type TetragonEvent struct {
 }
 
@@ -18,12 +25,18 @@ type TetragonEventListerOptions struct {
 	Namespaces    []string
 	Processes     []string
 	Pods          []string
-	Host          bool
-	Timestamps    bool
-	TTYEncode     string
-	StackTraces   bool
-	ImaHash       bool
-	PolicyNames   []string
+	// Host listens to events of processes outside of pods and profiles them per node.
+	Host bool
+	// HostNodeName is the node the profiler runs on, the only node whose host processes have
+	// their systemd unit resolved from HostProcRoot.
+	HostNodeName string
+	// HostProcRoot is the proc filesystem of HostNodeName, used to resolve the systemd unit of host processes.
+	HostProcRoot string
+	Timestamps   bool
+	TTYEncode    string
+	StackTraces  bool
+	ImaHash      bool
+	PolicyNames  []string
 
 	Debug         bool
 	ServerAddress string
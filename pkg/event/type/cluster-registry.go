@@ -0,0 +1,253 @@
+package eventtype
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ClusterRegistry holds the profiles of several clusters keyed by cluster name, so one
+// profiler can ingest events from many clusters.
+type ClusterRegistry struct {
+	Clusters map[string]*Cluster `json:"clusters"`
+
+	// DefaultCluster receives the events whose source does not name a cluster.
+	DefaultCluster string `json:"-"`
+	// NewCluster creates the profile of a cluster seen for the first time, defaults to NewCluster.
+	NewCluster func(name string) *Cluster `json:"-"`
+
+	mu sync.RWMutex
+}
+
+// ImageUsage is a container running an image in one of the clusters of the registry.
+type ImageUsage struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Image     *Image `json:"image"`
+}
+
+// ImageComparison compares the behaviour of an image across the clusters running it.
+// Behaviours are keyed by binary and arguments, optionally followed by the key of a
+// library, symbol or kernel behaviour of the process.
+type ImageComparison struct {
+	Repo     string   `json:"repo"`
+	Clusters []string `json:"clusters"`
+	// Common are the behaviours seen in every cluster running the image.
+	Common []string `json:"common"`
+	// Unique are the behaviours of each cluster that no other cluster running the image shows.
+	Unique map[string][]string `json:"unique"`
+}
+
+// NewClusterRegistry returns an empty registry routing events without a cluster name to defaultCluster.
+func NewClusterRegistry(defaultCluster string) *ClusterRegistry {
+	return &ClusterRegistry{
+		Clusters:       map[string]*Cluster{},
+		DefaultCluster: defaultCluster,
+		NewCluster:     NewCluster,
+	}
+}
+
+// GetCluster returns the profile of the named cluster, creating it on first use.
+func (registry *ClusterRegistry) GetCluster(name string) *Cluster {
+	if name == "" {
+		name = registry.DefaultCluster
+	}
+
+	registry.mu.RLock()
+	cluster, ok := registry.Clusters[name]
+	registry.mu.RUnlock()
+	if ok {
+		return cluster
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if cluster, ok := registry.Clusters[name]; ok {
+		return cluster
+	}
+	newCluster := registry.NewCluster
+	if newCluster == nil {
+		newCluster = NewCluster
+	}
+	cluster = newCluster(name)
+	registry.Clusters[name] = cluster
+
+	return cluster
+}
+
+// SinkEvent sinks the event into the cluster named by its source.
+func (registry *ClusterRegistry) SinkEvent(rawEvent IEvent) (*SinkResult, error) {
+	if rawEvent == nil {
+		return registry.GetCluster("").SinkEvent(nil)
+	}
+
+	source, err := rawEvent.GetSource()
+	if err != nil {
+		return nil, err
+	}
+
+	var clusterName string
+	if source != nil {
+		clusterName = source.ClusterName
+	}
+
+	return registry.GetCluster(clusterName).SinkEvent(rawEvent)
+}
+
+// ClusterNames returns the names of the registered clusters in sorted order.
+func (registry *ClusterRegistry) ClusterNames() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	names := make([]string, 0, len(registry.Clusters))
+	for name := range registry.Clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// FindImage returns every container running the image repository, in any cluster.
+func (registry *ClusterRegistry) FindImage(repo string) []*ImageUsage {
+	var usages []*ImageUsage
+
+	for _, name := range registry.ClusterNames() {
+		cluster := registry.GetCluster(name)
+		cluster.forEachImageContainer(repo, func(namespace *Namespace, pod *Pod, container *Container) {
+			usages = append(usages, &ImageUsage{
+				Cluster:   name,
+				Namespace: namespace.Name,
+				Pod:       pod.Name,
+				Container: container.Name,
+				Image:     container.Image,
+			})
+		})
+	}
+
+	return usages
+}
+
+// CompareImage compares the behaviour of the containers running the image repository
+// across clusters. It returns nil when no cluster runs the image.
+func (registry *ClusterRegistry) CompareImage(repo string) *ImageComparison {
+	behaviors := map[string]map[string]bool{}
+
+	for _, name := range registry.ClusterNames() {
+		cluster := registry.GetCluster(name)
+		cluster.forEachImageContainer(repo, func(namespace *Namespace, pod *Pod, container *Container) {
+			if behaviors[name] == nil {
+				behaviors[name] = map[string]bool{}
+			}
+			collectBehaviors(container.Processes, behaviors[name])
+		})
+	}
+
+	if len(behaviors) == 0 {
+		return nil
+	}
+
+	comparison := &ImageComparison{
+		Repo:   repo,
+		Common: []string{},
+		Unique: map[string][]string{},
+	}
+
+	seenIn := map[string]int{}
+	for name, set := range behaviors {
+		comparison.Clusters = append(comparison.Clusters, name)
+		for behavior := range set {
+			seenIn[behavior]++
+		}
+	}
+	sort.Strings(comparison.Clusters)
+
+	for behavior, count := range seenIn {
+		if count == len(behaviors) {
+			comparison.Common = append(comparison.Common, behavior)
+		}
+	}
+	sort.Strings(comparison.Common)
+
+	for name, set := range behaviors {
+		unique := []string{}
+		for behavior := range set {
+			if seenIn[behavior] == 1 && len(behaviors) > 1 {
+				unique = append(unique, behavior)
+			}
+		}
+		sort.Strings(unique)
+		comparison.Unique[name] = unique
+	}
+
+	return comparison
+}
+
+// StartCompaction runs Compact on every registered cluster every interval in the background
+// until ctx is done, including clusters registered after it started.
+func (registry *ClusterRegistry) StartCompaction(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, name := range registry.ClusterNames() {
+					result := registry.GetCluster(name).Compact()
+					if len(result.Removed) > 0 {
+						log.Printf("Compaction removed %d profile nodes from cluster %s", len(result.Removed), name)
+					}
+				}
+			}
+		}
+	}()
+}
+
+func (registry *ClusterRegistry) MarshalJSON() ([]byte, error) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	type clusterRegistryJSON ClusterRegistry
+	return json.Marshal((*clusterRegistryJSON)(registry))
+}
+
+// forEachImageContainer calls fn for every container running the image repository.
+func (cluster *Cluster) forEachImageContainer(repo string, fn func(namespace *Namespace, pod *Pod, container *Container)) {
+	cluster.ForEachContainer(func(namespace *Namespace, pod *Pod, container *Container) {
+		if container.Image != nil && container.Image.Repo == repo {
+			fn(namespace, pod, container)
+		}
+	})
+}
+
+// collectBehaviors adds the behaviours of the processes and their descendants to set.
+func collectBehaviors(processes map[string]*Process, set map[string]bool) {
+	for _, process := range processes {
+		name := process.Binary
+		if process.Arguments != "" {
+			name += " " + process.Arguments
+		}
+		set[name] = true
+
+		for key := range process.Libraries {
+			set[name+" "+key] = true
+		}
+		for key := range process.Symbols {
+			set[name+" "+key] = true
+		}
+		for key := range process.KernelBehaviors {
+			set[name+" "+key] = true
+		}
+
+		collectBehaviors(process.ChildProcesses, set)
+	}
+}
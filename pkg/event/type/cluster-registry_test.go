@@ -0,0 +1,92 @@
+package eventtype
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestClusterRegistrySinkEvent(t *testing.T) {
+	registry := NewClusterRegistry("default-cluster")
+	registry.NewCluster = func(name string) *Cluster {
+		cluster := NewCluster(name)
+		cluster.LearningPeriod = time.Hour
+		return cluster
+	}
+
+	mustSink(t, registry.GetCluster("prod"), newTestEvent("default", "/bin/sh", "/usr/bin/curl", "", withImage("nginx"), withSource(&EventSource{ClusterName: "prod"})))
+	if _, err := registry.SinkEvent(newTestEvent("default", "/bin/sh", "/usr/bin/curl", "", withImage("nginx"), withSource(&EventSource{ClusterName: "staging"}))); err != nil {
+		t.Fatalf("failed to sink event: %v", err)
+	}
+	if _, err := registry.SinkEvent(newTestEvent("default", "/bin/sh", "/usr/bin/curl", "")); err != nil {
+		t.Fatalf("failed to sink event: %v", err)
+	}
+
+	names := registry.ClusterNames()
+	expected := []string{"default-cluster", "prod", "staging"}
+	if len(names) != len(expected) {
+		t.Fatalf("clusters = %v; want %v", names, expected)
+	}
+	for i, name := range expected {
+		if names[i] != name {
+			t.Errorf("cluster %d = %q; want %q", i, names[i], name)
+		}
+	}
+
+	staging := registry.GetCluster("staging")
+	if staging.LearningPeriod != time.Hour {
+		t.Errorf("staging learning period = %s; want the registry cluster factory to be used", staging.LearningPeriod)
+	}
+	if len(staging.Namespaces) != 1 {
+		t.Errorf("staging has %d namespaces; want 1", len(staging.Namespaces))
+	}
+
+	if _, err := json.Marshal(registry); err != nil {
+		t.Errorf("failed to marshal registry: %v", err)
+	}
+}
+
+func TestClusterRegistryFindImage(t *testing.T) {
+	registry := NewClusterRegistry("default-cluster")
+	mustSink(t, registry, newTestEvent("default", "/bin/sh", "/usr/bin/curl", "", withImage("docker.io/library/nginx:1.25"), withSource(&EventSource{ClusterName: "prod"})))
+	mustSink(t, registry, newTestEvent("default", "/bin/sh", "/usr/bin/curl", "", withImage("nginx:1.26"), withSource(&EventSource{ClusterName: "staging"})))
+	mustSink(t, registry, newTestEvent("default", "/bin/sh", "/usr/bin/redis-server", "", withImage("redis"), withSource(&EventSource{ClusterName: "staging"})))
+
+	usages := registry.FindImage("library/nginx")
+	if len(usages) != 1 || usages[0].Cluster != "prod" {
+		t.Errorf("library/nginx usages = %+v; want prod only", usages)
+	}
+
+	usages = registry.FindImage("nginx")
+	if len(usages) != 1 || usages[0].Cluster != "staging" || usages[0].Image.Tag != "1.26" {
+		t.Errorf("nginx usages = %+v; want staging with tag 1.26", usages)
+	}
+}
+
+func TestClusterRegistryCompareImage(t *testing.T) {
+	registry := NewClusterRegistry("default-cluster")
+	mustSink(t, registry, newTestEvent("default", "/bin/sh", "/usr/bin/curl", "", withImage("nginx"), withSource(&EventSource{ClusterName: "prod"})))
+	mustSink(t, registry, newTestEvent("default", "/bin/sh", "/usr/bin/curl", "", withImage("nginx"), withSource(&EventSource{ClusterName: "staging"})))
+	mustSink(t, registry, newTestEvent("default", "/bin/sh", "/usr/bin/wget", "", withImage("nginx"), withSource(&EventSource{ClusterName: "staging"})))
+
+	comparison := registry.CompareImage("nginx")
+	if comparison == nil {
+		t.Fatalf("no comparison for a running image")
+	}
+	if len(comparison.Clusters) != 2 {
+		t.Errorf("clusters = %v; want prod and staging", comparison.Clusters)
+	}
+	if len(comparison.Common) != 2 || comparison.Common[0] != "/bin/sh" || comparison.Common[1] != "/usr/bin/curl" {
+		t.Errorf("common = %v; want /bin/sh and /usr/bin/curl", comparison.Common)
+	}
+	if len(comparison.Unique["prod"]) != 0 {
+		t.Errorf("prod unique = %v; want none", comparison.Unique["prod"])
+	}
+	if len(comparison.Unique["staging"]) != 1 || comparison.Unique["staging"][0] != "/usr/bin/wget" {
+		t.Errorf("staging unique = %v; want /usr/bin/wget", comparison.Unique["staging"])
+	}
+
+	if registry.CompareImage("redis") != nil {
+		t.Errorf("expected no comparison for an image that does not run")
+	}
+}
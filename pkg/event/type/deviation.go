@@ -0,0 +1,97 @@
+package eventtype
+
+import (
+	"time"
+)
+
+// DefaultLearningPeriod is the learning period of clusters created with NewCluster.
+const DefaultLearningPeriod = 24 * time.Hour
+
+type DeviationType string
+
+const (
+	DeviationAbnormalTermination    DeviationType = "ABNORMAL_TERMINATION"
+	DeviationNewLibrary             DeviationType = "NEW_LIBRARY"
+	DeviationNewCapability          DeviationType = "NEW_CAPABILITY"
+	DeviationNewPrivilegeTransition DeviationType = "NEW_PRIVILEGE_TRANSITION"
+	DeviationHostNamespace          DeviationType = "HOST_NAMESPACE_ENTERED"
+	DeviationUserNamespaceCreated   DeviationType = "USER_NAMESPACE_CREATED"
+	// DeviationPolicyViolation is reported by policy rules whatever the learning state,
+	// its value is the name of the violated rule.
+	DeviationPolicyViolation DeviationType = "POLICY_VIOLATION"
+	// DeviationSuspiciousBehavior is reported by the built-in detection rules whatever the
+	// learning state, its value is the name of the rule.
+	DeviationSuspiciousBehavior DeviationType = "SUSPICIOUS_BEHAVIOR"
+	// DeviationFrequencyAnomaly is a process or kernel behaviour seen far more often than
+	// usual, its value is executions, connections or the key of the kernel behaviour.
+	DeviationFrequencyAnomaly DeviationType = "FREQUENCY_ANOMALY"
+)
+
+// Deviation describes behaviour observed after the learning period of a container
+// that is not part of its learned profile.
+type Deviation struct {
+	Type      DeviationType `json:"type"`
+	Cluster   string        `json:"cluster"`
+	Namespace string        `json:"namespace"`
+	Pod       string        `json:"pod"`
+	Container string        `json:"container"`
+	Node      string        `json:"node,omitempty"`
+	Binary    string        `json:"binary"`
+	Arguments string        `json:"arguments"`
+	Value     string        `json:"value"`
+	Message   string        `json:"message"`
+	// Techniques are the MITRE ATT&CK technique IDs of the behaviour, e.g. T1059.004.
+	Techniques []string `json:"techniques,omitempty"`
+	// Tactics are the MITRE ATT&CK tactics of the techniques, e.g. Execution.
+	Tactics []string `json:"tactics,omitempty"`
+	// Connection is the destination of the network behaviour of the deviation, see
+	// KernelBehavior.Connection, empty when it is not about a connection.
+	Connection string `json:"connection,omitempty"`
+	// Rarity of the deviating process across the cluster, or of its connection when rarer,
+	// from 0 for a behaviour of every workload to 1, used to rank deviations.
+	Rarity float64   `json:"rarity"`
+	Time   time.Time `json:"time"`
+}
+
+// learning reports whether the container of the event is still in its learning period.
+func (ctx *sinkContext) learning() bool {
+	if ctx.cluster.LearningPeriod <= 0 {
+		return true
+	}
+	return ctx.now.Before(ctx.container.FirstSeen.Add(ctx.cluster.LearningPeriod))
+}
+
+// deviate records a deviation of the given process unless the container is still learning.
+func (ctx *sinkContext) deviate(process *Process, deviationType DeviationType, value string, message string) {
+	if ctx.learning() {
+		return
+	}
+	ctx.report(process, deviationType, value, message)
+}
+
+// report records a deviation of the given process whatever the learning state, it is used
+// by built-in detectors for behaviour that is suspicious on first sight.
+func (ctx *sinkContext) report(process *Process, deviationType DeviationType, value string, message string) {
+	techniques, tactics := ctx.cluster.attackTags(process, deviationType)
+	connection := ""
+	if ctx.behavior != nil {
+		connection = ctx.behavior.Connection()
+	}
+	ctx.result.Deviations = append(ctx.result.Deviations, &Deviation{
+		Type:       deviationType,
+		Cluster:    ctx.cluster.Name,
+		Namespace:  ctx.namespace.Name,
+		Pod:        ctx.pod.Name,
+		Container:  ctx.container.Name,
+		Node:       ctx.source.nodeName(),
+		Binary:     process.Binary,
+		Arguments:  process.Arguments,
+		Value:      value,
+		Message:    message,
+		Techniques: techniques,
+		Tactics:    tactics,
+		Connection: connection,
+		Rarity:     ctx.cluster.prevalenceIndex().deviationRarity(process.Binary, process.Arguments, connection),
+		Time:       ctx.now,
+	})
+}
@@ -0,0 +1,229 @@
+package eventtype
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type KernelBehaviorKind string
+
+const (
+	KernelBehaviorCredentials KernelBehaviorKind = "CREDENTIALS"
+	KernelBehaviorCapability  KernelBehaviorKind = "CAPABILITY"
+	KernelBehaviorExec        KernelBehaviorKind = "EXEC"
+	KernelBehaviorMount       KernelBehaviorKind = "MOUNT"
+	KernelBehaviorBpf         KernelBehaviorKind = "BPF"
+	KernelBehaviorPtrace      KernelBehaviorKind = "PTRACE"
+	KernelBehaviorFile        KernelBehaviorKind = "FILE"
+	KernelBehaviorNetwork     KernelBehaviorKind = "NETWORK"
+	KernelBehaviorModule      KernelBehaviorKind = "MODULE"
+	KernelBehaviorNamespace   KernelBehaviorKind = "NAMESPACE"
+)
+
+// KernelBehavior is a security relevant kernel function call made by a process.
+// Exactly one of the typed records matching Kind is set.
+type KernelBehavior struct {
+	Kind     KernelBehaviorKind `json:"kind"`
+	Function string             `json:"function"`
+
+	Credentials *CredentialsBehavior `json:"credentials,omitempty"`
+	Capability  *CapabilityBehavior  `json:"capability,omitempty"`
+	Exec        *ExecBehavior        `json:"exec,omitempty"`
+	Mount       *MountBehavior       `json:"mount,omitempty"`
+	Bpf         *BpfBehavior         `json:"bpf,omitempty"`
+	Ptrace      *PtraceBehavior      `json:"ptrace,omitempty"`
+	File        *FileBehavior        `json:"file,omitempty"`
+	Network     *NetworkBehavior     `json:"network,omitempty"`
+	Module      *ModuleBehavior      `json:"module,omitempty"`
+	Namespace   *NamespaceBehavior   `json:"namespace,omitempty"`
+
+	Count      int64        `json:"count"`
+	Provenance *Provenance  `json:"provenance,omitempty"`
+	Attack     []*AttackTag `json:"attack,omitempty"`
+	Rate       *Rate        `json:"rate,omitempty"`
+	FirstSeen  time.Time    `json:"first_seen"`
+	LastSeen   time.Time    `json:"last_seen"`
+}
+
+// CredentialsBehavior is a change of the credentials of a process, e.g. commit_creds.
+type CredentialsBehavior struct {
+	UID          uint32   `json:"uid"`
+	GID          uint32   `json:"gid"`
+	EUID         uint32   `json:"euid"`
+	EGID         uint32   `json:"egid"`
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// CapabilityBehavior is a capability check, e.g. cap_capable.
+type CapabilityBehavior struct {
+	Name string `json:"name"`
+}
+
+// ExecBehavior is a binary execution check, e.g. security_bprm_check.
+type ExecBehavior struct {
+	Path string `json:"path"`
+}
+
+// MountBehavior is a filesystem mount, e.g. sys_mount.
+type MountBehavior struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	FSType string `json:"fs_type"`
+}
+
+// BpfBehavior is the load of a BPF program or map, e.g. bpf_check.
+type BpfBehavior struct {
+	ProgType string `json:"prog_type,omitempty"`
+	ProgName string `json:"prog_name,omitempty"`
+	MapType  string `json:"map_type,omitempty"`
+	MapName  string `json:"map_name,omitempty"`
+}
+
+// PtraceBehavior is a ptrace request, e.g. sys_ptrace.
+type PtraceBehavior struct {
+	Request int64 `json:"request"`
+}
+
+// FileAccessRead and FileAccessWrite are the accesses of FileBehavior.Access.
+const (
+	FileAccessRead  = "read"
+	FileAccessWrite = "write"
+)
+
+// FileBehavior is an access to a file, e.g. security_file_permission.
+type FileBehavior struct {
+	Path string `json:"path"`
+	// Permission is the mode of the file, e.g. -rw-r-----.
+	Permission string `json:"permission,omitempty"`
+	Flags      string `json:"flags,omitempty"`
+	// Access is a comma separated list of FileAccessRead and FileAccessWrite, only known for
+	// the functions reporting it such as security_file_permission.
+	Access string `json:"access,omitempty"`
+}
+
+// IsWrite reports whether the behaviour modifies the file. Without the access reported by
+// the function it is not a write, the mode of the file only tells what it allows.
+func (behavior *KernelBehavior) IsWrite() bool {
+	if behavior.File == nil {
+		return false
+	}
+	if behavior.Function == "security_path_truncate" {
+		return true
+	}
+	return strings.Contains(behavior.File.Access, FileAccessWrite)
+}
+
+// NetworkBehavior is a socket operation, e.g. tcp_connect. The source port is not
+// part of the identity of the behaviour as it is usually ephemeral.
+type NetworkBehavior struct {
+	Family          string `json:"family"`
+	Protocol        string `json:"protocol"`
+	DestinationAddr string `json:"destination_addr"`
+	DestinationPort uint32 `json:"destination_port"`
+}
+
+// ModuleBehavior is the load of a kernel module, e.g. do_init_module.
+type ModuleBehavior struct {
+	Name string `json:"name"`
+}
+
+// GetKey keys the behaviour on its architecture independent function name, so that the same
+// syscall yields the same profile node on x86 and arm64 nodes.
+func (behavior *KernelBehavior) GetKey() string {
+	return key("kernel", string(behavior.Kind)+":"+NormalizeFunctionName(behavior.Function)+":"+behavior.identity())
+}
+
+// syscallPrefixes are the architecture specific prefixes of syscall entry points.
+var syscallPrefixes = []string{"__x64_", "__ia32_", "__arm64_", "__se_", "__do_"}
+
+// NormalizeFunctionName strips architecture specific syscall prefixes so that
+// __x64_sys_mount and __arm64_sys_mount are both looked up as sys_mount.
+func NormalizeFunctionName(function string) string {
+	for _, prefix := range syscallPrefixes {
+		function = strings.TrimPrefix(function, prefix)
+	}
+	return function
+}
+
+// identity returns the fields of the typed record that distinguish two behaviours of the same kind.
+func (behavior *KernelBehavior) identity() string {
+	switch {
+	case behavior.Credentials != nil:
+		c := behavior.Credentials
+		return fmt.Sprintf("%d:%d:%d:%d:%s", c.UID, c.GID, c.EUID, c.EGID, strings.Join(c.Capabilities, ","))
+	case behavior.Capability != nil:
+		return behavior.Capability.Name
+	case behavior.Exec != nil:
+		return behavior.Exec.Path
+	case behavior.Mount != nil:
+		return behavior.Mount.Source + ":" + behavior.Mount.Target + ":" + behavior.Mount.FSType
+	case behavior.Bpf != nil:
+		b := behavior.Bpf
+		return b.ProgType + ":" + b.ProgName + ":" + b.MapType + ":" + b.MapName
+	case behavior.Ptrace != nil:
+		return fmt.Sprintf("%d", behavior.Ptrace.Request)
+	case behavior.File != nil:
+		if behavior.File.Access != "" {
+			return behavior.File.Path + ":" + behavior.File.Permission + ":" + behavior.File.Access
+		}
+		return behavior.File.Path + ":" + behavior.File.Permission
+	case behavior.Network != nil:
+		n := behavior.Network
+		return fmt.Sprintf("%s:%s:%s:%d", n.Family, n.Protocol, n.DestinationAddr, n.DestinationPort)
+	case behavior.Module != nil:
+		return behavior.Module.Name
+	case behavior.Namespace != nil:
+		return behavior.Namespace.Operation + ":" + strings.Join(behavior.Namespace.Types, ",")
+	}
+	return ""
+}
+
+// sinkKernelBehavior records the kernel behaviour on the process, evicting the least
+// recently seen behaviour when the retention policy caps the number of children.
+func (ctx *sinkContext) sinkKernelBehavior(process *Process, raw *KernelBehavior) {
+	if process.KernelBehaviors == nil {
+		process.KernelBehaviors = map[string]*KernelBehavior{}
+	}
+
+	behaviorKey := raw.GetKey()
+	behavior, ok := process.KernelBehaviors[behaviorKey]
+	if !ok {
+		behavior = raw
+		behavior.Count = 0
+		behavior.Provenance = nil
+		behavior.Rate = nil
+		behavior.FirstSeen = ctx.now
+		behavior.Attack = ctx.cluster.Attack.TagKernelBehavior(behavior)
+		if evicted := evictLeastRecentlySeenBehavior(process.KernelBehaviors, ctx.policy); evicted != "" {
+			ctx.result.Evicted = append(ctx.result.Evicted, evicted)
+			ctx.cluster.invalidatePrevalence()
+		}
+		ctx.result.Inserted(behaviorKey)
+		process.KernelBehaviors[behaviorKey] = behavior
+		ctx.indexKernelBehavior(behavior)
+	}
+	behavior.Count++
+	behavior.Provenance = behavior.Provenance.record(ctx.source)
+	behavior.LastSeen = ctx.now
+	ctx.sinkBehaviorRate(process, behavior, behaviorKey)
+}
+
+// evictLeastRecentlySeenBehavior makes room for one more kernel behaviour when the
+// retention policy caps the number of children and returns the evicted key, if any.
+func evictLeastRecentlySeenBehavior(behaviors map[string]*KernelBehavior, policy RetentionPolicy) string {
+	if policy.MaxChildren <= 0 || len(behaviors) < policy.MaxChildren {
+		return ""
+	}
+
+	var oldestKey string
+	var oldest *KernelBehavior
+	for key, behavior := range behaviors {
+		if oldest == nil || behavior.LastSeen.Before(oldest.LastSeen) {
+			oldestKey, oldest = key, behavior
+		}
+	}
+	delete(behaviors, oldestKey)
+
+	return oldestKey
+}
@@ -0,0 +1,87 @@
+package eventtype
+
+import (
+	"testing"
+)
+
+// testKernelEvent is a testEvent reporting a kernel function call made by its process.
+type testKernelEvent struct {
+	*testEvent
+	behavior *KernelBehavior
+}
+
+func (e *testKernelEvent) GetKernelBehavior() (*KernelBehavior, error) {
+	return e.behavior, nil
+}
+
+func newTestKernelEvent(behavior *KernelBehavior, options ...testEventOption) *testKernelEvent {
+	return &testKernelEvent{
+		testEvent: newTestEvent("default", "/bin/sh", "/usr/bin/app", "", options...),
+		behavior:  behavior,
+	}
+}
+
+func TestSinkKernelBehavior(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+	cluster.Retention = &RetentionConfig{Default: RetentionPolicy{MaxChildren: 2}}
+
+	open := func(path string) *KernelBehavior {
+		return &KernelBehavior{Kind: KernelBehaviorFile, Function: "security_file_open", File: &FileBehavior{Path: path}}
+	}
+
+	mustSink(t, cluster, newTestKernelEvent(open("/etc/passwd")))
+	mustSink(t, cluster, newTestKernelEvent(open("/etc/passwd")))
+	mustSink(t, cluster, newTestKernelEvent(open("/etc/hosts")))
+	result := mustSink(t, cluster, newTestKernelEvent(nil))
+	if result.Operation != SinkOperationUpdated {
+		t.Errorf("event without a decoded behavior inserted %v", result.Path)
+	}
+
+	app := cluster.Namespaces["namespace:default"].Pods["pod:nginx"].Containers["container:nginx"].
+		Processes[(&Process{Binary: "/bin/sh"}).GetKey()].ChildProcesses[(&Process{Binary: "/usr/bin/app"}).GetKey()]
+	passwd := app.KernelBehaviors[open("/etc/passwd").GetKey()]
+	if passwd == nil || passwd.Count != 2 {
+		t.Fatalf("/etc/passwd behavior = %+v; want 2 calls", passwd)
+	}
+
+	result = mustSink(t, cluster, newTestKernelEvent(open("/etc/resolv.conf")))
+	if len(result.Evicted) != 1 || len(app.KernelBehaviors) != 2 {
+		t.Errorf("evicted %v leaving %d behaviors; want one eviction and 2 behaviors", result.Evicted, len(app.KernelBehaviors))
+	}
+}
+
+func TestKernelBehaviorKeyIsArchitectureIndependent(t *testing.T) {
+	mount := func(function string) *KernelBehavior {
+		return &KernelBehavior{Kind: KernelBehaviorMount, Function: function, Mount: &MountBehavior{Source: "/dev/sda1", Target: "/host"}}
+	}
+	if x64, arm64 := mount("__x64_sys_mount").GetKey(), mount("__arm64_sys_mount").GetKey(); x64 != arm64 {
+		t.Errorf("keys of the same syscall differ: %s and %s", x64, arm64)
+	}
+}
+
+func TestKernelBehaviorIsWrite(t *testing.T) {
+	tests := []struct {
+		function   string
+		permission string
+		access     string
+		want       bool
+	}{
+		{"security_file_permission", "-rw-r--r--", "read", false},
+		{"security_file_permission", "-r--r--r--", "read,write", true},
+		{"security_file_permission", "-rw-r--r--", "", false},
+		{"security_path_truncate", "", "", true},
+		{"security_file_open", "-rw-r--r--", "", false},
+		{"security_mmap_file", "-rwxr-xr-x", "", false},
+	}
+
+	for _, tt := range tests {
+		behavior := &KernelBehavior{
+			Kind:     KernelBehaviorFile,
+			Function: tt.function,
+			File:     &FileBehavior{Path: "/etc/hosts", Permission: tt.permission, Access: tt.access},
+		}
+		if got := behavior.IsWrite(); got != tt.want {
+			t.Errorf("IsWrite() of %s %q %q = %v; want %v", tt.function, tt.permission, tt.access, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package eventtype
+
+import (
+	"fmt"
+	"time"
+)
+
+// Library is a shared object loaded by a process.
+type Library struct {
+	Path      string    `json:"path"`
+	BuildID   string    `json:"build_id,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+func (library *Library) GetKey() string {
+	return key("library", library.Path+":"+library.BuildID)
+}
+
+// sinkLibrary records the shared object on the process and flags libraries,
+// or builds of a library, that were not loaded while learning.
+func (ctx *sinkContext) sinkLibrary(process *Process, raw *Library) {
+	if process.Libraries == nil {
+		process.Libraries = map[string]*Library{}
+	}
+
+	libraryKey := raw.GetKey()
+	library, ok := process.Libraries[libraryKey]
+	if !ok {
+		library = &Library{
+			Path:      raw.Path,
+			BuildID:   raw.BuildID,
+			FirstSeen: ctx.now,
+		}
+		ctx.result.Inserted(libraryKey)
+		ctx.deviate(process, DeviationNewLibrary, raw.Path,
+			fmt.Sprintf("%s loaded %s (build id %q) which was never loaded during learning", process.Binary, raw.Path, raw.BuildID))
+		process.Libraries[libraryKey] = library
+	}
+	library.LastSeen = ctx.now
+}
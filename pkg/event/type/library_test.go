@@ -0,0 +1,55 @@
+package eventtype
+
+import (
+	"runtime-behavior-profiler/pkg/util"
+	"testing"
+	"time"
+)
+
+// testLoaderEvent is a testEvent reporting a shared object loaded by its process.
+type testLoaderEvent struct {
+	*testEvent
+	library *Library
+}
+
+func (e *testLoaderEvent) GetLibrary() (*Library, error) {
+	return e.library, nil
+}
+
+func newTestLoaderEvent(path string, buildID string) *testLoaderEvent {
+	return &testLoaderEvent{
+		testEvent: newTestEvent("default", "/bin/sh", "/usr/sbin/nginx", ""),
+		library:   &Library{Path: path, BuildID: buildID},
+	}
+}
+
+func TestSinkLibraryFlagsNewLibraries(t *testing.T) {
+	clock := util.NewFakeClock(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC))
+	cluster := NewCluster("test-cluster")
+	cluster.Clock = clock
+	cluster.LearningPeriod = time.Hour
+
+	mustSink(t, cluster, newTestLoaderEvent("/lib/x86_64-linux-gnu/libc.so.6", "aa"))
+	clock.Advance(2 * time.Hour)
+
+	result := mustSink(t, cluster, newTestLoaderEvent("/lib/x86_64-linux-gnu/libc.so.6", "aa"))
+	if len(result.Deviations) != 0 {
+		t.Errorf("learned library reported: %v", result.Deviations)
+	}
+
+	result = mustSink(t, cluster, newTestLoaderEvent("/tmp/libpreload.so", ""))
+	if len(result.Deviations) != 1 || result.Deviations[0].Type != DeviationNewLibrary || result.Deviations[0].Value != "/tmp/libpreload.so" {
+		t.Errorf("deviations = %v; want a single new library", result.Deviations)
+	}
+
+	result = mustSink(t, cluster, newTestLoaderEvent("/lib/x86_64-linux-gnu/libc.so.6", "bb"))
+	if len(result.Deviations) != 1 {
+		t.Errorf("a new build of a learned library was not reported")
+	}
+
+	nginx := cluster.Namespaces["namespace:default"].Pods["pod:nginx"].Containers["container:nginx"].
+		Processes[(&Process{Binary: "/bin/sh"}).GetKey()].ChildProcesses[(&Process{Binary: "/usr/sbin/nginx"}).GetKey()]
+	if len(nginx.Libraries) != 3 {
+		t.Errorf("nginx has %d libraries; want 3", len(nginx.Libraries))
+	}
+}
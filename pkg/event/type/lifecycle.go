@@ -0,0 +1,95 @@
+package eventtype
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxRecentExits is the number of individual terminations kept per process.
+const maxRecentExits = 5
+
+// abnormalSignals are the termination signals reported as deviations the first time a
+// process is killed by one of them.
+var abnormalSignals = map[string]bool{
+	"SIGABRT": true,
+	"SIGBUS":  true,
+	"SIGFPE":  true,
+	"SIGILL":  true,
+	"SIGKILL": true,
+	"SIGSEGV": true,
+	"SIGSYS":  true,
+	"SIGTRAP": true,
+}
+
+// ProcessExit describes the termination of a single execution of a process.
+type ProcessExit struct {
+	ExecID    string    `json:"exec_id"`
+	StartTime time.Time `json:"start_time"`
+	ExitTime  time.Time `json:"exit_time"`
+	Status    uint32    `json:"status"`
+	Signal    string    `json:"signal,omitempty"`
+}
+
+// ProcessLifecycle aggregates the terminations observed for a profiled process.
+type ProcessLifecycle struct {
+	Exits         int64            `json:"exits"`
+	TimedExits    int64            `json:"timed_exits"`
+	MinDuration   time.Duration    `json:"min_duration"`
+	MaxDuration   time.Duration    `json:"max_duration"`
+	MeanDuration  time.Duration    `json:"mean_duration"`
+	TotalDuration time.Duration    `json:"total_duration"`
+	ExitCodes     map[uint32]int64 `json:"exit_codes"`
+	Signals       map[string]int64 `json:"signals"`
+	RecentExits   []*ProcessExit   `json:"recent_exits"`
+}
+
+// Duration returns how long the execution ran, or zero when either timestamp is unknown.
+func (exit *ProcessExit) Duration() time.Duration {
+	if exit.StartTime.IsZero() || exit.ExitTime.IsZero() || exit.ExitTime.Before(exit.StartTime) {
+		return 0
+	}
+	return exit.ExitTime.Sub(exit.StartTime)
+}
+
+// sinkExit records the termination on the process lifecycle, flags abnormal terminations
+// by a signal not seen before on the process and drops the exec id of the terminated execution from the index.
+func (ctx *sinkContext) sinkExit(process *Process, exit *ProcessExit) {
+	if process.Lifecycle == nil {
+		process.Lifecycle = &ProcessLifecycle{
+			ExitCodes: map[uint32]int64{},
+			Signals:   map[string]int64{},
+		}
+	}
+	lifecycle := process.Lifecycle
+
+	if duration := exit.Duration(); duration > 0 {
+		if lifecycle.TimedExits == 0 || duration < lifecycle.MinDuration {
+			lifecycle.MinDuration = duration
+		}
+		if duration > lifecycle.MaxDuration {
+			lifecycle.MaxDuration = duration
+		}
+		lifecycle.TimedExits++
+		lifecycle.TotalDuration += duration
+		lifecycle.MeanDuration = lifecycle.TotalDuration / time.Duration(lifecycle.TimedExits)
+	}
+	lifecycle.Exits++
+	lifecycle.ExitCodes[exit.Status]++
+
+	if exit.Signal != "" {
+		if abnormalSignals[exit.Signal] && lifecycle.Signals[exit.Signal] == 0 {
+			ctx.deviate(process, DeviationAbnormalTermination, exit.Signal,
+				fmt.Sprintf("%s terminated by %s which was not seen before", process.Binary, exit.Signal))
+		}
+		lifecycle.Signals[exit.Signal]++
+	}
+
+	lifecycle.RecentExits = append(lifecycle.RecentExits, exit)
+	if len(lifecycle.RecentExits) > maxRecentExits {
+		lifecycle.RecentExits = lifecycle.RecentExits[len(lifecycle.RecentExits)-maxRecentExits:]
+	}
+
+	if ctx.container.execIndex != nil {
+		delete(ctx.container.execIndex, exit.ExecID)
+	}
+}
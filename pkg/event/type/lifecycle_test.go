@@ -0,0 +1,110 @@
+package eventtype
+
+import (
+	"runtime-behavior-profiler/pkg/util"
+	"testing"
+	"time"
+)
+
+// testExitEvent is a testEvent reporting the termination of its process.
+type testExitEvent struct {
+	*testEvent
+	exit *ProcessExit
+}
+
+func (e *testExitEvent) GetExit() (*ProcessExit, error) {
+	return e.exit, nil
+}
+
+func newTestExitEvent(execID string, duration time.Duration, status uint32, signal string, now time.Time) *testExitEvent {
+	event := newTestEvent("default", "/bin/sh", "/usr/bin/worker", "")
+	event.process.ExecID = execID
+	return &testExitEvent{
+		testEvent: event,
+		exit: &ProcessExit{
+			ExecID:    execID,
+			StartTime: now.Add(-duration),
+			ExitTime:  now,
+			Status:    status,
+			Signal:    signal,
+		},
+	}
+}
+
+func TestSinkExitTracksLifecycle(t *testing.T) {
+	clock := util.NewFakeClock(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC))
+	cluster := NewCluster("test-cluster")
+	cluster.Clock = clock
+
+	mustSink(t, cluster, newTestExitEvent("a", time.Second, 0, "", clock.Now()))
+	mustSink(t, cluster, newTestExitEvent("b", 3*time.Second, 1, "", clock.Now()))
+	mustSink(t, cluster, newTestExitEvent("c", 0, 0, "SIGTERM", time.Time{}))
+
+	container := cluster.Namespaces["namespace:default"].Pods["pod:nginx"].Containers["container:nginx"]
+	worker := container.Processes[(&Process{Binary: "/bin/sh"}).GetKey()].ChildProcesses[(&Process{Binary: "/usr/bin/worker"}).GetKey()]
+	lifecycle := worker.Lifecycle
+	if lifecycle == nil {
+		t.Fatalf("lifecycle not recorded")
+	}
+
+	if lifecycle.Exits != 3 || lifecycle.TimedExits != 2 {
+		t.Errorf("exits = %d, timed = %d; want 3 and 2", lifecycle.Exits, lifecycle.TimedExits)
+	}
+	if lifecycle.MinDuration != time.Second || lifecycle.MaxDuration != 3*time.Second || lifecycle.MeanDuration != 2*time.Second {
+		t.Errorf("durations = %s/%s/%s; want 1s/3s/2s", lifecycle.MinDuration, lifecycle.MaxDuration, lifecycle.MeanDuration)
+	}
+	if lifecycle.ExitCodes[0] != 2 || lifecycle.ExitCodes[1] != 1 || lifecycle.Signals["SIGTERM"] != 1 {
+		t.Errorf("exit codes = %v, signals = %v", lifecycle.ExitCodes, lifecycle.Signals)
+	}
+	if container.lookupExec("a") != nil {
+		t.Errorf("exec id of a terminated process is still indexed")
+	}
+}
+
+func TestSinkExitFlagsAbnormalTermination(t *testing.T) {
+	clock := util.NewFakeClock(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC))
+	cluster := NewCluster("test-cluster")
+	cluster.Clock = clock
+	cluster.LearningPeriod = time.Hour
+
+	// Seen while learning, never reported.
+	result := mustSink(t, cluster, newTestExitEvent("a", time.Second, 0, "SIGKILL", clock.Now()))
+	if len(result.Deviations) != 0 {
+		t.Errorf("deviation reported while learning: %v", result.Deviations)
+	}
+
+	clock.Advance(2 * time.Hour)
+
+	result = mustSink(t, cluster, newTestExitEvent("b", time.Second, 0, "SIGKILL", clock.Now()))
+	if len(result.Deviations) != 0 {
+		t.Errorf("SIGKILL was learned but reported: %v", result.Deviations)
+	}
+
+	result = mustSink(t, cluster, newTestExitEvent("c", time.Second, 0, "SIGSEGV", clock.Now()))
+	if len(result.Deviations) != 1 || result.Deviations[0].Type != DeviationAbnormalTermination || result.Deviations[0].Value != "SIGSEGV" {
+		t.Fatalf("deviations = %v; want a single SIGSEGV abnormal termination", result.Deviations)
+	}
+
+	result = mustSink(t, cluster, newTestExitEvent("d", time.Second, 0, "SIGSEGV", clock.Now()))
+	if len(result.Deviations) != 0 {
+		t.Errorf("SIGSEGV reported twice: %v", result.Deviations)
+	}
+}
+
+func TestNewClusterReportsDeviationsAfterTheDefaultLearningPeriod(t *testing.T) {
+	clock := util.NewFakeClock(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC))
+	cluster := NewCluster("test-cluster")
+	cluster.Clock = clock
+
+	mustSink(t, cluster, newTestExitEvent("a", time.Second, 0, "", clock.Now()))
+	clock.Advance(DefaultLearningPeriod - time.Minute)
+	if result := mustSink(t, cluster, newTestExitEvent("b", time.Second, 0, "SIGKILL", clock.Now())); len(result.Deviations) != 0 {
+		t.Errorf("deviation reported within the learning period: %v", result.Deviations)
+	}
+
+	clock.Advance(time.Minute)
+	result := mustSink(t, cluster, newTestExitEvent("c", time.Second, 0, "SIGSEGV", clock.Now()))
+	if len(result.Deviations) != 1 || result.Deviations[0].Type != DeviationAbnormalTermination {
+		t.Errorf("deviations = %v; want an abnormal termination once learning ended", result.Deviations)
+	}
+}
@@ -0,0 +1,172 @@
+package eventtype
+
+import (
+	"time"
+)
+
+// getLineage returns the ancestors of the event process ordered from the oldest ancestor
+// to the immediate parent. Events without ancestry information yield the parent only.
+func getLineage(rawEvent IEvent) ([]*Process, error) {
+	if ancestryEvent, ok := rawEvent.(IAncestryEvent); ok {
+		ancestors, err := ancestryEvent.GetAncestors()
+		if err != nil {
+			return nil, err
+		}
+		if len(ancestors) > 0 {
+			return ancestors, nil
+		}
+	}
+
+	parent, err := rawEvent.GetParentProcess()
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Process{parent}, nil
+}
+
+// sinkLineage places the process in the container process tree and returns its profile node.
+// A process already known by exec id is updated in place wherever it sits in the tree,
+// the lineage is only materialized from the container root when no ancestor is known.
+func (ctx *sinkContext) sinkLineage(lineage []*Process, processRaw *Process) *Process {
+	container := ctx.container
+	now := ctx.now
+
+	if process := container.lookupExec(processRaw.ExecID); process != nil {
+		process.LastSeen = now
+		process.addArgumentExample(processRaw.Arguments)
+		container.indexExec(processRaw.ExecID, process, now)
+		return process
+	}
+
+	// A known parent takes the process directly, otherwise the lineage is attached below
+	// the deepest ancestor already known, or below the parent of the oldest ancestor.
+	if parent := container.lookupExec(processRaw.ParentExecID); parent != nil {
+		parent.LastSeen = now
+		container.indexExec(processRaw.ParentExecID, parent, now)
+		return ctx.sinkProcess(parent.ChildProcesses, processRaw)
+	}
+
+	children := container.Processes
+	start := 0
+	for i := len(lineage) - 1; i >= 0; i-- {
+		if anchor := container.lookupExec(lineage[i].ExecID); anchor != nil {
+			anchor.LastSeen = now
+			container.indexExec(lineage[i].ExecID, anchor, now)
+			children = anchor.ChildProcesses
+			start = i + 1
+			break
+		}
+	}
+	if start == 0 && len(lineage) > 0 {
+		if anchor := container.lookupExec(lineage[0].ParentExecID); anchor != nil {
+			anchor.LastSeen = now
+			container.indexExec(lineage[0].ParentExecID, anchor, now)
+			children = anchor.ChildProcesses
+		}
+	}
+
+	for _, ancestorRaw := range lineage[start:] {
+		children = ctx.sinkProcess(children, ancestorRaw).ChildProcesses
+	}
+
+	return ctx.sinkProcess(children, processRaw)
+}
+
+// sinkProcess inserts or updates the raw process in processes, evicting the least recently
+// seen sibling when the retention policy caps the number of children.
+func (ctx *sinkContext) sinkProcess(processes map[string]*Process, raw *Process) *Process {
+	node := ctx.cluster.newProcessNode(raw, ctx.now)
+	key := node.GetKey()
+
+	process, ok := processes[key]
+	if !ok {
+		process = node
+		process.Attack = ctx.cluster.Attack.TagProcess(process)
+		if evicted := ctx.container.evict(processes, ctx.policy); evicted != "" {
+			ctx.result.Evicted = append(ctx.result.Evicted, evicted)
+			ctx.cluster.invalidatePrevalence()
+		}
+		ctx.result.Inserted(process.GetKey())
+		processes[key] = process
+		ctx.indexProcess(process)
+	}
+	process.LastSeen = ctx.now
+	process.addArgumentExample(raw.Arguments)
+	ctx.container.indexExec(raw.ExecID, process, ctx.now)
+
+	return process
+}
+
+// lookupExec returns the profile node of the process with the given exec id, if known.
+func (container *Container) lookupExec(execID string) *Process {
+	if execID == "" || container.execIndex == nil {
+		return nil
+	}
+	if entry, ok := container.execIndex[execID]; ok {
+		return entry.process
+	}
+	return nil
+}
+
+// indexExec records the profile node an exec id was attached to and when it was last seen.
+func (container *Container) indexExec(execID string, process *Process, now time.Time) {
+	if execID == "" {
+		return
+	}
+	if container.execIndex == nil {
+		container.execIndex = map[string]*execEntry{}
+	}
+	container.execIndex[execID] = &execEntry{process: process, lastSeen: now}
+}
+
+// forgetExecs drops exec index entries pointing at removed profile nodes.
+func (container *Container) forgetExecs(removed map[*Process]bool) {
+	if len(removed) == 0 {
+		return
+	}
+	for execID, entry := range container.execIndex {
+		if removed[entry.process] {
+			delete(container.execIndex, execID)
+		}
+	}
+}
+
+// expireExecs drops exec index entries last seen before cutoff. Sources without exit events
+// never remove exec ids otherwise, a later event of a forgotten exec id is placed from its
+// lineage again.
+func (container *Container) expireExecs(cutoff time.Time) int {
+	expired := 0
+	for execID, entry := range container.execIndex {
+		if entry.lastSeen.Before(cutoff) {
+			delete(container.execIndex, execID)
+			expired++
+		}
+	}
+	return expired
+}
+
+// evict makes room for one more entry in processes when the retention policy caps the
+// number of children. It returns the key of the evicted process, or an empty string.
+func (container *Container) evict(processes map[string]*Process, policy RetentionPolicy) string {
+	if policy.MaxChildren <= 0 || len(processes) < policy.MaxChildren {
+		return ""
+	}
+
+	key, evicted := evictLeastRecentlySeen(processes)
+	if evicted != nil {
+		removed := map[*Process]bool{}
+		collectSubtree(evicted, removed)
+		container.forgetExecs(removed)
+	}
+
+	return key
+}
+
+// collectSubtree adds process and all of its descendants to nodes.
+func collectSubtree(process *Process, nodes map[*Process]bool) {
+	nodes[process] = true
+	for _, child := range process.ChildProcesses {
+		collectSubtree(child, nodes)
+	}
+}
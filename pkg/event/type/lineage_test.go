@@ -0,0 +1,89 @@
+package eventtype
+
+import (
+	"runtime-behavior-profiler/pkg/util"
+	"testing"
+	"time"
+)
+
+func TestSinkEventBuildsLineage(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+
+	shim := &Process{Binary: "/usr/bin/containerd-shim", ExecID: "shim"}
+	sh := &Process{Binary: "/bin/sh", ExecID: "sh", ParentExecID: "shim"}
+	bash := &Process{Binary: "/bin/bash", ExecID: "bash", ParentExecID: "sh"}
+	curl := &Process{Binary: "/usr/bin/curl", Arguments: "https://example.com", ExecID: "curl", ParentExecID: "bash"}
+
+	result := mustSink(t, cluster, newTestEvent("default", "", "", "", withLineage(curl, bash, shim, sh, bash)))
+	if len(result.Path) != 7 {
+		t.Fatalf("exec inserted %v; want namespace, pod, container and 4 processes", result.Path)
+	}
+
+	container := cluster.Namespaces["namespace:default"].Pods["pod:nginx"].Containers["container:nginx"]
+	node := container.Processes[shim.GetKey()]
+	for _, raw := range []*Process{sh, bash, curl} {
+		if node == nil {
+			t.Fatalf("lineage broken before %s", raw.Binary)
+		}
+		node = node.ChildProcesses[raw.GetKey()]
+	}
+	if node == nil {
+		t.Fatalf("curl not found below bash")
+	}
+
+	// A later event only carrying the immediate parent updates the existing node.
+	result = mustSink(t, cluster, newTestEvent("default", "", "", "", withLineage(curl, bash)))
+	if result.Operation != SinkOperationUpdated {
+		t.Errorf("second curl event operation = %s; want %s", result.Operation, SinkOperationUpdated)
+	}
+	if len(container.Processes) != 1 {
+		t.Errorf("container has %d root processes; want 1", len(container.Processes))
+	}
+
+	// A child of bash without ancestry is attached through the exec id index.
+	ls := &Process{Binary: "/bin/ls", ExecID: "ls", ParentExecID: "bash"}
+	mustSink(t, cluster, newTestEvent("default", "", "", "", withLineage(ls, &Process{Binary: "root"})))
+
+	bashNode := container.Processes[shim.GetKey()].ChildProcesses[sh.GetKey()].ChildProcesses[bash.GetKey()]
+	if _, ok := bashNode.ChildProcesses[ls.GetKey()]; !ok {
+		t.Errorf("ls was not attached below bash")
+	}
+}
+
+func TestEvictionForgetsExecIDs(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+	cluster.Retention = &RetentionConfig{Default: RetentionPolicy{MaxChildren: 1}}
+
+	sh := &Process{Binary: "/bin/sh", ExecID: "sh"}
+	mustSink(t, cluster, newTestEvent("default", "", "", "", withLineage(&Process{Binary: "/bin/ls", ExecID: "ls", ParentExecID: "sh"}, sh)))
+	mustSink(t, cluster, newTestEvent("default", "", "", "", withLineage(&Process{Binary: "/bin/bash", ExecID: "bash"}, &Process{Binary: "/sbin/init"})))
+
+	container := cluster.Namespaces["namespace:default"].Pods["pod:nginx"].Containers["container:nginx"]
+	if container.lookupExec("sh") != nil || container.lookupExec("ls") != nil {
+		t.Errorf("exec ids of the evicted subtree are still indexed")
+	}
+}
+
+func TestCompactExpiresExecIDs(t *testing.T) {
+	clock := util.NewFakeClock(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC))
+	cluster := newRetentionTestCluster(clock, &RetentionConfig{Default: RetentionPolicy{ExecTTL: time.Hour}})
+
+	sh := &Process{Binary: "/bin/sh", ExecID: "sh"}
+	mustSink(t, cluster, newTestEvent("default", "", "", "", withLineage(&Process{Binary: "/bin/ls", ExecID: "ls", ParentExecID: "sh"}, sh)))
+	clock.Advance(50 * time.Minute)
+	mustSink(t, cluster, newTestEvent("default", "", "", "", withLineage(&Process{Binary: "/bin/cat", ExecID: "cat", ParentExecID: "sh"}, sh)))
+	clock.Advance(20 * time.Minute)
+
+	result := cluster.Compact()
+	container := cluster.Namespaces["namespace:default"].Pods["pod:nginx"].Containers["container:nginx"]
+	if result.ExpiredExecs != 1 || container.lookupExec("ls") != nil {
+		t.Errorf("expired %d exec ids; want ls expired", result.ExpiredExecs)
+	}
+	// The shell was seen again as the parent of cat.
+	if container.lookupExec("sh") == nil || container.lookupExec("cat") == nil {
+		t.Errorf("exec ids seen within the TTL were expired")
+	}
+	if len(result.Removed) != 0 {
+		t.Errorf("compaction removed %v; want the profile kept without a TTL", result.Removed)
+	}
+}
@@ -0,0 +1,111 @@
+package eventtype
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxNamespaceChanges is the number of namespace changes kept per container.
+const maxNamespaceChanges = 50
+
+// LinuxNamespace identifies a Linux namespace a process runs in.
+type LinuxNamespace struct {
+	Inum   uint32 `json:"inum"`
+	IsHost bool   `json:"is_host"`
+}
+
+// LinuxNamespaces maps a namespace type (mnt, pid, net, user, uts, ipc, cgroup, time)
+// to the namespace a process runs in.
+type LinuxNamespaces map[string]*LinuxNamespace
+
+// NamespaceChange is a process observed in a namespace other than the one its container
+// was first seen in.
+type NamespaceChange struct {
+	Type      string    `json:"type"`
+	From      uint32    `json:"from"`
+	To        uint32    `json:"to"`
+	IsHost    bool      `json:"is_host"`
+	Binary    string    `json:"binary"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+// NamespaceBehavior is the creation of, or move into, namespaces through unshare or setns.
+type NamespaceBehavior struct {
+	Operation string   `json:"operation"`
+	Types     []string `json:"types"`
+}
+
+// sinkNamespaces compares the namespaces of the process with the ones its container was
+// first seen in, recording every new namespace and reporting moves into host namespaces
+// and the creation of new user namespaces regardless of the learning state.
+func (ctx *sinkContext) sinkNamespaces(process *Process, processRaw *Process) {
+	if len(processRaw.Namespaces) == 0 {
+		return
+	}
+
+	container := ctx.container
+	if container.LinuxNamespaces == nil {
+		container.LinuxNamespaces = LinuxNamespaces{}
+	}
+
+	for nsType, namespace := range processRaw.Namespaces {
+		baseline, ok := container.LinuxNamespaces[nsType]
+		if !ok {
+			container.LinuxNamespaces[nsType] = &LinuxNamespace{Inum: namespace.Inum, IsHost: namespace.IsHost}
+			continue
+		}
+		if baseline.Inum == namespace.Inum || !container.firstNamespaceReport(fmt.Sprintf("%s:%d", nsType, namespace.Inum)) {
+			continue
+		}
+
+		container.NamespaceChanges = append(container.NamespaceChanges, &NamespaceChange{
+			Type:      nsType,
+			From:      baseline.Inum,
+			To:        namespace.Inum,
+			IsHost:    namespace.IsHost,
+			Binary:    process.Binary,
+			FirstSeen: ctx.now,
+		})
+		if len(container.NamespaceChanges) > maxNamespaceChanges {
+			container.NamespaceChanges = container.NamespaceChanges[len(container.NamespaceChanges)-maxNamespaceChanges:]
+		}
+
+		switch {
+		case namespace.IsHost && !baseline.IsHost:
+			ctx.report(process, DeviationHostNamespace, nsType,
+				fmt.Sprintf("%s entered the host %s namespace", process.Binary, nsType))
+		case nsType == "user" && !namespace.IsHost:
+			ctx.report(process, DeviationUserNamespaceCreated, nsType,
+				fmt.Sprintf("%s runs in a new user namespace %d", process.Binary, namespace.Inum))
+		}
+	}
+}
+
+// detectNamespaceBehavior reports the first unshare call of each process creating a user
+// namespace.
+func (ctx *sinkContext) detectNamespaceBehavior(process *Process, behavior *KernelBehavior) {
+	if behavior.Namespace == nil || behavior.Namespace.Operation != "unshare" {
+		return
+	}
+
+	for _, nsType := range behavior.Namespace.Types {
+		if nsType == "user" && ctx.container.firstNamespaceReport(behavior.Namespace.Operation+":"+process.GetKey()+":"+nsType) {
+			ctx.report(process, DeviationUserNamespaceCreated, behavior.Namespace.Operation,
+				fmt.Sprintf("%s called %s for a user namespace", process.Binary, behavior.Namespace.Operation))
+		}
+	}
+}
+
+// firstNamespaceReport records a namespace the container was seen in, or a process that
+// created a namespace, and reports whether it is the first time. The keys are kept apart
+// from the capped NamespaceChanges so that nothing is reported twice.
+func (container *Container) firstNamespaceReport(key string) bool {
+	if container.namespaceReports[key] {
+		return false
+	}
+	if container.namespaceReports == nil {
+		container.namespaceReports = map[string]bool{}
+	}
+	container.namespaceReports[key] = true
+	return true
+}
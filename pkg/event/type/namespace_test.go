@@ -0,0 +1,80 @@
+package eventtype
+
+import (
+	"testing"
+)
+
+func TestSinkNamespacesDetectsBreakouts(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+
+	container := LinuxNamespaces{
+		"mnt":  {Inum: 100},
+		"pid":  {Inum: 101},
+		"user": {Inum: 1, IsHost: true},
+	}
+	result := mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/sbin/nginx", "", withNamespaces(container)))
+	if len(result.Deviations) != 0 {
+		t.Errorf("baseline namespaces reported: %v", result.Deviations)
+	}
+
+	// nsenter into the host mount namespace is reported even while learning.
+	result = mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/bin/nsenter", "", withNamespaces(LinuxNamespaces{
+		"mnt":  {Inum: 2, IsHost: true},
+		"pid":  {Inum: 101},
+		"user": {Inum: 1, IsHost: true},
+	})))
+	if len(result.Deviations) != 1 || result.Deviations[0].Type != DeviationHostNamespace || result.Deviations[0].Value != "mnt" {
+		t.Errorf("deviations = %v; want the host mnt namespace", result.Deviations)
+	}
+
+	result = mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/bin/nsenter", "", withNamespaces(LinuxNamespaces{"mnt": {Inum: 2, IsHost: true}})))
+	if len(result.Deviations) != 0 {
+		t.Errorf("known namespace change reported twice: %v", result.Deviations)
+	}
+
+	result = mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/bin/unshare", "", withNamespaces(LinuxNamespaces{"user": {Inum: 200}})))
+	if len(result.Deviations) != 1 || result.Deviations[0].Type != DeviationUserNamespaceCreated {
+		t.Errorf("deviations = %v; want a new user namespace", result.Deviations)
+	}
+
+	nginx := cluster.Namespaces["namespace:default"].Pods["pod:nginx"].Containers["container:nginx"]
+	if len(nginx.NamespaceChanges) != 2 {
+		t.Errorf("container recorded %d namespace changes; want 2", len(nginx.NamespaceChanges))
+	}
+
+	// The host mnt namespace stays reported once it left the capped history.
+	for inum := uint32(1000); inum < 1000+maxNamespaceChanges; inum++ {
+		mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/bin/unshare", "", withNamespaces(LinuxNamespaces{"pid": {Inum: inum}})))
+	}
+	result = mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/bin/nsenter", "", withNamespaces(LinuxNamespaces{"mnt": {Inum: 2, IsHost: true}})))
+	if len(nginx.NamespaceChanges) != maxNamespaceChanges || len(result.Deviations) != 0 {
+		t.Errorf("host namespace reported again after %d changes: %v", len(nginx.NamespaceChanges), result.Deviations)
+	}
+}
+
+func TestDetectUnshareOfUserNamespace(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+
+	unshare := func(operation string, nsTypes ...string) *testKernelEvent {
+		return newTestKernelEvent(&KernelBehavior{
+			Kind:      KernelBehaviorNamespace,
+			Function:  "__x64_sys_" + operation,
+			Namespace: &NamespaceBehavior{Operation: operation, Types: nsTypes},
+		})
+	}
+
+	result := mustSink(t, cluster, unshare("unshare", "mnt"))
+	if len(result.Deviations) != 0 {
+		t.Errorf("unshare of a mount namespace reported: %v", result.Deviations)
+	}
+
+	result = mustSink(t, cluster, unshare("unshare", "user", "mnt"))
+	if len(result.Deviations) != 1 || result.Deviations[0].Type != DeviationUserNamespaceCreated {
+		t.Errorf("deviations = %v; want a user namespace creation", result.Deviations)
+	}
+
+	result = mustSink(t, cluster, unshare("unshare", "user"))
+	if len(result.Deviations) != 0 {
+		t.Errorf("second user namespace creation of the process reported: %v", result.Deviations)
+	}
+}
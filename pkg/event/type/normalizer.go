@@ -0,0 +1,116 @@
+package eventtype
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxArgumentExamples is the number of distinct raw argument strings kept per process.
+const maxArgumentExamples = 5
+
+// ArgumentNormalizer generalizes process arguments before they are used to key a process,
+// so that invocations differing only in volatile values share a single profile node.
+type ArgumentNormalizer interface {
+	Normalize(arguments string) string
+}
+
+// ArgumentRule replaces every match of Pattern in an argument token with Replacement.
+type ArgumentRule struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+type compiledArgumentRule struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// RegexArgumentNormalizer tokenizes arguments on whitespace and masks volatile values
+// in each token. User supplied rules are applied before the built-in masks.
+type RegexArgumentNormalizer struct {
+	rules []compiledArgumentRule
+}
+
+var (
+	uuidPattern      = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+	timestampPattern = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}(?:[T_ ]\d{2}[:\-]?\d{2}[:\-]?\d{2}(?:\.\d+)?(?:Z|[+\-]\d{2}:?\d{2})?)?\b`)
+	ipPattern        = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}(?::\d+)?\b`)
+	tmpPathPattern   = regexp.MustCompile(`(?:/var)?/tmp/[^\s'"]*|/dev/shm/[^\s'"]*`)
+	hexPattern       = regexp.MustCompile(`\b(?:0x)?[0-9a-fA-F]{8,}\b`)
+	numberPattern    = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	allDigitsPattern = regexp.MustCompile(`^\d+$`)
+)
+
+// NewArgumentNormalizer returns a RegexArgumentNormalizer with the given user rules
+// followed by the built-in masks for UUIDs, timestamps, IPs, temp paths, hex and numbers.
+func NewArgumentNormalizer(rules []ArgumentRule) (*RegexArgumentNormalizer, error) {
+	normalizer := &RegexArgumentNormalizer{}
+
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument rule %q: %w", rule.Pattern, err)
+		}
+		normalizer.rules = append(normalizer.rules, compiledArgumentRule{re: re, replacement: rule.Replacement})
+	}
+
+	return normalizer, nil
+}
+
+// DefaultArgumentNormalizer returns a normalizer applying only the built-in masks.
+func DefaultArgumentNormalizer() *RegexArgumentNormalizer {
+	return &RegexArgumentNormalizer{}
+}
+
+// Normalize implements ArgumentNormalizer.
+func (n *RegexArgumentNormalizer) Normalize(arguments string) string {
+	tokens := strings.Fields(arguments)
+	for i, token := range tokens {
+		tokens[i] = n.normalizeToken(token)
+	}
+	return strings.Join(tokens, " ")
+}
+
+func (n *RegexArgumentNormalizer) normalizeToken(token string) string {
+	for _, rule := range n.rules {
+		token = rule.re.ReplaceAllString(token, rule.replacement)
+	}
+
+	token = uuidPattern.ReplaceAllString(token, "<uuid>")
+	token = timestampPattern.ReplaceAllString(token, "<time>")
+	token = ipPattern.ReplaceAllString(token, "<ip>")
+	token = tmpPathPattern.ReplaceAllString(token, "<tmp>")
+	token = hexPattern.ReplaceAllStringFunc(token, func(match string) string {
+		// Long decimal values are numbers, not hex digests.
+		if allDigitsPattern.MatchString(match) {
+			return "<num>"
+		}
+		return "<hex>"
+	})
+	token = numberPattern.ReplaceAllString(token, "<num>")
+
+	return token
+}
+
+// normalizeArguments applies the cluster normalizer, leaving arguments untouched when none is set.
+func (cluster *Cluster) normalizeArguments(arguments string) string {
+	if cluster.Normalizer == nil {
+		return arguments
+	}
+	return cluster.Normalizer.Normalize(arguments)
+}
+
+// addArgumentExample records a raw argument string on the process for auditability,
+// keeping at most maxArgumentExamples distinct values.
+func (process *Process) addArgumentExample(arguments string) {
+	if arguments == "" || len(process.ArgumentExamples) >= maxArgumentExamples {
+		return
+	}
+	for _, example := range process.ArgumentExamples {
+		if example == arguments {
+			return
+		}
+	}
+	process.ArgumentExamples = append(process.ArgumentExamples, arguments)
+}
@@ -0,0 +1,64 @@
+package eventtype
+
+import (
+	"testing"
+)
+
+func TestRegexArgumentNormalizer(t *testing.T) {
+	normalizer, err := NewArgumentNormalizer([]ArgumentRule{
+		{Pattern: `^--token=.*$`, Replacement: "--token=<secret>"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create normalizer: %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1", "<num>"},
+		{"  -n   5  ", "-n <num>"},
+		{"--request-id=3f2b8c1e-9a7d-4e6f-8b2a-1c3d5e7f9a0b", "--request-id=<uuid>"},
+		{"connect 10.0.12.7:8080", "connect <ip>"},
+		{"-o /tmp/tmp.XyZ123/out.json", "-o <tmp>"},
+		{"cat /dev/shm/sem.abc", "cat <tmp>"},
+		{"--since 2024-12-02T12:38:31Z", "--since <time>"},
+		{"checkout deadbeefcafe1234", "checkout <hex>"},
+		{"kill -9 123456789", "kill -<num> <num>"},
+		{"--token=abc123 https://www.google.com", "--token=<secret> https://www.google.com"},
+		{"sha256sum file.txt", "sha256sum file.txt"},
+	}
+
+	for _, test := range tests {
+		result := normalizer.Normalize(test.input)
+		if result != test.expected {
+			t.Errorf("Normalize(%q) = %q; expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestNewArgumentNormalizerRejectsInvalidRule(t *testing.T) {
+	if _, err := NewArgumentNormalizer([]ArgumentRule{{Pattern: "("}}); err == nil {
+		t.Errorf("expected an error for an invalid pattern")
+	}
+}
+
+func TestSinkEventMergesNormalizedArguments(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+
+	mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/bin/sleep", "1"))
+	result := mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/bin/sleep", "2"))
+
+	if result.Operation != SinkOperationUpdated {
+		t.Errorf("sleep 2 operation = %s; want %s", result.Operation, SinkOperationUpdated)
+	}
+
+	parent := cluster.Namespaces["namespace:default"].Pods["pod:nginx"].Containers["container:nginx"].Processes[(&Process{Binary: "/bin/sh"}).GetKey()]
+	process := parent.ChildProcesses[(&Process{Binary: "/bin/sleep", Arguments: "<num>"}).GetKey()]
+	if process == nil {
+		t.Fatalf("normalized sleep process not found")
+	}
+	if len(process.ArgumentExamples) != 2 || process.ArgumentExamples[0] != "1" || process.ArgumentExamples[1] != "2" {
+		t.Errorf("argument examples = %v; want [1 2]", process.ArgumentExamples)
+	}
+}
@@ -0,0 +1,275 @@
+package eventtype
+
+import (
+	"math"
+	"net"
+	"sort"
+	"strconv"
+)
+
+type PrevalenceKind string
+
+const (
+	// PrevalenceProcess is a binary, whatever its arguments.
+	PrevalenceProcess PrevalenceKind = "process"
+	// PrevalenceArguments is a binary run with a given set of normalized arguments.
+	PrevalenceArguments PrevalenceKind = "arguments"
+	// PrevalenceConnection is a destination connected to, e.g. IPPROTO_TCP 10.0.0.1:5432.
+	PrevalenceConnection PrevalenceKind = "connection"
+)
+
+// Prevalence is how widespread a behaviour is across the namespaces, workloads and images
+// of a cluster.
+type Prevalence struct {
+	Cluster    string         `json:"cluster"`
+	Kind       PrevalenceKind `json:"kind"`
+	Behavior   string         `json:"behavior"`
+	Namespaces int            `json:"namespaces"`
+	Workloads  int            `json:"workloads"`
+	Images     int            `json:"images"`
+	// Rarity goes from 0, for a behaviour of every workload, to 1 for a behaviour never seen.
+	Rarity float64 `json:"rarity"`
+}
+
+// prevalenceIndex records the namespaces, workloads and images showing each behaviour of
+// the cluster. It is built on first use and kept up to date as nodes are inserted.
+type prevalenceIndex struct {
+	totals    *prevalenceSets
+	behaviors map[PrevalenceKind]map[string]*prevalenceSets
+}
+
+type prevalenceSets struct {
+	namespaces map[string]bool
+	workloads  map[string]bool
+	images     map[string]bool
+}
+
+// prevalenceLocation is the container a behaviour was seen in.
+type prevalenceLocation struct {
+	namespace string
+	workload  string
+	image     string
+}
+
+func newPrevalenceSets() *prevalenceSets {
+	return &prevalenceSets{namespaces: map[string]bool{}, workloads: map[string]bool{}, images: map[string]bool{}}
+}
+
+func (sets *prevalenceSets) add(location prevalenceLocation) {
+	sets.namespaces[location.namespace] = true
+	sets.workloads[location.workload] = true
+	sets.images[location.image] = true
+}
+
+func newPrevalenceLocation(namespace *Namespace, pod *Pod, container *Container) prevalenceLocation {
+	return prevalenceLocation{
+		namespace: namespace.Name,
+		workload:  namespace.Name + "/" + pod.Name,
+		image:     container.Image.FullName(),
+	}
+}
+
+func (index *prevalenceIndex) add(kind PrevalenceKind, behavior string, location prevalenceLocation) {
+	sets, ok := index.behaviors[kind][behavior]
+	if !ok {
+		sets = newPrevalenceSets()
+		index.behaviors[kind][behavior] = sets
+	}
+	sets.add(location)
+}
+
+// addProcess records the process and its argument set.
+func (index *prevalenceIndex) addProcess(process *Process, location prevalenceLocation) {
+	index.add(PrevalenceProcess, process.Binary, location)
+	index.add(PrevalenceArguments, argumentSet(process.Binary, process.Arguments), location)
+}
+
+// addKernelBehavior records the destination of a network behaviour.
+func (index *prevalenceIndex) addKernelBehavior(behavior *KernelBehavior, location prevalenceLocation) {
+	if connection := behavior.Connection(); connection != "" {
+		index.add(PrevalenceConnection, connection, location)
+	}
+}
+
+// deviationRarity is the rarity of the argument set of the process of a deviation, or of the
+// destination it connected to when rarer.
+func (index *prevalenceIndex) deviationRarity(binary string, arguments string, connection string) float64 {
+	rarity := index.rarity(PrevalenceArguments, argumentSet(binary, arguments))
+	if connection != "" {
+		rarity = math.Max(rarity, index.rarity(PrevalenceConnection, connection))
+	}
+	return rarity
+}
+
+func (index *prevalenceIndex) addProcesses(processes map[string]*Process, location prevalenceLocation) {
+	for _, process := range processes {
+		index.addProcess(process, location)
+		for _, behavior := range process.KernelBehaviors {
+			index.addKernelBehavior(behavior, location)
+		}
+		index.addProcesses(process.ChildProcesses, location)
+	}
+}
+
+// rarity is one minus the geometric mean of the shares of the namespaces, workloads and
+// images showing the behaviour, so that a behaviour rare along any of them scores high.
+func (index *prevalenceIndex) rarity(kind PrevalenceKind, behavior string) float64 {
+	sets, ok := index.behaviors[kind][behavior]
+	if !ok {
+		return 1
+	}
+	if len(index.totals.workloads) == 0 {
+		return 0
+	}
+
+	share := float64(len(sets.namespaces)) / float64(len(index.totals.namespaces)) *
+		float64(len(sets.workloads)) / float64(len(index.totals.workloads)) *
+		float64(len(sets.images)) / float64(len(index.totals.images))
+	return 1 - math.Cbrt(share)
+}
+
+// prevalenceIndex returns the index of the cluster, building it from the profile when it
+// was never built or invalidated since. The cluster lock must be held.
+func (cluster *Cluster) prevalenceIndex() *prevalenceIndex {
+	if cluster.prevalence != nil {
+		return cluster.prevalence
+	}
+
+	index := &prevalenceIndex{
+		totals: newPrevalenceSets(),
+		behaviors: map[PrevalenceKind]map[string]*prevalenceSets{
+			PrevalenceProcess:    {},
+			PrevalenceArguments:  {},
+			PrevalenceConnection: {},
+		},
+	}
+	for _, namespace := range cluster.Namespaces {
+		for _, pod := range namespace.Pods {
+			for _, container := range pod.Containers {
+				location := newPrevalenceLocation(namespace, pod, container)
+				index.totals.add(location)
+				index.addProcesses(container.Processes, location)
+			}
+		}
+	}
+	cluster.prevalence = index
+
+	return index
+}
+
+// invalidatePrevalence drops the index once nodes were removed from the profile.
+func (cluster *Cluster) invalidatePrevalence() {
+	cluster.prevalence = nil
+}
+
+// Prevalences returns the prevalence of every process, argument set and connection of the
+// cluster, from the rarest.
+func (cluster *Cluster) Prevalences() []*Prevalence {
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+
+	index := cluster.prevalenceIndex()
+	var prevalences []*Prevalence
+	for kind, behaviors := range index.behaviors {
+		for behavior, sets := range behaviors {
+			prevalences = append(prevalences, &Prevalence{
+				Cluster:    cluster.Name,
+				Kind:       kind,
+				Behavior:   behavior,
+				Namespaces: len(sets.namespaces),
+				Workloads:  len(sets.workloads),
+				Images:     len(sets.images),
+				Rarity:     index.rarity(kind, behavior),
+			})
+		}
+	}
+	sort.Slice(prevalences, func(i, j int) bool {
+		if prevalences[i].Rarity != prevalences[j].Rarity {
+			return prevalences[i].Rarity > prevalences[j].Rarity
+		}
+		if prevalences[i].Kind != prevalences[j].Kind {
+			return prevalences[i].Kind < prevalences[j].Kind
+		}
+		return prevalences[i].Behavior < prevalences[j].Behavior
+	})
+
+	return prevalences
+}
+
+// Rarity returns the rarity of a behaviour in the cluster, see Prevalence.
+func (cluster *Cluster) Rarity(kind PrevalenceKind, behavior string) float64 {
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+
+	return cluster.prevalenceIndex().rarity(kind, behavior)
+}
+
+// ScoreDeviation sets the rarity of a deviation to the rarity of the argument set of its
+// process, or of its connection when rarer.
+func (cluster *Cluster) ScoreDeviation(deviation *Deviation) {
+	arguments := cluster.normalizeArguments(deviation.Arguments)
+
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+
+	deviation.Rarity = cluster.prevalenceIndex().deviationRarity(deviation.Binary, arguments, deviation.Connection)
+}
+
+// ScoreDeviation scores the deviation against the cluster it was reported in.
+func (registry *ClusterRegistry) ScoreDeviation(deviation *Deviation) {
+	name := deviation.Cluster
+	if name == "" {
+		name = registry.DefaultCluster
+	}
+
+	registry.mu.RLock()
+	cluster, ok := registry.Clusters[name]
+	registry.mu.RUnlock()
+	if !ok {
+		deviation.Rarity = 1
+		return
+	}
+	cluster.ScoreDeviation(deviation)
+}
+
+// indexProcess records a process inserted into the container of the event.
+func (ctx *sinkContext) indexProcess(process *Process) {
+	if ctx.cluster.prevalence != nil {
+		ctx.cluster.prevalence.addProcess(process, newPrevalenceLocation(ctx.namespace, ctx.pod, ctx.container))
+	}
+}
+
+// indexKernelBehavior records a kernel behaviour inserted into the container of the event.
+func (ctx *sinkContext) indexKernelBehavior(behavior *KernelBehavior) {
+	if ctx.cluster.prevalence != nil {
+		ctx.cluster.prevalence.addKernelBehavior(behavior, newPrevalenceLocation(ctx.namespace, ctx.pod, ctx.container))
+	}
+}
+
+// indexContainer records a container inserted into the cluster.
+func (ctx *sinkContext) indexContainer() {
+	if ctx.cluster.prevalence != nil {
+		ctx.cluster.prevalence.totals.add(newPrevalenceLocation(ctx.namespace, ctx.pod, ctx.container))
+	}
+}
+
+func argumentSet(binary string, arguments string) string {
+	if arguments == "" {
+		return binary
+	}
+	return binary + " " + arguments
+}
+
+// Connection returns the destination of a network behaviour as ranked by PrevalenceConnection,
+// e.g. IPPROTO_TCP 10.0.0.1:5432, empty for other behaviours.
+func (behavior *KernelBehavior) Connection() string {
+	network := behavior.Network
+	if network == nil || network.DestinationAddr == "" && network.DestinationPort == 0 {
+		return ""
+	}
+	destination := net.JoinHostPort(network.DestinationAddr, strconv.FormatUint(uint64(network.DestinationPort), 10))
+	if network.Protocol == "" {
+		return destination
+	}
+	return network.Protocol + " " + destination
+}
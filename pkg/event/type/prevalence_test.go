@@ -0,0 +1,113 @@
+package eventtype
+
+import (
+	"testing"
+)
+
+func TestClusterRarity(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+	mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/sbin/nginx", "", withPod("web"), withImage("nginx")))
+	mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/sbin/nginx", "", withPod("api"), withImage("nginx")))
+	mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/bin/python3", "", withPod("worker"), withImage("python")))
+	mustSink(t, cluster, newTestEvent("data", "/bin/sh", "/usr/bin/curl", "", withPod("db"), withImage("postgres")))
+
+	if rarity := cluster.Rarity(PrevalenceProcess, "/bin/sh"); rarity != 0 {
+		t.Errorf("rarity of a process of every workload = %v; want 0", rarity)
+	}
+	if rarity := cluster.Rarity(PrevalenceProcess, "/usr/bin/wget"); rarity != 1 {
+		t.Errorf("rarity of an unknown process = %v; want 1", rarity)
+	}
+	nginx := cluster.Rarity(PrevalenceArguments, "/usr/sbin/nginx")
+	curl := cluster.Rarity(PrevalenceArguments, "/usr/bin/curl")
+	if nginx <= 0 || curl <= nginx {
+		t.Errorf("rarity of nginx = %v, curl = %v; want curl rarer than nginx", nginx, curl)
+	}
+
+	// Nodes inserted once the index is built are indexed as they come.
+	mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/bin/curl", "", withPod("web"), withImage("nginx")))
+	if rarity := cluster.Rarity(PrevalenceArguments, "/usr/bin/curl"); rarity >= curl {
+		t.Errorf("rarity of curl run in a second workload = %v; want below %v", rarity, curl)
+	}
+	mustSink(t, cluster, newTestEvent("cache", "/bin/sh", "/usr/bin/redis-server", "", withPod("redis"), withImage("redis")))
+	if rarity := cluster.Rarity(PrevalenceProcess, "/bin/sh"); rarity != 0 {
+		t.Errorf("rarity of a process of every workload = %v; want 0 with a new workload", rarity)
+	}
+
+	prevalences := cluster.Prevalences()
+	if len(prevalences) == 0 || prevalences[0].Rarity < prevalences[len(prevalences)-1].Rarity {
+		t.Fatalf("prevalences = %+v; want them sorted from the rarest", prevalences)
+	}
+	for _, prevalence := range prevalences {
+		if prevalence.Kind == PrevalenceProcess && prevalence.Behavior == "/usr/sbin/nginx" &&
+			(prevalence.Namespaces != 1 || prevalence.Workloads != 2 || prevalence.Images != 1) {
+			t.Errorf("nginx prevalence = %+v; want 1 namespace, 2 workloads and 1 image", prevalence)
+		}
+	}
+}
+
+func TestClusterRarityOfConnections(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+	connect := func(pod string, address string) *testKernelEvent {
+		return newTestKernelEvent(&KernelBehavior{
+			Kind:     KernelBehaviorNetwork,
+			Function: "tcp_connect",
+			Network:  &NetworkBehavior{Family: "AF_INET", Protocol: "IPPROTO_TCP", DestinationAddr: address, DestinationPort: 5432},
+		}, withPod(pod))
+	}
+	mustSink(t, cluster, connect("web", "10.0.0.1"))
+	mustSink(t, cluster, connect("api", "10.0.0.1"))
+	mustSink(t, cluster, connect("api", "203.0.113.7"))
+
+	if rarity := cluster.Rarity(PrevalenceConnection, "IPPROTO_TCP 10.0.0.1:5432"); rarity != 0 {
+		t.Errorf("rarity of the database = %v; want 0", rarity)
+	}
+	if rarity := cluster.Rarity(PrevalenceConnection, "IPPROTO_TCP 203.0.113.7:5432"); rarity <= 0 {
+		t.Errorf("rarity of a connection of one workload = %v; want above 0", rarity)
+	}
+}
+
+func TestDeviationsAreScored(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+	baseline := LinuxNamespaces{"mnt": {Inum: 100}}
+	for _, pod := range []string{"web", "api", "worker"} {
+		mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/sbin/nginx", "", withPod(pod), withImage("nginx"), withNamespaces(baseline)))
+	}
+
+	result := mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/bin/nsenter", "", withPod("web"), withImage("nginx"),
+		withNamespaces(LinuxNamespaces{"mnt": {Inum: 2, IsHost: true}})))
+	if len(result.Deviations) != 1 {
+		t.Fatalf("deviations = %v; want the host mnt namespace", result.Deviations)
+	}
+	rarity := result.Deviations[0].Rarity
+	if rarity <= 0 || rarity >= 1 {
+		t.Errorf("deviation rarity = %v; want nsenter in one workload of three scored", rarity)
+	}
+
+	deviation := &Deviation{Binary: "/usr/sbin/nginx"}
+	cluster.ScoreDeviation(deviation)
+	if deviation.Rarity != 0 {
+		t.Errorf("rarity of a deviation of nginx = %v; want 0", deviation.Rarity)
+	}
+
+	// A common process connecting to a destination never seen is ranked by the destination.
+	deviation = &Deviation{Binary: "/usr/sbin/nginx", Connection: "IPPROTO_TCP 203.0.113.7:443"}
+	cluster.ScoreDeviation(deviation)
+	if deviation.Rarity != 1 {
+		t.Errorf("rarity of a deviation of nginx to a new destination = %v; want 1", deviation.Rarity)
+	}
+}
+
+func TestEvictionInvalidatesPrevalence(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+	cluster.Retention = &RetentionConfig{Default: RetentionPolicy{MaxChildren: 1}}
+	mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/bin/curl", "", withPod("web"), withImage("nginx")))
+	if rarity := cluster.Rarity(PrevalenceProcess, "/usr/bin/curl"); rarity != 0 {
+		t.Fatalf("rarity of curl = %v; want 0", rarity)
+	}
+
+	// The single child slot of the shell evicts curl.
+	mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/bin/wget", "", withPod("web"), withImage("nginx")))
+	if rarity := cluster.Rarity(PrevalenceProcess, "/usr/bin/curl"); rarity != 1 {
+		t.Errorf("rarity of evicted curl = %v; want 1", rarity)
+	}
+}
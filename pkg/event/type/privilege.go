@@ -0,0 +1,114 @@
+package eventtype
+
+import (
+	"fmt"
+	"time"
+)
+
+type PrivilegeTransitionKind string
+
+const (
+	PrivilegeTransitionUID    PrivilegeTransitionKind = "UID"
+	PrivilegeTransitionGID    PrivilegeTransitionKind = "GID"
+	PrivilegeTransitionSetuid PrivilegeTransitionKind = "SETUID"
+	PrivilegeTransitionSetgid PrivilegeTransitionKind = "SETGID"
+)
+
+// Credentials are the privileges a single execution of a process ran with.
+type Credentials struct {
+	UID  uint32 `json:"uid"`
+	GID  uint32 `json:"gid"`
+	EUID uint32 `json:"euid"`
+	EGID uint32 `json:"egid"`
+	// Capabilities is the effective capability set.
+	Capabilities []string `json:"capabilities,omitempty"`
+	// Setuid and Setgid are set when the executed binary is set-user-ID or set-group-ID.
+	Setuid *uint32 `json:"setuid,omitempty"`
+	Setgid *uint32 `json:"setgid,omitempty"`
+}
+
+// PrivilegeProfile aggregates the privileges observed for a profiled process.
+type PrivilegeProfile struct {
+	Capabilities map[string]int64                `json:"capabilities"`
+	Transitions  map[string]*PrivilegeTransition `json:"transitions,omitempty"`
+}
+
+// PrivilegeTransition is a change of user or group between a process and its parent,
+// or the execution of a set-user-ID or set-group-ID binary.
+type PrivilegeTransition struct {
+	Kind      PrivilegeTransitionKind `json:"kind"`
+	From      uint32                  `json:"from"`
+	To        uint32                  `json:"to"`
+	Count     int64                   `json:"count"`
+	FirstSeen time.Time               `json:"first_seen"`
+	LastSeen  time.Time               `json:"last_seen"`
+}
+
+func (transition *PrivilegeTransition) GetKey() string {
+	return key("privilege", fmt.Sprintf("%s:%d->%d", transition.Kind, transition.From, transition.To))
+}
+
+// privilegeTransitions returns the transitions from the parent credentials to the process
+// credentials. The parent credentials may be nil when the parent is unknown.
+func privilegeTransitions(parent *Credentials, process *Credentials) []*PrivilegeTransition {
+	var transitions []*PrivilegeTransition
+
+	if parent != nil && parent.EUID != process.EUID {
+		transitions = append(transitions, &PrivilegeTransition{Kind: PrivilegeTransitionUID, From: parent.EUID, To: process.EUID})
+	}
+	if parent != nil && parent.EGID != process.EGID {
+		transitions = append(transitions, &PrivilegeTransition{Kind: PrivilegeTransitionGID, From: parent.EGID, To: process.EGID})
+	}
+	if process.Setuid != nil {
+		transitions = append(transitions, &PrivilegeTransition{Kind: PrivilegeTransitionSetuid, From: process.UID, To: *process.Setuid})
+	}
+	if process.Setgid != nil {
+		transitions = append(transitions, &PrivilegeTransition{Kind: PrivilegeTransitionSetgid, From: process.GID, To: *process.Setgid})
+	}
+
+	return transitions
+}
+
+// sinkPrivileges records the effective capabilities and privilege transitions of an
+// execution of the process and flags capabilities and transitions that were never seen while learning.
+func (ctx *sinkContext) sinkPrivileges(process *Process, processRaw *Process, parentRaw *Process) {
+	credentials := processRaw.Credentials
+	if credentials == nil {
+		return
+	}
+
+	if process.Privileges == nil {
+		process.Privileges = &PrivilegeProfile{
+			Capabilities: map[string]int64{},
+			Transitions:  map[string]*PrivilegeTransition{},
+		}
+	}
+	privileges := process.Privileges
+
+	for _, capability := range credentials.Capabilities {
+		if privileges.Capabilities[capability] == 0 {
+			ctx.deviate(process, DeviationNewCapability, capability,
+				fmt.Sprintf("%s gained %s which was never seen during learning", process.Binary, capability))
+		}
+		privileges.Capabilities[capability]++
+	}
+
+	var parentCredentials *Credentials
+	if parentRaw != nil {
+		parentCredentials = parentRaw.Credentials
+	}
+
+	for _, raw := range privilegeTransitions(parentCredentials, credentials) {
+		transitionKey := raw.GetKey()
+		transition, ok := privileges.Transitions[transitionKey]
+		if !ok {
+			transition = raw
+			transition.FirstSeen = ctx.now
+			ctx.deviate(process, DeviationNewPrivilegeTransition, transitionKey,
+				fmt.Sprintf("%s made a %s transition from %d to %d which was never seen during learning", process.Binary, raw.Kind, raw.From, raw.To))
+			privileges.Transitions[transitionKey] = transition
+		}
+		transition.Count++
+		transition.LastSeen = ctx.now
+	}
+}
@@ -0,0 +1,81 @@
+package eventtype
+
+import (
+	"runtime-behavior-profiler/pkg/util"
+	"testing"
+	"time"
+)
+
+func TestSinkPrivileges(t *testing.T) {
+	clock := util.NewFakeClock(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC))
+	cluster := NewCluster("test-cluster")
+	cluster.Clock = clock
+	cluster.LearningPeriod = time.Hour
+
+	user := &Credentials{UID: 1000, GID: 1000, EUID: 1000, EGID: 1000}
+	root := uint32(0)
+
+	mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/bin/sudo", "", withCredentials(user, &Credentials{
+		UID: 1000, GID: 1000, EUID: 0, EGID: 1000,
+		Capabilities: []string{"CAP_SETUID"},
+		Setuid:       &root,
+	})))
+	clock.Advance(2 * time.Hour)
+
+	result := mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/bin/sudo", "", withCredentials(user, &Credentials{
+		UID: 1000, GID: 1000, EUID: 0, EGID: 1000,
+		Capabilities: []string{"CAP_SETUID"},
+		Setuid:       &root,
+	})))
+	if len(result.Deviations) != 0 {
+		t.Errorf("learned privileges reported: %v", result.Deviations)
+	}
+
+	result = mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/bin/sudo", "", withCredentials(user, &Credentials{
+		UID: 1000, GID: 1000, EUID: 0, EGID: 0,
+		Capabilities: []string{"CAP_SETUID", "CAP_SYS_ADMIN"},
+		Setuid:       &root,
+	})))
+
+	deviations := map[DeviationType]string{}
+	for _, deviation := range result.Deviations {
+		deviations[deviation.Type] = deviation.Value
+	}
+	if len(result.Deviations) != 2 || deviations[DeviationNewCapability] != "CAP_SYS_ADMIN" || deviations[DeviationNewPrivilegeTransition] != "privilege:GID:1000->0" {
+		t.Errorf("deviations = %v; want CAP_SYS_ADMIN and a GID transition", deviations)
+	}
+
+	sudo := cluster.Namespaces["namespace:default"].Pods["pod:nginx"].Containers["container:nginx"].
+		Processes[(&Process{Binary: "/bin/sh"}).GetKey()].ChildProcesses[(&Process{Binary: "/usr/bin/sudo"}).GetKey()]
+	if len(sudo.Privileges.Transitions) != 3 || sudo.Privileges.Capabilities["CAP_SETUID"] != 3 {
+		t.Errorf("privileges = %+v; want UID, GID and SETUID transitions", sudo.Privileges)
+	}
+}
+
+func TestSinkPrivilegesCountsExecutions(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+
+	user := &Credentials{UID: 1000, GID: 1000, EUID: 1000, EGID: 1000}
+	root := &Credentials{UID: 1000, GID: 1000, EUID: 0, EGID: 1000, Capabilities: []string{"CAP_SETUID"}}
+	exec := func(execID string) *testEvent {
+		event := newTestEvent("default", "/bin/sh", "/usr/bin/sudo", "", withCredentials(user, root))
+		event.process.ExecID = execID
+		return event
+	}
+
+	mustSink(t, cluster, exec("sudo-1"))
+	// Later events of the same execution carry its credentials again.
+	mustSink(t, cluster, newTestKernelEvent(&KernelBehavior{Kind: KernelBehaviorFile, Function: "security_file_open", File: &FileBehavior{Path: "/etc/shadow"}},
+		withLineage(exec("sudo-1").process, exec("sudo-1").parent)))
+	mustSink(t, cluster, exec("sudo-1"))
+	mustSink(t, cluster, exec("sudo-2"))
+
+	sudo := cluster.Namespaces["namespace:default"].Pods["pod:nginx"].Containers["container:nginx"].
+		Processes[(&Process{Binary: "/bin/sh"}).GetKey()].ChildProcesses[(&Process{Binary: "/usr/bin/sudo"}).GetKey()]
+	if count := sudo.Privileges.Capabilities["CAP_SETUID"]; count != 2 {
+		t.Errorf("CAP_SETUID counted %d times; want once per execution", count)
+	}
+	if transition := sudo.Privileges.Transitions["privilege:UID:1000->0"]; transition == nil || transition.Count != 2 {
+		t.Errorf("UID transition = %+v; want it counted once per execution", transition)
+	}
+}
@@ -0,0 +1,153 @@
+package eventtype
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// shortRateAlpha and longRateAlpha weight the last minute in the moving averages of
+	// Rate, about the last five minutes and the last hour.
+	shortRateAlpha = 2.0 / (5 + 1)
+	longRateAlpha  = 2.0 / (60 + 1)
+)
+
+// connectFunctions are the kernel functions of outbound connections.
+var connectFunctions = map[string]bool{
+	"tcp_connect":             true,
+	"sys_connect":             true,
+	"security_socket_connect": true,
+}
+
+// Rate is the frequency of the events of a profile node, maintained from the time of the
+// events rather than the time they were received.
+type Rate struct {
+	// Minute is the start of the current minute and MinuteCount the events seen within it.
+	Minute      time.Time `json:"minute"`
+	MinuteCount int64     `json:"minute_count"`
+	// Minutes is the number of minutes elapsed between the first event and Minute.
+	Minutes int64 `json:"minutes"`
+	// ShortAverage and LongAverage are moving averages of the events per minute, over about
+	// the last five minutes and the last hour.
+	ShortAverage float64 `json:"short_average"`
+	LongAverage  float64 `json:"long_average"`
+	// Peak is the largest number of events seen within a past minute.
+	Peak int64 `json:"peak"`
+	// Hours is the time of day histogram of the events, by UTC hour.
+	Hours [24]int64 `json:"hours"`
+
+	// anomalyMinute is the last minute a frequency anomaly was reported in.
+	anomalyMinute time.Time
+}
+
+// FrequencyConfig controls the detection of frequency anomalies, a minute holding more
+// events than the node ever had in a minute and Factor times its long average.
+type FrequencyConfig struct {
+	// MinHistory is how long a node is observed before its frequency is checked.
+	MinHistory time.Duration
+	// Factor is how many times its long average a minute must exceed.
+	Factor float64
+	// MinEvents is the number of events within a minute below which nothing is reported.
+	MinEvents int64
+}
+
+// DefaultFrequencyConfig returns the frequency configuration of clusters created with NewCluster.
+func DefaultFrequencyConfig() *FrequencyConfig {
+	return &FrequencyConfig{
+		MinHistory: time.Hour,
+		Factor:     10,
+		MinEvents:  10,
+	}
+}
+
+// record counts an event at the given time, the rate is created on first use. Events older
+// than the current minute only count in the histogram.
+func (rate *Rate) record(at time.Time) *Rate {
+	minute := at.Truncate(time.Minute)
+	if rate == nil {
+		rate = &Rate{Minute: minute}
+	}
+	rate.Hours[at.UTC().Hour()]++
+
+	if minute.After(rate.Minute) {
+		rate.closeMinutes(int64(minute.Sub(rate.Minute) / time.Minute))
+		rate.Minute = minute
+		rate.MinuteCount = 0
+	}
+	if !minute.Before(rate.Minute) {
+		rate.MinuteCount++
+	}
+
+	return rate
+}
+
+// closeMinutes folds the current minute and the empty minutes that followed it into the
+// moving averages.
+func (rate *Rate) closeMinutes(minutes int64) {
+	count := float64(rate.MinuteCount)
+	rate.ShortAverage += shortRateAlpha * (count - rate.ShortAverage)
+	rate.LongAverage += longRateAlpha * (count - rate.LongAverage)
+	rate.ShortAverage *= math.Pow(1-shortRateAlpha, float64(minutes-1))
+	rate.LongAverage *= math.Pow(1-longRateAlpha, float64(minutes-1))
+
+	if rate.MinuteCount > rate.Peak {
+		rate.Peak = rate.MinuteCount
+	}
+	rate.Minutes += minutes
+}
+
+// anomalous reports whether the current minute is a frequency anomaly, at most once per minute.
+func (rate *Rate) anomalous(config *FrequencyConfig) bool {
+	if config == nil || time.Duration(rate.Minutes)*time.Minute < config.MinHistory || rate.anomalyMinute.Equal(rate.Minute) {
+		return false
+	}
+	if rate.MinuteCount < config.MinEvents || rate.MinuteCount <= rate.Peak || float64(rate.MinuteCount) <= config.Factor*rate.LongAverage {
+		return false
+	}
+
+	rate.anomalyMinute = rate.Minute
+	return true
+}
+
+// eventTime returns the time of the event, or the cluster time when the source does not
+// report it.
+func (ctx *sinkContext) eventTime() time.Time {
+	if ctx.source == nil || ctx.source.Time.IsZero() {
+		return ctx.now
+	}
+	return ctx.source.Time
+}
+
+// sinkExecution records a new execution of the process.
+func (ctx *sinkContext) sinkExecution(process *Process) {
+	process.Executions = process.Executions.record(ctx.eventTime())
+	if process.Executions.anomalous(ctx.cluster.Frequency) {
+		ctx.deviate(process, DeviationFrequencyAnomaly, "executions",
+			fmt.Sprintf("%s ran %d times within a minute, %.2f times per minute on average",
+				process.Binary, process.Executions.MinuteCount, process.Executions.LongAverage))
+	}
+}
+
+// sinkBehaviorRate records a kernel behaviour of the process, outbound connections are also
+// counted across destinations.
+func (ctx *sinkContext) sinkBehaviorRate(process *Process, behavior *KernelBehavior, behaviorKey string) {
+	at := ctx.eventTime()
+
+	behavior.Rate = behavior.Rate.record(at)
+	if behavior.Rate.anomalous(ctx.cluster.Frequency) {
+		ctx.deviate(process, DeviationFrequencyAnomaly, behaviorKey,
+			fmt.Sprintf("%s called %s %d times within a minute, %.2f times per minute on average",
+				process.Binary, behavior.Function, behavior.Rate.MinuteCount, behavior.Rate.LongAverage))
+	}
+
+	if behavior.Kind != KernelBehaviorNetwork || !connectFunctions[NormalizeFunctionName(behavior.Function)] {
+		return
+	}
+	process.Connections = process.Connections.record(at)
+	if process.Connections.anomalous(ctx.cluster.Frequency) {
+		ctx.deviate(process, DeviationFrequencyAnomaly, "connections",
+			fmt.Sprintf("%s opened %d outbound connections within a minute, %.2f per minute on average",
+				process.Binary, process.Connections.MinuteCount, process.Connections.LongAverage))
+	}
+}
@@ -0,0 +1,138 @@
+package eventtype
+
+import (
+	"runtime-behavior-profiler/pkg/util"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateRecord(t *testing.T) {
+	start := time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC)
+
+	var rate *Rate
+	for i := 0; i < 6; i++ {
+		rate = rate.record(start.Add(time.Duration(i) * 10 * time.Second))
+	}
+	if rate.MinuteCount != 6 || rate.Minutes != 0 || rate.LongAverage != 0 {
+		t.Errorf("rate = %+v; want 6 events in the first minute", rate)
+	}
+
+	rate = rate.record(start.Add(time.Minute))
+	if rate.MinuteCount != 1 || rate.Minutes != 1 || rate.Peak != 6 || rate.ShortAverage != 2 {
+		t.Errorf("rate = %+v; want the first minute folded into the averages", rate)
+	}
+	short, long := rate.ShortAverage, rate.LongAverage
+
+	// Idle minutes decay the averages.
+	rate = rate.record(start.Add(2*time.Hour + 30*time.Second))
+	if rate.Minutes != 120 || rate.ShortAverage >= short || rate.LongAverage >= long || rate.MinuteCount != 1 {
+		t.Errorf("rate = %+v; want the averages decayed over two hours", rate)
+	}
+
+	// Late events only count in the histogram.
+	rate = rate.record(start.Add(time.Hour))
+	if rate.MinuteCount != 1 || rate.Hours[10] != 7 || rate.Hours[11] != 1 || rate.Hours[12] != 1 {
+		t.Errorf("rate = %+v; want the late event in the histogram only", rate)
+	}
+}
+
+func TestRateAnomalous(t *testing.T) {
+	config := &FrequencyConfig{MinHistory: time.Hour, Factor: 10, MinEvents: 5}
+	start := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	// An hourly cron job running every second after two hours.
+	var rate *Rate
+	for hour := 0; hour < 3; hour++ {
+		rate = rate.record(start.Add(time.Duration(hour) * time.Hour))
+		if rate.anomalous(config) {
+			t.Fatalf("hourly run %d reported as anomalous: %+v", hour, rate)
+		}
+	}
+
+	var reported []int
+	for second := 1; second <= 10; second++ {
+		rate = rate.record(start.Add(2*time.Hour + time.Duration(second)*time.Second))
+		if rate.anomalous(config) {
+			reported = append(reported, second)
+		}
+	}
+	if len(reported) != 1 || reported[0] != 4 {
+		t.Errorf("anomalies reported at runs %v; want once, when the minute reaches 5 events", reported)
+	}
+
+	if rate.anomalous(nil) {
+		t.Error("anomaly reported without a frequency configuration")
+	}
+}
+
+func TestSinkEventDetectsFrequencyAnomalies(t *testing.T) {
+	clock := util.NewFakeClock(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC))
+	cluster := NewCluster("test-cluster")
+	cluster.Clock = clock
+	cluster.LearningPeriod = time.Hour
+
+	connect := func(port uint32) *testKernelEvent {
+		event := newTestKernelEvent(&KernelBehavior{
+			Kind:     KernelBehaviorNetwork,
+			Function: "tcp_connect",
+			Network:  &NetworkBehavior{Family: "AF_INET", Protocol: "IPPROTO_TCP", DestinationAddr: "10.0.0.1", DestinationPort: port},
+		}, withSource(&EventSource{Time: clock.Now()}))
+		event.process.ExecID = "app-1"
+		return event
+	}
+
+	// A connection every five minutes for two hours.
+	for i := 0; i < 24; i++ {
+		result := mustSink(t, cluster, connect(5432))
+		if len(result.Deviations) != 0 {
+			t.Fatalf("baseline connection %d reported: %v", i, result.Deviations)
+		}
+		clock.Advance(5 * time.Minute)
+	}
+
+	// A scan of ten ports within a minute.
+	var deviations []*Deviation
+	for port := uint32(1); port <= 10; port++ {
+		deviations = append(deviations, mustSink(t, cluster, connect(port)).Deviations...)
+		clock.Advance(time.Second)
+	}
+	if len(deviations) != 1 || deviations[0].Type != DeviationFrequencyAnomaly || deviations[0].Value != "connections" {
+		t.Fatalf("deviations = %v; want a connection frequency anomaly", deviations)
+	}
+	// The anomaly is scored by the connection that triggered it.
+	if !strings.HasPrefix(deviations[0].Connection, "IPPROTO_TCP 10.0.0.1:") {
+		t.Errorf("deviation connection = %q; want a destination of the scan", deviations[0].Connection)
+	}
+
+	app := cluster.Namespaces["namespace:default"].Pods["pod:nginx"].Containers["container:nginx"].
+		Processes[(&Process{Binary: "/bin/sh"}).GetKey()].
+		ChildProcesses[(&Process{Binary: "/usr/bin/app"}).GetKey()]
+	if app.Connections == nil || app.Connections.MinuteCount != 10 || app.Connections.Minutes != 120 {
+		t.Errorf("connections = %+v; want 10 in the current minute after two hours", app.Connections)
+	}
+	if app.Executions == nil || app.Executions.MinuteCount != 1 || app.Executions.Minutes != 0 {
+		t.Errorf("executions = %+v; want a single execution", app.Executions)
+	}
+	behavior := app.KernelBehaviors[connect(5432).behavior.GetKey()]
+	if behavior.Rate == nil || behavior.Rate.Hours[0]+behavior.Rate.Hours[1] != 24 {
+		t.Errorf("rate = %+v; want 24 connections to the database", behavior.Rate)
+	}
+}
+
+func TestSinkEventUsesEventTime(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+	event := newTestEvent("default", "/bin/sh", "/usr/bin/backup", "", withSource(&EventSource{Time: time.Date(2024, 12, 1, 3, 0, 0, 0, time.UTC)}))
+	event.process.ExecID = "backup-1"
+	mustSink(t, cluster, event)
+
+	// Another event of the same execution is not another execution.
+	mustSink(t, cluster, event)
+
+	backup := cluster.Namespaces["namespace:default"].Pods["pod:nginx"].Containers["container:nginx"].
+		Processes[(&Process{Binary: "/bin/sh"}).GetKey()].
+		ChildProcesses[(&Process{Binary: "/usr/bin/backup"}).GetKey()]
+	if backup.Executions == nil || backup.Executions.Hours[3] != 1 || !backup.Executions.Minute.Equal(event.source.Time) {
+		t.Errorf("executions = %+v; want one execution at 03:00", backup.Executions)
+	}
+}
@@ -0,0 +1,222 @@
+package eventtype
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// RetentionPolicy describes how long behaviour is kept in the profile and how large it may grow.
+type RetentionPolicy struct {
+	// TTL is how long a process may go unseen before compaction drops it.
+	// A zero TTL disables expiry.
+	TTL time.Duration `json:"ttl"`
+	// MaxChildren caps the number of processes kept under a single node. When the cap is
+	// reached the least recently seen process is evicted. Zero means unlimited.
+	MaxChildren int `json:"max_children"`
+	// ExecTTL is how long an exec id may go unseen before compaction drops it from the
+	// exec id index. A zero ExecTTL keeps exec ids until their process exits.
+	ExecTTL time.Duration `json:"exec_ttl"`
+}
+
+// RetentionConfig holds the default retention policy and per namespace overrides.
+type RetentionConfig struct {
+	Default    RetentionPolicy            `json:"default"`
+	Namespaces map[string]RetentionPolicy `json:"namespaces"`
+}
+
+// CompactResult lists the profile nodes removed by a compaction run.
+type CompactResult struct {
+	Removed []string `json:"removed"`
+	// ExpiredExecs is the number of exec ids dropped from the exec id indexes.
+	ExpiredExecs int `json:"expired_execs"`
+}
+
+// DefaultRetentionConfig returns the retention configuration used when none is provided.
+func DefaultRetentionConfig() *RetentionConfig {
+	return &RetentionConfig{
+		Default: RetentionPolicy{
+			TTL:         7 * 24 * time.Hour,
+			MaxChildren: 1024,
+			ExecTTL:     time.Hour,
+		},
+		Namespaces: map[string]RetentionPolicy{},
+	}
+}
+
+// ParseRetentionConfig parses a JSON retention configuration whose TTLs are durations such
+// as "168h", e.g. {"default": {"ttl": "168h", "max_children": 1024, "exec_ttl": "1h"}, "namespaces":
+// {"batch": {"ttl": "1h"}}}. The default policy falls back to DefaultRetentionConfig when
+// omitted, a namespace override replaces it entirely.
+func ParseRetentionConfig(data []byte) (*RetentionConfig, error) {
+	type policyJSON struct {
+		TTL         string `json:"ttl"`
+		MaxChildren int    `json:"max_children"`
+		ExecTTL     string `json:"exec_ttl"`
+	}
+	var raw struct {
+		Default    *policyJSON           `json:"default"`
+		Namespaces map[string]policyJSON `json:"namespaces"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	parse := func(name string, raw policyJSON) (RetentionPolicy, error) {
+		policy := RetentionPolicy{MaxChildren: raw.MaxChildren}
+		for _, duration := range []struct {
+			field string
+			raw   string
+			value *time.Duration
+		}{
+			{"ttl", raw.TTL, &policy.TTL},
+			{"exec_ttl", raw.ExecTTL, &policy.ExecTTL},
+		} {
+			if duration.raw == "" {
+				continue
+			}
+			value, err := time.ParseDuration(duration.raw)
+			if err != nil {
+				return policy, fmt.Errorf("invalid %s of %s: %w", duration.field, name, err)
+			}
+			*duration.value = value
+		}
+		return policy, nil
+	}
+
+	config := DefaultRetentionConfig()
+	if raw.Default != nil {
+		policy, err := parse("the default policy", *raw.Default)
+		if err != nil {
+			return nil, err
+		}
+		config.Default = policy
+	}
+	for namespace, rawPolicy := range raw.Namespaces {
+		policy, err := parse("namespace "+namespace, rawPolicy)
+		if err != nil {
+			return nil, err
+		}
+		config.Namespaces[namespace] = policy
+	}
+
+	return config, nil
+}
+
+// PolicyFor returns the retention policy applying to the given namespace.
+// A nil RetentionConfig yields an empty policy which retains everything.
+func (config *RetentionConfig) PolicyFor(namespace string) RetentionPolicy {
+	if config == nil {
+		return RetentionPolicy{}
+	}
+	if policy, ok := config.Namespaces[namespace]; ok {
+		return policy
+	}
+	return config.Default
+}
+
+// Compact drops every process that has not been seen within the TTL of its namespace
+// and has no recently seen descendants, then removes containers, pods and namespaces
+// left empty. Exec ids not seen within the ExecTTL of their namespace are forgotten.
+func (cluster *Cluster) Compact() *CompactResult {
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+
+	result := &CompactResult{
+		Removed: []string{},
+	}
+	now := cluster.now()
+
+	for namespaceKey, namespace := range cluster.Namespaces {
+		policy := cluster.Retention.PolicyFor(namespace.Name)
+		if policy.ExecTTL > 0 {
+			for _, pod := range namespace.Pods {
+				for _, container := range pod.Containers {
+					result.ExpiredExecs += container.expireExecs(now.Add(-policy.ExecTTL))
+				}
+			}
+		}
+		if policy.TTL <= 0 {
+			continue
+		}
+		cutoff := now.Add(-policy.TTL)
+
+		for podKey, pod := range namespace.Pods {
+			for containerKey, container := range pod.Containers {
+				removed := map[*Process]bool{}
+				result.compactProcesses(container.Processes, cutoff, removed)
+				container.forgetExecs(removed)
+
+				if len(container.Processes) == 0 {
+					delete(pod.Containers, containerKey)
+					result.Removed = append(result.Removed, containerKey)
+				}
+			}
+			if len(pod.Containers) == 0 {
+				delete(namespace.Pods, podKey)
+				result.Removed = append(result.Removed, podKey)
+			}
+		}
+		if len(namespace.Pods) == 0 {
+			delete(cluster.Namespaces, namespaceKey)
+			result.Removed = append(result.Removed, namespaceKey)
+		}
+	}
+	if len(result.Removed) > 0 {
+		cluster.invalidatePrevalence()
+	}
+
+	return result
+}
+
+// StartCompaction runs Compact every interval in the background until ctx is done.
+func (cluster *Cluster) StartCompaction(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				result := cluster.Compact()
+				if len(result.Removed) > 0 {
+					log.Printf("Compaction removed %d profile nodes", len(result.Removed))
+				}
+			}
+		}
+	}()
+}
+
+// compactProcesses removes the processes last seen before cutoff whose descendants
+// were all removed as well, recording every removed node.
+func (result *CompactResult) compactProcesses(processes map[string]*Process, cutoff time.Time, removed map[*Process]bool) {
+	for key, process := range processes {
+		result.compactProcesses(process.ChildProcesses, cutoff, removed)
+
+		if process.LastSeen.Before(cutoff) && len(process.ChildProcesses) == 0 {
+			delete(processes, key)
+			removed[process] = true
+			result.Removed = append(result.Removed, key)
+		}
+	}
+}
+
+// evictLeastRecentlySeen removes the least recently seen process from processes and
+// returns it with its key, or an empty key and nil when there is nothing to evict.
+func evictLeastRecentlySeen(processes map[string]*Process) (string, *Process) {
+	var oldestKey string
+	var oldest *Process
+	for key, process := range processes {
+		if oldest == nil || process.LastSeen.Before(oldest.LastSeen) {
+			oldestKey, oldest = key, process
+		}
+	}
+	if oldest != nil {
+		delete(processes, oldestKey)
+	}
+	return oldestKey, oldest
+}
@@ -0,0 +1,134 @@
+package eventtype
+
+import (
+	"runtime-behavior-profiler/pkg/util"
+	"testing"
+	"time"
+)
+
+func newRetentionTestCluster(clock util.Clock, retention *RetentionConfig) *Cluster {
+	cluster := NewCluster("test-cluster")
+	cluster.Clock = clock
+	cluster.Retention = retention
+	return cluster
+}
+
+func countProcesses(cluster *Cluster) int {
+	count := 0
+	var walk func(processes map[string]*Process)
+	walk = func(processes map[string]*Process) {
+		for _, process := range processes {
+			count++
+			walk(process.ChildProcesses)
+		}
+	}
+	for _, namespace := range cluster.Namespaces {
+		for _, pod := range namespace.Pods {
+			for _, container := range pod.Containers {
+				walk(container.Processes)
+			}
+		}
+	}
+	return count
+}
+
+func TestParseRetentionConfig(t *testing.T) {
+	config, err := ParseRetentionConfig([]byte(`{"namespaces": {"batch": {"ttl": "1h", "max_children": 8}}}`))
+	if err != nil {
+		t.Fatalf("ParseRetentionConfig() error = %v", err)
+	}
+	if config.Default != DefaultRetentionConfig().Default {
+		t.Errorf("default policy = %+v; want the default retention policy", config.Default)
+	}
+	if policy := config.PolicyFor("batch"); policy.TTL != time.Hour || policy.MaxChildren != 8 {
+		t.Errorf("batch policy = %+v; want a 1h TTL and 8 children", policy)
+	}
+
+	if _, err := ParseRetentionConfig([]byte(`{"default": {"ttl": "a week"}}`)); err == nil {
+		t.Error("ParseRetentionConfig() accepted an invalid TTL")
+	}
+}
+
+func TestCompactDropsExpiredProcesses(t *testing.T) {
+	clock := util.NewFakeClock(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC))
+	cluster := newRetentionTestCluster(clock, &RetentionConfig{
+		Default: RetentionPolicy{TTL: time.Hour},
+	})
+
+	mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/bin/sleep", "alpha"))
+	clock.Advance(45 * time.Minute)
+	mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/bin/sleep", "beta"))
+	clock.Advance(30 * time.Minute)
+
+	result := cluster.Compact()
+	if len(result.Removed) != 1 {
+		t.Fatalf("compaction removed %v; want only the expired process", result.Removed)
+	}
+	if got := countProcesses(cluster); got != 2 {
+		t.Errorf("%d processes left after compaction; want 2", got)
+	}
+
+	clock.Advance(time.Hour)
+	cluster.Compact()
+	if len(cluster.Namespaces) != 0 {
+		t.Errorf("%d namespaces left after everything expired; want 0", len(cluster.Namespaces))
+	}
+}
+
+func TestCompactHonoursNamespacePolicy(t *testing.T) {
+	clock := util.NewFakeClock(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC))
+	cluster := newRetentionTestCluster(clock, &RetentionConfig{
+		Default: RetentionPolicy{TTL: time.Hour},
+		Namespaces: map[string]RetentionPolicy{
+			"kube-system": {},
+		},
+	})
+
+	mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/bin/ls", ""))
+	mustSink(t, cluster, newTestEvent("kube-system", "/bin/sh", "/bin/ls", ""))
+	clock.Advance(2 * time.Hour)
+
+	cluster.Compact()
+	if _, ok := cluster.Namespaces["namespace:default"]; ok {
+		t.Errorf("namespace default should have expired")
+	}
+	if _, ok := cluster.Namespaces["namespace:kube-system"]; !ok {
+		t.Errorf("namespace kube-system has no TTL and should be retained")
+	}
+}
+
+func TestSinkEvictsLeastRecentlySeenChild(t *testing.T) {
+	clock := util.NewFakeClock(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC))
+	cluster := newRetentionTestCluster(clock, &RetentionConfig{
+		Default: RetentionPolicy{MaxChildren: 2},
+	})
+
+	mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/bin/sleep", "alpha"))
+	clock.Advance(time.Minute)
+	mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/bin/sleep", "beta"))
+	clock.Advance(time.Minute)
+	// Touch the oldest child so that "sleep beta" becomes the least recently seen.
+	mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/bin/sleep", "alpha"))
+	clock.Advance(time.Minute)
+
+	result := mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/bin/sleep", "gamma"))
+
+	evicted := (&Process{Binary: "/bin/sleep", Arguments: "beta"}).GetKey()
+	if len(result.Evicted) != 1 || result.Evicted[0] != evicted {
+		t.Fatalf("evicted %v; want [%s]", result.Evicted, evicted)
+	}
+	if got := countProcesses(cluster); got != 3 {
+		t.Errorf("%d processes in profile; want parent and 2 children", got)
+	}
+}
+
+func mustSink(t *testing.T, cluster interface {
+	SinkEvent(IEvent) (*SinkResult, error)
+}, event IEvent) *SinkResult {
+	t.Helper()
+	result, err := cluster.SinkEvent(event)
+	if err != nil {
+		t.Fatalf("failed to sink event: %v", err)
+	}
+	return result
+}
@@ -1,6 +1,7 @@
 package eventtype
 
 import (
+	"encoding/json"
 	"log"
 	"runtime-behavior-profiler/pkg/util"
 	"time"
@@ -32,6 +33,11 @@ func (cluster *Cluster) SinkEvent(rawEvent IEvent) (*SinkResult, error) {
 
 	startTime := time.Now()
 
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+
+	now := cluster.now()
+
 	sinkResult := SinkResult{
 		Operation: SinkOperationUpdated,
 		Path:      []string{},
@@ -55,6 +61,8 @@ func (cluster *Cluster) SinkEvent(rawEvent IEvent) (*SinkResult, error) {
 		cluster.Namespaces[namespaceKey] = namespace
 	}
 
+	policy := cluster.Retention.PolicyFor(namespace.Name)
+
 	// Pod
 	podRaw, err := rawEvent.GetPod()
 	if err != nil {
@@ -79,34 +87,23 @@ func (cluster *Cluster) SinkEvent(rawEvent IEvent) (*SinkResult, error) {
 	}
 	containerKey := containerRaw.GetKey()
 
-	container, ok := pod.Containers[containerKey]
-	if !ok {
+	container, containerFound := pod.Containers[containerKey]
+	if !containerFound {
 		container = &Container{
 			Name:      containerRaw.Name,
 			Image:     newImage(util.ExtractImageParts(containerRaw.Image.Repo)),
 			Processes: map[string]*Process{},
+			FirstSeen: now,
 		}
 		sinkResult.Inserted(container.GetKey())
 		pod.Containers[containerKey] = container
 	}
 
-	// Parent
-	parentRaw, err := rawEvent.GetParentProcess()
+	// Process lineage
+	lineage, err := getLineage(rawEvent)
 	if err != nil {
 		return nil, err
 	}
-	parentRawKey := parentRaw.GetKey()
-
-	parent, ok := container.Processes[parentRawKey]
-	if !ok {
-		parent = &Process{
-			Binary:         parentRaw.Binary,
-			Arguments:      parentRaw.Arguments,
-			ChildProcesses: map[string]*Process{},
-		}
-		sinkResult.Inserted(parent.GetKey())
-		container.Processes[parentRawKey] = parent
-	}
 
 	// Process
 	processRaw, err := rawEvent.GetProcess()
@@ -114,25 +111,118 @@ func (cluster *Cluster) SinkEvent(rawEvent IEvent) (*SinkResult, error) {
 		return nil, err
 	}
 
-	processRawKey := processRaw.GetKey()
+	source, err := rawEvent.GetSource()
+	if err != nil {
+		return nil, err
+	}
 
-	process, ok := parent.ChildProcesses[processRawKey]
-	if !ok {
-		process = &Process{
-			Binary:         processRaw.Binary,
-			Arguments:      processRaw.Arguments,
-			ChildProcesses: map[string]*Process{},
-		}
-		sinkResult.Inserted(process.GetKey())
-		parent.ChildProcesses[processRawKey] = process
+	sinkCtx := &sinkContext{
+		cluster:   cluster,
+		result:    &sinkResult,
+		namespace: namespace,
+		pod:       pod,
+		container: container,
+		policy:    policy,
+		source:    source,
+		now:       now,
+	}
+	if !containerFound {
+		sinkCtx.indexContainer()
+	}
+
+	// An exec id already known is another event of the same execution, without an exec id
+	// only an exec event tells a new execution.
+	execution := container.lookupExec(processRaw.ExecID) == nil && (processRaw.ExecID != "" || isExecEvent(rawEvent))
+	process := sinkCtx.sinkLineage(lineage, processRaw)
+	process.Provenance = process.Provenance.record(source)
+	if execution {
+		sinkCtx.sinkExecution(process)
+		sinkCtx.sinkPrivileges(process, processRaw, lineage[len(lineage)-1])
 	}
+	sinkCtx.sinkNamespaces(process, processRaw)
 
-	util.Noop(process)
+	// Behaviour
+	err = sinkCtx.sinkBehavior(rawEvent, process)
+	if err != nil {
+		return nil, err
+	}
 
 	elapsedTime := time.Since(startTime)
 	log.Printf("Add function took %s", elapsedTime)
 
-	return nil, nil
+	return &sinkResult, nil
+}
+
+// sinkContext carries the profile nodes an event resolved to while it is being sunk.
+type sinkContext struct {
+	cluster   *Cluster
+	result    *SinkResult
+	namespace *Namespace
+	pod       *Pod
+	container *Container
+	policy    RetentionPolicy
+	source    *EventSource
+	now       time.Time
+	// behavior is the kernel behaviour of the event, nil for other events.
+	behavior *KernelBehavior
+}
+
+// newProcessNode builds the profile node for a raw process, keyed on its normalized arguments.
+func (cluster *Cluster) newProcessNode(raw *Process, now time.Time) *Process {
+	return &Process{
+		Binary:         raw.Binary,
+		Arguments:      cluster.normalizeArguments(raw.Arguments),
+		ChildProcesses: map[string]*Process{},
+		FirstSeen:      now,
+	}
+}
+
+// now returns the current time according to the cluster clock.
+func (cluster *Cluster) now() time.Time {
+	if cluster.Clock == nil {
+		return time.Now()
+	}
+	return cluster.Clock.Now()
+}
+
+// NewCluster returns an empty Cluster profile with the default retention configuration
+// and learning period.
+func NewCluster(name string) *Cluster {
+	return &Cluster{
+		Name:           name,
+		Namespaces:     map[string]*Namespace{},
+		Clock:          util.RealClock(),
+		Normalizer:     DefaultArgumentNormalizer(),
+		Retention:      DefaultRetentionConfig(),
+		LearningPeriod: DefaultLearningPeriod,
+		Attack:         DefaultAttackMapping(),
+		Frequency:      DefaultFrequencyConfig(),
+	}
+}
+
+// MarshalJSON serializes the cluster while holding its read lock so that the profile
+// can be dumped while events are being sunk or compaction is running.
+func (cluster *Cluster) MarshalJSON() ([]byte, error) {
+	cluster.mu.RLock()
+	defer cluster.mu.RUnlock()
+
+	type clusterJSON Cluster
+	return json.Marshal((*clusterJSON)(cluster))
+}
+
+// ForEachContainer calls fn for every container of the cluster while holding its read lock,
+// fn must not sink events into the cluster.
+func (cluster *Cluster) ForEachContainer(fn func(namespace *Namespace, pod *Pod, container *Container)) {
+	cluster.mu.RLock()
+	defer cluster.mu.RUnlock()
+
+	for _, namespace := range cluster.Namespaces {
+		for _, pod := range namespace.Pods {
+			for _, container := range pod.Containers {
+				fn(namespace, pod, container)
+			}
+		}
+	}
 }
 
 func (pod *Pod) GetName() string {
@@ -179,6 +269,18 @@ func newImage(registry string, repo string, tag string) *Image {
 	}
 }
 
+// FullName returns the repository of the image including its registry when known, e.g.
+// docker.io/library/nginx, or an empty string for a nil image.
+func (image *Image) FullName() string {
+	if image == nil || image.Repo == "" {
+		return ""
+	}
+	if image.Registry != nil && image.Registry.Name != "" {
+		return image.Registry.Name + "/" + image.Repo
+	}
+	return image.Repo
+}
+
 // Inserted appends the given path to the SinkResult path and sets the operation to SinkOperationInserted.
 func (sinkResult *SinkResult) Inserted(path string) {
 	sinkResult.Path = append(sinkResult.Path, path)
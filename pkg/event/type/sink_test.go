@@ -0,0 +1,123 @@
+package eventtype
+
+import (
+	"testing"
+)
+
+// testEvent is a minimal IEvent used to drive SinkEvent in tests.
+type testEvent struct {
+	namespace string
+	pod       string
+	container string
+	image     string
+	parent    *Process
+	ancestors []*Process
+	process   *Process
+	source    *EventSource
+}
+
+// testEventOption sets a field of a test event that is not an argument of newTestEvent.
+type testEventOption func(event *testEvent)
+
+func newTestEvent(namespace string, parentBinary string, binary string, arguments string, options ...testEventOption) *testEvent {
+	event := &testEvent{
+		namespace: namespace,
+		pod:       "nginx-554b9c67f9-c5cv4",
+		container: "nginx",
+		image:     "docker.io/library/nginx:latest",
+		parent:    &Process{Binary: parentBinary},
+		process:   &Process{Binary: binary, Arguments: arguments},
+	}
+	for _, option := range options {
+		option(event)
+	}
+	return event
+}
+
+func withPod(pod string) testEventOption {
+	return func(event *testEvent) { event.pod = pod }
+}
+
+func withImage(image string) testEventOption {
+	return func(event *testEvent) { event.image = image }
+}
+
+func withSource(source *EventSource) testEventOption {
+	return func(event *testEvent) { event.source = source }
+}
+
+// withLineage replaces the process of the event, its parent and its ancestors.
+func withLineage(process *Process, parent *Process, ancestors ...*Process) testEventOption {
+	return func(event *testEvent) {
+		event.process = process
+		event.parent = parent
+		event.ancestors = ancestors
+	}
+}
+
+func withCredentials(parent *Credentials, process *Credentials) testEventOption {
+	return func(event *testEvent) {
+		event.parent.Credentials = parent
+		event.process.Credentials = process
+	}
+}
+
+func withNamespaces(namespaces LinuxNamespaces) testEventOption {
+	return func(event *testEvent) { event.process.Namespaces = namespaces }
+}
+
+func (e *testEvent) GetNamespace() (*Namespace, error) {
+	return &Namespace{Name: e.namespace, Pods: map[string]*Pod{}}, nil
+}
+
+func (e *testEvent) GetPod() (*Pod, error) {
+	return &Pod{Name: e.pod, Containers: map[string]*Container{}}, nil
+}
+
+func (e *testEvent) GetContainer() (*Container, error) {
+	return &Container{Name: e.container, Image: &Image{Repo: e.image}, Processes: map[string]*Process{}}, nil
+}
+
+func (e *testEvent) GetParentProcess() (*Process, error) {
+	return e.parent, nil
+}
+
+func (e *testEvent) GetProcess() (*Process, error) {
+	return e.process, nil
+}
+
+func (e *testEvent) GetSource() (*EventSource, error) {
+	return e.source, nil
+}
+
+func (e *testEvent) GetAncestors() ([]*Process, error) {
+	return e.ancestors, nil
+}
+
+func TestSinkEvent(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+
+	result, err := cluster.SinkEvent(newTestEvent("default", "/bin/sh", "/usr/bin/curl", "https://example.com"))
+	if err != nil {
+		t.Fatalf("failed to sink event: %v", err)
+	}
+	if result.Operation != SinkOperationInserted {
+		t.Errorf("first sink operation = %s; want %s", result.Operation, SinkOperationInserted)
+	}
+	if len(result.Path) != 5 {
+		t.Errorf("first sink inserted %d nodes; want 5", len(result.Path))
+	}
+
+	result, err = cluster.SinkEvent(newTestEvent("default", "/bin/sh", "/usr/bin/curl", "https://example.com"))
+	if err != nil {
+		t.Fatalf("failed to sink event: %v", err)
+	}
+	if result.Operation != SinkOperationUpdated {
+		t.Errorf("second sink operation = %s; want %s", result.Operation, SinkOperationUpdated)
+	}
+
+	result, err = cluster.SinkEvent(nil)
+	if err != nil || result.Operation != SinkOperationIgnored {
+		t.Errorf("nil event = %v, %v; want %s", result, err, SinkOperationIgnored)
+	}
+}
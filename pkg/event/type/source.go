@@ -0,0 +1,60 @@
+package eventtype
+
+import (
+	"time"
+)
+
+// EventSource is the metadata of the sensor that produced an event. Any field may be
+// empty when the source does not report it.
+type EventSource struct {
+	NodeName    string
+	ClusterName string
+	// PolicyName is the tracing policy that generated the event, only set for policy driven
+	// events such as kprobes, tracepoints, uprobes and LSM hooks.
+	PolicyName string
+	// Time is when the event occurred, zero when unknown.
+	Time time.Time
+}
+
+// Provenance counts the events each cluster, node and policy contributed to a profile node.
+type Provenance struct {
+	Clusters map[string]int64 `json:"clusters,omitempty"`
+	Nodes    map[string]int64 `json:"nodes,omitempty"`
+	Policies map[string]int64 `json:"policies,omitempty"`
+}
+
+// record counts the event source and returns the provenance, which is created on the
+// first known source so profile nodes without metadata stay unchanged.
+func (provenance *Provenance) record(source *EventSource) *Provenance {
+	if source == nil || (source.ClusterName == "" && source.NodeName == "" && source.PolicyName == "") {
+		return provenance
+	}
+	if provenance == nil {
+		provenance = &Provenance{}
+	}
+
+	provenance.Clusters = countSource(provenance.Clusters, source.ClusterName)
+	provenance.Nodes = countSource(provenance.Nodes, source.NodeName)
+	provenance.Policies = countSource(provenance.Policies, source.PolicyName)
+
+	return provenance
+}
+
+func countSource(counts map[string]int64, name string) map[string]int64 {
+	if name == "" {
+		return counts
+	}
+	if counts == nil {
+		counts = map[string]int64{}
+	}
+	counts[name]++
+	return counts
+}
+
+// nodeName returns the node of the source, empty when unknown.
+func (source *EventSource) nodeName() string {
+	if source == nil {
+		return ""
+	}
+	return source.NodeName
+}
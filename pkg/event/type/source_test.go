@@ -0,0 +1,47 @@
+package eventtype
+
+import (
+	"testing"
+)
+
+func TestSinkEventRecordsProvenance(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+
+	capable := func() *KernelBehavior {
+		return &KernelBehavior{Kind: KernelBehaviorCapability, Function: "cap_capable", Capability: &CapabilityBehavior{Name: "CAP_SYS_ADMIN"}}
+	}
+
+	for _, source := range []*EventSource{
+		{NodeName: "worker-1", ClusterName: "prod", PolicyName: "capabilities"},
+		{NodeName: "worker-2", ClusterName: "prod", PolicyName: "capabilities"},
+		{NodeName: "worker-2", ClusterName: "prod"},
+	} {
+		mustSink(t, cluster, newTestKernelEvent(capable(), withSource(source)))
+	}
+
+	process := cluster.Namespaces["namespace:default"].Pods["pod:nginx"].Containers["container:nginx"].Processes[(&Process{Binary: "/bin/sh"}).GetKey()].ChildProcesses[(&Process{Binary: "/usr/bin/app"}).GetKey()]
+	if process.Provenance == nil {
+		t.Fatalf("process has no provenance")
+	}
+	if process.Provenance.Nodes["worker-1"] != 1 || process.Provenance.Nodes["worker-2"] != 2 {
+		t.Errorf("process nodes = %v; want worker-1:1 worker-2:2", process.Provenance.Nodes)
+	}
+	if process.Provenance.Clusters["prod"] != 3 {
+		t.Errorf("process clusters = %v; want prod:3", process.Provenance.Clusters)
+	}
+
+	behavior := process.KernelBehaviors[capable().GetKey()]
+	if behavior.Provenance.Policies["capabilities"] != 2 || len(behavior.Provenance.Policies) != 1 {
+		t.Errorf("behavior policies = %v; want capabilities:2", behavior.Provenance.Policies)
+	}
+}
+
+func TestSinkEventWithoutSource(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+	mustSink(t, cluster, newTestEvent("default", "/bin/sh", "/usr/bin/curl", ""))
+
+	process := cluster.Namespaces["namespace:default"].Pods["pod:nginx"].Containers["container:nginx"].Processes[(&Process{Binary: "/bin/sh"}).GetKey()]
+	if process.Provenance != nil {
+		t.Errorf("provenance = %+v; want nil without source metadata", process.Provenance)
+	}
+}
@@ -0,0 +1,42 @@
+package eventtype
+
+import (
+	"time"
+)
+
+// Symbol is a user space function of a binary or library observed being called
+// by a process through an uprobe.
+type Symbol struct {
+	Path       string      `json:"path"`
+	Symbol     string      `json:"symbol"`
+	Calls      int64       `json:"calls"`
+	Provenance *Provenance `json:"provenance,omitempty"`
+	FirstSeen  time.Time   `json:"first_seen"`
+	LastSeen   time.Time   `json:"last_seen"`
+}
+
+func (symbol *Symbol) GetKey() string {
+	return key("symbol", symbol.Path+":"+symbol.Symbol)
+}
+
+// sinkSymbol records the called function in the symbol set of the process.
+func (ctx *sinkContext) sinkSymbol(process *Process, raw *Symbol) {
+	if process.Symbols == nil {
+		process.Symbols = map[string]*Symbol{}
+	}
+
+	symbolKey := raw.GetKey()
+	symbol, ok := process.Symbols[symbolKey]
+	if !ok {
+		symbol = &Symbol{
+			Path:      raw.Path,
+			Symbol:    raw.Symbol,
+			FirstSeen: ctx.now,
+		}
+		ctx.result.Inserted(symbolKey)
+		process.Symbols[symbolKey] = symbol
+	}
+	symbol.Calls++
+	symbol.Provenance = symbol.Provenance.record(ctx.source)
+	symbol.LastSeen = ctx.now
+}
@@ -0,0 +1,46 @@
+package eventtype
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// testUprobeEvent is a testEvent reporting a function called by its process.
+type testUprobeEvent struct {
+	*testEvent
+	symbol *Symbol
+}
+
+func (e *testUprobeEvent) GetSymbol() (*Symbol, error) {
+	return e.symbol, nil
+}
+
+func TestSinkSymbolRecordsCalls(t *testing.T) {
+	cluster := NewCluster("test-cluster")
+
+	for _, name := range []string{"SSL_write", "SSL_write", "SSL_read"} {
+		mustSink(t, cluster, &testUprobeEvent{
+			testEvent: newTestEvent("default", "/bin/sh", "/usr/bin/curl", ""),
+			symbol:    &Symbol{Path: "/usr/lib/libssl.so.3", Symbol: name},
+		})
+	}
+
+	curl := cluster.Namespaces["namespace:default"].Pods["pod:nginx"].Containers["container:nginx"].
+		Processes[(&Process{Binary: "/bin/sh"}).GetKey()].ChildProcesses[(&Process{Binary: "/usr/bin/curl"}).GetKey()]
+	if len(curl.Symbols) != 2 {
+		t.Fatalf("curl has %d symbols; want 2", len(curl.Symbols))
+	}
+	write := curl.Symbols[(&Symbol{Path: "/usr/lib/libssl.so.3", Symbol: "SSL_write"}).GetKey()]
+	if write == nil || write.Calls != 2 {
+		t.Errorf("SSL_write = %+v; want 2 calls", write)
+	}
+
+	profileJSON, err := json.Marshal(cluster)
+	if err != nil {
+		t.Fatalf("failed to marshal cluster: %v", err)
+	}
+	if !strings.Contains(string(profileJSON), `"symbol":"SSL_read"`) {
+		t.Errorf("symbols missing from the JSON profile")
+	}
+}
@@ -1,5 +1,11 @@
 package eventtype
 
+import (
+	"runtime-behavior-profiler/pkg/util"
+	"sync"
+	"time"
+)
+
 type SinkOperation string
 
 const (
@@ -14,16 +20,93 @@ type IEvent interface {
 	GetContainer() (*Container, error)
 	GetParentProcess() (*Process, error)
 	GetProcess() (*Process, error)
+	// GetSource returns the metadata of the sensor that produced the event, nil when unknown.
+	GetSource() (*EventSource, error)
+}
+
+// IAncestryEvent is implemented by events that know the lineage of their process
+// beyond the immediate parent returned by IEvent.GetParentProcess.
+type IAncestryEvent interface {
+	// GetAncestors returns the ancestors of the process ordered from the oldest
+	// ancestor to the immediate parent.
+	GetAncestors() ([]*Process, error)
+}
+
+// IExecEvent is implemented by events that tell whether they report the execution of their
+// process, e.g. OCSF activities of which only a process launch does. Other events are an
+// execution unless they implement one of the behaviour interfaces below.
+type IExecEvent interface {
+	IsExec() bool
+}
+
+// IExitEvent is implemented by events reporting the termination of a process.
+type IExitEvent interface {
+	GetExit() (*ProcessExit, error)
+}
+
+// ILoaderEvent is implemented by events reporting a shared object loaded by a process.
+type ILoaderEvent interface {
+	GetLibrary() (*Library, error)
+}
+
+// IUprobeEvent is implemented by events reporting a traced user space function call.
+type IUprobeEvent interface {
+	GetSymbol() (*Symbol, error)
+}
+
+// IKernelEvent is implemented by events reporting a kernel function call. GetKernelBehavior
+// returns nil when the function is not part of the known security relevant catalog.
+type IKernelEvent interface {
+	GetKernelBehavior() (*KernelBehavior, error)
+}
+
+// IEventSink is implemented by the profiles events are sunk into, a Cluster or a ClusterRegistry.
+type IEventSink interface {
+	SinkEvent(rawEvent IEvent) (*SinkResult, error)
+}
+
+// IDeviationScorer is implemented by the profiles able to score the deviations reported
+// against them, a Cluster or a ClusterRegistry.
+type IDeviationScorer interface {
+	ScoreDeviation(deviation *Deviation)
+}
+
+// IAttackMapper is implemented by the profiles tagging their deviations with MITRE ATT&CK
+// techniques, a Cluster or a ClusterRegistry.
+type IAttackMapper interface {
+	AttackMappingOf(deviation *Deviation) *AttackMapping
 }
 
 type SinkResult struct {
-	Operation SinkOperation `json:"operation"`
-	Path      []string      `json:"path"`
+	Operation  SinkOperation `json:"operation"`
+	Path       []string      `json:"path"`
+	Evicted    []string      `json:"evicted,omitempty"`
+	Deviations []*Deviation  `json:"deviations,omitempty"`
 }
 
 type Cluster struct {
 	Name       string                `json:"name"`
 	Namespaces map[string]*Namespace `json:"namespaces"`
+
+	// Clock is used to timestamp profile nodes, defaults to the real clock.
+	Clock util.Clock `json:"-"`
+	// Retention controls aging and compaction of the profile, nil keeps everything forever.
+	Retention *RetentionConfig `json:"-"`
+	// LearningPeriod is how long a container is observed before new behaviour is reported
+	// as a deviation. Zero keeps every container learning forever.
+	LearningPeriod time.Duration `json:"-"`
+	// Normalizer generalizes process arguments before keying, nil keys on the raw arguments.
+	Normalizer ArgumentNormalizer `json:"-"`
+	// Attack tags new profile nodes and deviations with MITRE ATT&CK techniques, nil leaves
+	// them untagged.
+	Attack *AttackMapping `json:"-"`
+	// Frequency controls the detection of frequency anomalies, nil only keeps the rates.
+	Frequency *FrequencyConfig `json:"-"`
+
+	// prevalence indexes the behaviours of the profile for rarity scoring, nil until first
+	// used and whenever nodes were removed since.
+	prevalence *prevalenceIndex
+	mu         sync.RWMutex
 }
 
 type Namespace struct {
@@ -40,6 +123,26 @@ type Container struct {
 	Name      string              `json:"name"`
 	Image     *Image              `json:"image"`
 	Processes map[string]*Process `json:"processes"`
+	FirstSeen time.Time           `json:"first_seen"`
+
+	// LinuxNamespaces are the namespaces the container was first seen in and
+	// NamespaceChanges the processes later seen in other namespaces.
+	LinuxNamespaces  LinuxNamespaces    `json:"linux_namespaces,omitempty"`
+	NamespaceChanges []*NamespaceChange `json:"namespace_changes,omitempty"`
+
+	// namespaceReports are the namespace changes and creations already reported, see
+	// firstNamespaceReport.
+	namespaceReports map[string]bool
+
+	// execIndex maps the exec id of every process recently seen in the container to its
+	// profile node.
+	execIndex map[string]*execEntry
+}
+
+// execEntry is the profile node of an exec id and the last time an event carried it.
+type execEntry struct {
+	process  *Process
+	lastSeen time.Time
 }
 
 type Image struct {
@@ -53,7 +156,31 @@ type Registry struct {
 }
 
 type Process struct {
-	Binary         string              `json:"binary"`
-	Arguments      string              `json:"arguments"`
-	ChildProcesses map[string]*Process `json:"child_processes"`
+	Binary    string `json:"binary"`
+	Arguments string `json:"arguments"`
+	// ArgumentExamples keeps a few raw argument strings that were generalized into Arguments.
+	ArgumentExamples []string                   `json:"argument_examples,omitempty"`
+	ChildProcesses   map[string]*Process        `json:"child_processes"`
+	Lifecycle        *ProcessLifecycle          `json:"lifecycle,omitempty"`
+	Libraries        map[string]*Library        `json:"libraries,omitempty"`
+	Symbols          map[string]*Symbol         `json:"symbols,omitempty"`
+	KernelBehaviors  map[string]*KernelBehavior `json:"kernel_behaviors,omitempty"`
+	Privileges       *PrivilegeProfile          `json:"privileges,omitempty"`
+	Provenance       *Provenance                `json:"provenance,omitempty"`
+	Attack           []*AttackTag               `json:"attack,omitempty"`
+	// Executions is the rate of the executions of the process and Connections the rate of
+	// its outbound connections, whatever their destination.
+	Executions  *Rate     `json:"executions,omitempty"`
+	Connections *Rate     `json:"connections,omitempty"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+
+	// ExecID and ParentExecID identify a single execution. They are only set on raw
+	// processes produced by events and are used to place the process in the tree.
+	ExecID       string `json:"-"`
+	ParentExecID string `json:"-"`
+	// Credentials are the privileges of the execution, only set on raw processes.
+	Credentials *Credentials `json:"-"`
+	// Namespaces are the Linux namespaces of the execution, only set on raw processes.
+	Namespaces LinuxNamespaces `json:"-"`
 }
@@ -0,0 +1,51 @@
+package util
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ReadCgroupUnit returns the systemd unit a process runs in, read from <procRoot>/<pid>/cgroup.
+// When the cgroup is not a systemd unit the cgroup path is returned instead, and an empty
+// string when the process is gone or the file cannot be parsed.
+func ReadCgroupUnit(procRoot string, pid uint32) string {
+	file, err := os.Open(filepath.Join(procRoot, strconv.FormatUint(uint64(pid), 10), "cgroup"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	var cgroupPath string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Lines are formatted as hierarchy-ID:controller-list:cgroup-path, the unified
+		// hierarchy has an empty controller list and systemd v1 uses name=systemd.
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[1] != "" && parts[1] != "name=systemd" {
+			continue
+		}
+		cgroupPath = parts[2]
+		if unit := systemdUnit(cgroupPath); unit != "" {
+			return unit
+		}
+	}
+
+	return cgroupPath
+}
+
+// systemdUnit returns the innermost systemd service or scope of a cgroup path.
+func systemdUnit(cgroupPath string) string {
+	elements := strings.Split(cgroupPath, "/")
+	for i := len(elements) - 1; i >= 0; i-- {
+		if strings.HasSuffix(elements[i], ".service") || strings.HasSuffix(elements[i], ".scope") {
+			return elements[i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCgroupUnit(t *testing.T) {
+	procRoot := t.TempDir()
+
+	tests := []struct {
+		pid      string
+		cgroup   string
+		expected string
+	}{
+		{"1", "0::/init.scope\n", "init.scope"},
+		{"2", "0::/system.slice/containerd.service\n", "containerd.service"},
+		{"3", "12:memory:/system.slice/kubelet.service\n1:name=systemd:/system.slice/kubelet.service\n", "kubelet.service"},
+		{"4", "0::/kubepods.slice/kubepods-burstable.slice\n", "/kubepods.slice/kubepods-burstable.slice"},
+	}
+
+	for _, test := range tests {
+		if err := os.MkdirAll(filepath.Join(procRoot, test.pid), 0o755); err != nil {
+			t.Fatalf("failed to create proc dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(procRoot, test.pid, "cgroup"), []byte(test.cgroup), 0o644); err != nil {
+			t.Fatalf("failed to write cgroup file: %v", err)
+		}
+	}
+
+	for i, test := range tests {
+		result := ReadCgroupUnit(procRoot, uint32(i+1))
+		if result != test.expected {
+			t.Errorf("ReadCgroupUnit(%s) = %q; expected %q", test.pid, result, test.expected)
+		}
+	}
+
+	if result := ReadCgroupUnit(procRoot, 99); result != "" {
+		t.Errorf("ReadCgroupUnit of a missing process = %q; expected empty", result)
+	}
+}
@@ -12,6 +12,9 @@ func ExtractPodName(input string) string {
 	// - Second last segment (optional): 10 alphanumeric characters
 	re := regexp.MustCompile(`^([a-zA-Z0-9\-]+)-[a-zA-Z0-9]{10}-[a-zA-Z0-9]{5}$|^([a-zA-Z0-9\-]+)-[a-zA-Z0-9]{5}$|^([a-zA-Z0-9\-]+)$`)
 	match := re.FindStringSubmatch(input)
+	if match == nil {
+		return input // Names such as node names may contain dots
+	}
 
 	// Return the first matched group with a valid base name
 	if match[1] != "" {
@@ -14,6 +14,7 @@ func TestExtractPodName(t *testing.T) {
 		{"nginx-c5cv4", "nginx"},
 		{"nginx", "nginx"},
 		{"app-server-abc1234567-xy123", "app-server"},
+		{"ip-10-0-1-23.ec2.internal", "ip-10-0-1-23.ec2.internal"},
 	}
 
 	for _, test := range tests {